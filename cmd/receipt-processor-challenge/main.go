@@ -2,19 +2,201 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
+	"io"
+	"log"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"receipt-processor-challenge/internal/app"
 	"receipt-processor-challenge/internal/app/receipt/calculator"
+	"receipt-processor-challenge/internal/app/receipt/duplicates"
+	"receipt-processor-challenge/internal/config"
+	rcp "receipt-processor-challenge/internal/domain/receipt"
 	"receipt-processor-challenge/internal/inputports/http"
-	"receipt-processor-challenge/internal/interfaceadapters/storage/memory"
+	"receipt-processor-challenge/internal/interfaceadapters/metrics"
+	"receipt-processor-challenge/internal/interfaceadapters/storage"
+	"receipt-processor-challenge/internal/tracing"
 )
 
+const serviceName string = "receipt-processor-challenge"
+
 func main() {
-	ctx := context.Background()
-	repo := memory.New(ctx)
+	cfg, err := config.Load(os.Args[1:], os.Getenv)
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+
+	if err != nil {
+		log.Print(err)
+		os.Exit(2) //nolint:gomnd
+	}
+
+	slog.SetDefault(newLogger(cfg.Log, os.Stderr))
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	err = run(ctx, cfg)
+
+	stop()
+
+	if err != nil {
+		slog.Error("service stopped", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
+}
+
+// newLogger returns the logger of the configured level and format, the level
+// is validated by the configuration.
+func newLogger(cfg config.Log, w io.Writer) *slog.Logger {
+	level := slog.LevelInfo
+	_ = level.UnmarshalText([]byte(cfg.Level))
+
+	opts := &slog.HandlerOptions{Level: level}
+
+	if cfg.Format == config.FormatJSON {
+		return slog.New(slog.NewJSONHandler(w, opts))
+	}
+
+	return slog.New(slog.NewTextHandler(w, opts))
+}
+
+// run starts the service and stops it when ctx is done, the storage is closed
+// once the requests in flight finish.
+func run(ctx context.Context, cfg config.Config) (err error) {
+	// the storage outlives ctx, it is closed after the server stops.
+	store, err := storage.New(context.Background(), storage.Config{
+		Backend:        cfg.Storage.Backend,
+		Dir:            cfg.Storage.Dir,
+		DSN:            cfg.Storage.DSN,
+		SnapshotEvery:  cfg.Storage.SnapshotEvery,
+		MemorySnapshot: cfg.Storage.MemorySnapshot,
+		TimeOut:        time.Duration(cfg.Storage.TimeOut),
+		Retention: storage.RetentionConfig{
+			Capacity: cfg.Storage.Capacity,
+			MaxBytes: cfg.Storage.MaxBytes,
+			TTL:      time.Duration(cfg.Storage.TTL),
+		},
+		Cache: storage.CacheConfig{
+			Backend:   cfg.Storage.Cache.Backend,
+			Size:      cfg.Storage.Cache.Size,
+			TTL:       time.Duration(cfg.Storage.Cache.TTL),
+			RedisAddr: cfg.Storage.Cache.RedisAddr,
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		err = errors.Join(err, store.Close())
+	}()
+
+	registry := metrics.NewRegistry()
+	store.RegisterMetrics(registry)
+
+	var repo rcp.Repository = metrics.NewRepository(store.Repository, registry)
+
+	tracer, err := newTracer(cfg.Tracing)
+	if err != nil {
+		return err
+	}
+
+	if tracer != nil {
+		// the spans of the requests drained on shutdown are exported too.
+		defer func() {
+			err = errors.Join(err, tracer.Shutdown(context.Background()))
+		}()
+
+		repo = tracing.NewRepository(repo)
+	}
+
 	calc := calculator.New()
-	app := app.NewServices(repo, calc)
+	if cfg.Rules.Path != "" {
+		sets, err := calculator.LoadRuleSets(cfg.Rules.Path)
+		if err != nil {
+			return err
+		}
+
+		calc, err = calculator.NewWithRules(sets...)
+		if err != nil {
+			return err
+		}
+	}
+
+	tolerance, err := rcp.ParseMoney(cfg.Rules.TotalTolerance)
+	if err != nil {
+		return err
+	}
+
+	detector, err := duplicates.New(repo, cfg.Duplicates.Policy, cfg.Duplicates.Similarity)
+	if err != nil {
+		return err
+	}
+
+	app := app.NewServices(repo, metrics.NewCalculator(calc, registry), rcp.NewValidator(tolerance), detector)
+
+	opts := []http.Option{
+		http.WithAddr(cfg.Server.Addr),
+		http.WithTimeOuts(
+			time.Duration(cfg.Server.ReadTimeOut),
+			time.Duration(cfg.Server.WriteTimeOut),
+			time.Duration(cfg.Server.IdleTimeOut),
+		),
+		http.WithShutdownTimeOut(time.Duration(cfg.Server.ShutdownTimeOut)),
+		http.WithLogger(slog.Default()),
+		http.WithMetrics(registry),
+		http.WithSpecValidation(),
+		http.WithBatchLimits(cfg.Server.Batch.MaxSize, cfg.Server.Batch.Concurrency),
+		http.WithBodyLimit(cfg.Server.MaxBodyBytes),
+	}
+
+	if tracer != nil {
+		opts = append(opts, http.WithTracer(tracer))
+	}
+
+	if cfg.Server.TLS.Enabled() {
+		opts = append(opts, http.WithTLS(cfg.Server.TLS.CertFile, cfg.Server.TLS.KeyFile))
+	}
+
+	opts = append(opts, http.WithHealthChecks(http.HealthCheck{
+		Name:  "storage",
+		Check: store.Ping,
+		// the memory engine runs in the process, the other backends are
+		// external dependencies.
+		Liveness: store.Memory != nil,
+	}))
+
+	if store.Cache != nil {
+		opts = append(opts, http.WithHealthChecks(http.HealthCheck{
+			Name:     "cache",
+			Check:    store.PingCache,
+			Optional: true,
+		}))
+	}
+
+	if store.Memory != nil && cfg.Storage.MemorySnapshot != "" {
+		opts = append(opts, http.WithAdmin(store, cfg.Server.AdminToken))
+	}
+
+	server := http.NewServer(ctx, app, opts...)
+
+	return server.Start(ctx)
+}
+
+// newTracer returns nil when tracing is disabled.
+func newTracer(cfg config.Tracing) (*tracing.Tracer, error) {
+	if cfg.Exporter == "" {
+		return nil, nil //nolint:nilnil
+	}
+
+	exporter, err := tracing.NewExporter(cfg.Exporter, cfg.File, cfg.Endpoint, serviceName)
+	if err != nil {
+		return nil, err
+	}
 
-	server := http.NewServer(ctx, app)
-	server.Start()
+	return tracing.New(exporter, tracing.WithSampleRatio(cfg.SampleRatio)), nil
 }
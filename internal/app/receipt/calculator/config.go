@@ -0,0 +1,297 @@
+package calculator
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"receipt-processor-challenge/internal/domain/receipt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	TypeTotalMultiple string = "total-multiple"
+	TypeItemGroups    string = "item-groups"
+)
+
+var (
+	ErrInvalidRule     = errors.New("invalid rule")
+	ErrUnknownRuleType = errors.New("unknown rule type")
+
+	// factoriesMu guards factories, rule types can be registered while rule
+	// sets are loaded.
+	factoriesMu sync.RWMutex              //nolint:gochecknoglobals
+	factories   = map[string]RuleFactory{ //nolint:gochecknoglobals
+		RuleRetailerName:     newRetailerNameRule,
+		RuleRoundDollar:      newRoundDollarRule,
+		TypeTotalMultiple:    newTotalMultipleRule,
+		TypeItemGroups:       newItemGroupRule,
+		RuleItemDescription:  newItemDescriptionRule,
+		RuleOddPurchaseDay:   newOddPurchaseDayRule,
+		RulePurchaseTimeSpan: newPurchaseTimeRule,
+	}
+)
+
+// RulesConfig is the content of a rule set file. EffectiveFrom uses the
+// receipt purchase date format, an empty value makes the rule set effective
+// for every receipt.
+type RulesConfig struct {
+	Version       string       `json:"version"       yaml:"version"`
+	EffectiveFrom string       `json:"effectiveFrom" yaml:"effectiveFrom"`
+	Rules         []RuleConfig `json:"rules"         yaml:"rules"`
+}
+
+// RuleConfig describes a single rule, Type selects the rule implementation and
+// the remaining fields are the weights and thresholds used by it.
+type RuleConfig struct {
+	Type        string  `json:"type"        yaml:"type"`
+	ID          string  `json:"id"          yaml:"id"`
+	Description string  `json:"description" yaml:"description"`
+	Points      int     `json:"points"      yaml:"points"`
+	Multiple    float64 `json:"multiple"    yaml:"multiple"`
+	Factor      float64 `json:"factor"      yaml:"factor"`
+	Every       int     `json:"every"       yaml:"every"`
+	FromHour    int     `json:"fromHour"    yaml:"fromHour"`
+	ToHour      int     `json:"toHour"      yaml:"toHour"`
+}
+
+// RuleFactory builds a rule from its configuration.
+type RuleFactory func(cfg RuleConfig) (Rule, error)
+
+// RegisterRuleType makes a new rule type available to rule set files.
+func RegisterRuleType(ruleType string, factory RuleFactory) {
+	factoriesMu.Lock()
+	defer factoriesMu.Unlock()
+
+	factories[ruleType] = factory
+}
+
+func ruleFactory(ruleType string) (RuleFactory, bool) {
+	factoriesMu.RLock()
+	defer factoriesMu.RUnlock()
+
+	factory, ok := factories[ruleType]
+
+	return factory, ok
+}
+
+// LoadRuleSets reads every rule set version found in path, path can be a
+// single rule set file or a directory with one file per version.
+func LoadRuleSets(path string) ([]*RuleSet, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if !info.IsDir() {
+		rs, err := LoadRules(path)
+		if err != nil {
+			return nil, err
+		}
+
+		return []*RuleSet{rs}, nil
+	}
+
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+
+	sets := []*RuleSet{}
+
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+
+		switch strings.ToLower(filepath.Ext(entry.Name())) {
+		case ".json", ".yaml", ".yml":
+		default:
+			continue
+		}
+
+		rs, err := LoadRules(filepath.Join(path, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		sets = append(sets, rs)
+	}
+
+	return sets, nil
+}
+
+// LoadRules reads a rule set from a JSON (.json) or YAML file, the file name
+// is used as version when the file does not set one.
+func LoadRules(path string) (*RuleSet, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := RulesConfig{}
+
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		decoder := json.NewDecoder(bytes.NewReader(content))
+		decoder.DisallowUnknownFields()
+		err = decoder.Decode(&cfg)
+	} else {
+		decoder := yaml.NewDecoder(bytes.NewReader(content))
+		decoder.KnownFields(true)
+		err = decoder.Decode(&cfg)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("%s:%w", err.Error(), ErrInvalidRule)
+	}
+
+	if cfg.Version == "" {
+		cfg.Version = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+
+	return cfg.RuleSet()
+}
+
+// RuleSet builds the rules in the same order they are configured.
+func (cfg RulesConfig) RuleSet() (*RuleSet, error) {
+	effectiveFrom := time.Time{}
+
+	if cfg.EffectiveFrom != "" {
+		date, err := time.Parse(receipt.DatePurchaseFormat, cfg.EffectiveFrom)
+		if err != nil {
+			return nil, fmt.Errorf("%s effectiveFrom format:%w", cfg.Version, ErrInvalidRule)
+		}
+
+		effectiveFrom = date
+	}
+
+	rs, _ := NewRuleSet()
+	rs.Versioned(cfg.Version, effectiveFrom)
+
+	for _, ruleCfg := range cfg.Rules {
+		factory, ok := ruleFactory(ruleCfg.Type)
+		if !ok {
+			return nil, fmt.Errorf("%s:%w", ruleCfg.Type, ErrUnknownRuleType)
+		}
+
+		if ruleCfg.ID == "" {
+			ruleCfg.ID = ruleCfg.Type
+		}
+
+		rule, err := factory(ruleCfg)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := rs.Register(rule); err != nil {
+			return nil, err
+		}
+	}
+
+	return rs, nil
+}
+
+func (cfg RuleConfig) info(description string, args ...any) ruleInfo {
+	if cfg.Description != "" {
+		description = cfg.Description
+	} else {
+		description = fmt.Sprintf(description, args...)
+	}
+
+	return ruleInfo{id: cfg.ID, description: description}
+}
+
+func (cfg RuleConfig) invalid(field string) error {
+	return fmt.Errorf("%s %s must be greater than zero:%w", cfg.ID, field, ErrInvalidRule)
+}
+
+func newRetailerNameRule(cfg RuleConfig) (Rule, error) {
+	return retailerNameRule{
+		ruleInfo: cfg.info("%d points for every alphanumeric character in the retailer name", cfg.Points),
+		points:   cfg.Points,
+	}, nil
+}
+
+func newRoundDollarRule(cfg RuleConfig) (Rule, error) {
+	return roundDollarRule{
+		ruleInfo: cfg.info("%d points if the total is a round dollar amount with no cents", cfg.Points),
+		points:   cfg.Points,
+	}, nil
+}
+
+func newTotalMultipleRule(cfg RuleConfig) (Rule, error) {
+	multiple, err := receipt.ParseMoney(strconv.FormatFloat(cfg.Multiple, 'f', -1, 64))
+	if err != nil || multiple <= 0 {
+		return nil, fmt.Errorf("%s multiple must be a positive amount of cents:%w", cfg.ID, ErrInvalidRule)
+	}
+
+	return totalMultipleRule{
+		ruleInfo: cfg.info("%d points if the total is a multiple of %s", cfg.Points, multiple),
+		points:   cfg.Points,
+		multiple: multiple,
+	}, nil
+}
+
+func newItemGroupRule(cfg RuleConfig) (Rule, error) {
+	if cfg.Every <= 0 {
+		return nil, cfg.invalid("every")
+	}
+
+	return itemGroupRule{
+		ruleInfo: cfg.info("%d points for every %d items on the receipt", cfg.Points, cfg.Every),
+		points:   cfg.Points,
+		every:    cfg.Every,
+	}, nil
+}
+
+func newItemDescriptionRule(cfg RuleConfig) (Rule, error) {
+	if cfg.Multiple <= 0 || cfg.Multiple != float64(int(cfg.Multiple)) {
+		return nil, cfg.invalid("multiple")
+	}
+
+	factor, err := newFactor(cfg.Factor)
+	if err != nil {
+		return nil, err
+	}
+
+	return itemDescriptionRule{
+		ruleInfo: cfg.info(
+			"Trimmed description length is a multiple of %d, price * %s rounded up",
+			int(cfg.Multiple), factor,
+		),
+		multiple: int(cfg.Multiple),
+		factor:   factor,
+	}, nil
+}
+
+func newOddPurchaseDayRule(cfg RuleConfig) (Rule, error) {
+	return oddPurchaseDayRule{
+		ruleInfo: cfg.info("%d points if the day in the purchase date is odd", cfg.Points),
+		points:   cfg.Points,
+	}, nil
+}
+
+func newPurchaseTimeRule(cfg RuleConfig) (Rule, error) {
+	const lastHour = 23
+
+	if cfg.FromHour < 0 || cfg.ToHour > lastHour || cfg.FromHour > cfg.ToHour {
+		return nil, fmt.Errorf("%s hours out of range:%w", cfg.ID, ErrInvalidRule)
+	}
+
+	return purchaseTimeRule{
+		ruleInfo: cfg.info(
+			"%d points if the hour of purchase is between %d and %d",
+			cfg.Points, cfg.FromHour, cfg.ToHour,
+		),
+		points:   cfg.Points,
+		fromHour: cfg.FromHour,
+		toHour:   cfg.ToHour,
+	}, nil
+}
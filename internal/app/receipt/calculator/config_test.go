@@ -0,0 +1,208 @@
+package calculator
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"receipt-processor-challenge/internal/domain/receipt"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_LoadRules(t *testing.T) {
+	cases := []struct {
+		name          string
+		file          string
+		content       string
+		expectedIDs   []string
+		expectedError error
+	}{
+		{
+			name: "json-case",
+			file: "rules.json",
+			content: `{"rules": [
+				{"type": "retailer-name", "points": 2},
+				{"type": "total-multiple", "id": "multiple-of-50-cents", "points": 10, "multiple": 0.5}
+			]}`,
+			expectedIDs:   []string{RuleRetailerName, "multiple-of-50-cents"},
+			expectedError: nil,
+		},
+		{
+			name: "yaml-case",
+			file: "rules.yaml",
+			content: `
+rules:
+  - type: odd-purchase-day
+    points: 3
+  - type: item-groups
+    points: 1
+    every: 3
+`,
+			expectedIDs:   []string{RuleOddPurchaseDay, TypeItemGroups},
+			expectedError: nil,
+		},
+		{
+			name: "unknown-type-case",
+			file: "rules.yaml",
+			content: `
+rules:
+  - type: full-moon
+    points: 3
+`,
+			expectedError: ErrUnknownRuleType,
+		},
+		{
+			name: "duplicated-rule-case",
+			file: "rules.yaml",
+			content: `
+rules:
+  - type: round-dollar
+    points: 3
+  - type: round-dollar
+    points: 5
+`,
+			expectedError: ErrDuplicatedRule,
+		},
+		{
+			name: "invalid-threshold-case",
+			file: "rules.yaml",
+			content: `
+rules:
+  - type: item-groups
+    points: 5
+`,
+			expectedError: ErrInvalidRule,
+		},
+		{
+			name: "unknown-field-case",
+			file: "rules.json",
+			content: `{"rules": [
+				{"type": "retailer-name", "weight": 2}
+			]}`,
+			expectedError: ErrInvalidRule,
+		},
+	}
+
+	for _, c := range cases {
+		path := filepath.Join(t.TempDir(), c.file)
+		if err := os.WriteFile(path, []byte(c.content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+
+		expectedIDs := c.expectedIDs
+		expectedError := c.expectedError
+
+		t.Run(c.name, func(t *testing.T) {
+			rules, err := LoadRules(path)
+			if expectedError != nil {
+				assert.True(t, errors.Is(err, expectedError), err)
+
+				return
+			}
+
+			assert.NoError(t, err)
+
+			ids := []string{}
+			for _, rule := range rules.Rules() {
+				ids = append(ids, rule.ID())
+			}
+
+			assert.Equal(t, expectedIDs, ids)
+		})
+	}
+}
+
+func Test_LoadRulesDefaultFile(t *testing.T) {
+	rules, err := LoadRules("../../../../configs/rules.yaml")
+	assert.NoError(t, err)
+
+	rcpt := receipt.Receipt{
+		Retailer:     "M&M Corner Market",
+		PurchaseDate: purchaseDate(t, "2022-03-20"),
+		PurchaseTime: purchaseTime(t, "14:33"),
+		Items: []receipt.Item{
+			{ShortDescription: "Gatorade", Price: money(t, "2.25")},
+			{ShortDescription: "Gatorade", Price: money(t, "2.25")},
+			{ShortDescription: "Gatorade", Price: money(t, "2.25")},
+			{ShortDescription: "Gatorade", Price: money(t, "2.25")},
+		},
+		Total: money(t, "9.00"),
+	}
+
+	expected, err := New().Points(rcpt)
+	assert.NoError(t, err)
+
+	cal, err := NewWithRules(rules)
+	assert.NoError(t, err)
+
+	points, err := cal.Points(rcpt)
+	assert.NoError(t, err)
+	assert.Equal(t, expected, points)
+}
+
+func Test_LoadRuleSets(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"2022.yaml": `
+rules:
+  - type: retailer-name
+    points: 1
+`,
+		"2023.json": `{"version": "summer", "effectiveFrom": "2023-06-01", "rules": [
+			{"type": "retailer-name", "points": 3}
+		]}`,
+		"notes.txt": "not a rule set",
+	}
+
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	sets, err := LoadRuleSets(dir)
+	assert.NoError(t, err)
+	assert.Len(t, sets, 2)
+
+	assert.Equal(t, "2022", sets[0].Version())
+	assert.True(t, sets[0].EffectiveFrom().IsZero())
+	assert.Equal(t, "summer", sets[1].Version())
+	assert.Equal(t, purchaseDate(t, "2023-06-01"), sets[1].EffectiveFrom())
+
+	_, err = LoadRuleSets(filepath.Join(dir, "missing"))
+	assert.Error(t, err)
+}
+
+func Test_RegisterRuleTypeConcurrently(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules.yaml")
+	if err := os.WriteFile(path, []byte("rules:\n  - type: retailer-name\n    points: 1\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	wg := sync.WaitGroup{}
+
+	for i := 0; i < 8; i++ {
+		wg.Add(2)
+
+		go func(i int) {
+			defer wg.Done()
+
+			RegisterRuleType(fmt.Sprintf("concurrent-%d", i), newRetailerNameRule)
+		}(i)
+
+		go func() {
+			defer wg.Done()
+
+			_, err := LoadRules(path)
+			assert.NoError(t, err)
+		}()
+	}
+
+	wg.Wait()
+
+	_, ok := ruleFactory("concurrent-7")
+	assert.True(t, ok)
+}
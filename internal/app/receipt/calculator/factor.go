@@ -0,0 +1,61 @@
+package calculator
+
+import (
+	"fmt"
+	"math"
+	"receipt-processor-challenge/internal/domain/receipt"
+	"strconv"
+	"strings"
+)
+
+const centsPerDollar int64 = 100
+
+// factor is an exact decimal multiplier kept as a fraction, so 0.2 is 2/10.
+type factor struct {
+	num int64
+	den int64
+}
+
+// trimmedFactorPoints is 0.2.
+var trimmedFactorPoints = factor{num: 2, den: 10} //nolint:gochecknoglobals
+
+// newFactor converts a configured decimal into a fraction using the shortest
+// representation of the float, so 0.2 becomes 2/10 instead of its binary value.
+func newFactor(f float64) (factor, error) {
+	if math.IsNaN(f) || math.IsInf(f, 0) || f < 0 {
+		return factor{}, fmt.Errorf("%g:%w", f, ErrInvalidRule)
+	}
+
+	text := strconv.FormatFloat(f, 'f', -1, 64)
+	integer, decimals, _ := strings.Cut(text, ".")
+
+	num, err := strconv.ParseInt(integer+decimals, 10, 64)
+	if err != nil {
+		return factor{}, fmt.Errorf("%g:%w", f, ErrInvalidRule)
+	}
+
+	den := int64(1)
+	for range decimals {
+		den *= 10
+	}
+
+	return factor{num: num, den: den}, nil
+}
+
+// ceil multiplies the amount by the factor and rounds the result in dollars
+// up to the nearest integer.
+func (f factor) ceil(m receipt.Money) int64 {
+	value := m.Cents() * f.num
+	div := f.den * centsPerDollar
+
+	result := value / div
+	if value%div != 0 && value > 0 {
+		result++
+	}
+
+	return result
+}
+
+func (f factor) String() string {
+	return strconv.FormatFloat(float64(f.num)/float64(f.den), 'f', -1, 64)
+}
@@ -1,27 +1,34 @@
 package calculator
 
 import (
+	"errors"
 	"fmt"
-	"math"
 	"receipt-processor-challenge/internal/domain/receipt"
-	"strconv"
-	"strings"
-	"time"
-	"unicode"
+	"sort"
+	"sync"
 )
 
 const (
-	oddDayPoints        int     = 6
-	tPurchasePoints     int     = 10
-	roundTotalPoints    int     = 50
-	multipleOf25Points  int     = 25
-	trimmedFactorPoints float64 = 0.2
-	itemsFactorPoints   int     = 5
-	numItems            int     = 2
+	oddDayPoints        int           = 6
+	tPurchasePoints     int           = 10
+	roundTotalPoints    int           = 50
+	multipleOf25Points  int           = 25
+	multipleOf25Total   receipt.Money = 25
+	descriptionMultiple int           = 3
+	itemsFactorPoints   int           = 5
+	numItems            int           = 2
+	fromPurchaseHour    int           = 14
+	toPurchaseHour      int           = 16
+)
+
+var (
+	ErrNoRuleSet         = errors.New("no rule set")
+	ErrDuplicatedRuleSet = errors.New("duplicated rule set version")
+	ErrUnknownRuleSet    = errors.New("unknown rule set version")
 )
 
 /*
-RULES
+DEFAULT RULES
   - One point for every alphanumeric character in the retailer name.
   - 50 points if the total is a round dollar amount with no cents.
   - 25 points if the total is a multiple of 0.25.
@@ -31,99 +38,84 @@ RULES
     is the number of points earned.
   - 6 points if the day in the purchase date is odd.
   - 10 points if the time of purchase is after 2:00pm and before 4:00pm.
+
+The rules can be replaced by rule sets loaded with LoadRuleSets. Every rule set
+has a version and an effective date, a receipt is scored with the latest rule
+set in effect at its purchase date (or the oldest one when the receipt was
+purchased before any of them) and can be re-scored with any other version.
 */
-type Calculator struct{}
+type Calculator struct {
+	// versions are sorted by effective date.
+	versions []*RuleSet
+}
+
+//nolint:gochecknoglobals
+var defaultVersions = sync.OnceValue(func() []*RuleSet {
+	return []*RuleSet{DefaultRules()}
+})
 
 func New() Calculator {
-	return Calculator{}
-}
+	calc, _ := NewWithRules(DefaultRules())
 
-func (c Calculator) Points(rcpt receipt.Receipt) (*receipt.Points, error) {
-	points := retailerNamePoints(rcpt.Retailer)
-	points += roundDollarPoints(rcpt.Total)
-	points += multipleOf25CentsPoints(rcpt.Total)
-	points += itemsPoints(rcpt.Items)
-	points += trimmedDescriptionPoints(rcpt.Items)
-	points += oddPurchaseDayPoints(rcpt.PurchaseDate)
-	points += timePurchasePoints(rcpt.PurchaseTime)
-
-	return &receipt.Points{Points: points}, nil
+	return calc
 }
 
-func retailerNamePoints(name string) int {
-	points := 0
-
-	for _, c := range name {
-		if unicode.IsLetter(c) || unicode.IsNumber(c) {
-			points++
-		}
+func NewWithRules(sets ...*RuleSet) (Calculator, error) {
+	if len(sets) == 0 {
+		return Calculator{}, ErrNoRuleSet
 	}
 
-	return points
-}
+	versions := append([]*RuleSet(nil), sets...)
+	sort.SliceStable(versions, func(i, j int) bool {
+		return versions[i].effectiveFrom.Before(versions[j].effectiveFrom)
+	})
 
-func roundDollarPoints(total float64) int {
-	if total == math.Trunc(total) {
-		return roundTotalPoints
-	}
+	seen := make(map[string]struct{}, len(versions))
 
-	return 0
-}
+	for _, rs := range versions {
+		if _, ok := seen[rs.version]; ok {
+			return Calculator{}, fmt.Errorf("%s:%w", rs.version, ErrDuplicatedRuleSet)
+		}
 
-func multipleOf25CentsPoints(total float64) int {
-	const frac float64 = 0.25
-	if math.Mod(total, frac) == 0 {
-		return multipleOf25Points
+		seen[rs.version] = struct{}{}
 	}
 
-	return 0
+	return Calculator{versions: versions}, nil
 }
 
-func itemsPoints(items []receipt.Item) int {
-	return (len(items) / numItems) * itemsFactorPoints
-}
-
-func trimmedDescriptionPoints(items []receipt.Item) int {
-	total := 0
-
-	for _, item := range items {
-		x := strings.TrimSpace(item.ShortDescription)
-		l := len(x)
-
-		if l%3 == 0 {
-			test := item.Price * trimmedFactorPoints
-
-			if test == math.Trunc(test) {
-				total += int(test)
+// ruleSets returns the versions of the calculator, a zero Calculator scores
+// with the default rules.
+func (c Calculator) ruleSets() []*RuleSet {
+	if len(c.versions) == 0 {
+		return defaultVersions()
+	}
 
-				continue
-			}
+	return c.versions
+}
 
-			fl := fmt.Sprintf("%f", test)
-			text := strings.Split(fl, ".")
-			itext, err := strconv.Atoi(text[0])
+// Points scores the receipt with the rule set in effect at its purchase date.
+func (c Calculator) Points(rcpt receipt.Receipt) (*receipt.Points, error) {
+	versions := c.ruleSets()
+	active := versions[0]
 
-			if err == nil {
-				total += itext + 1
-			}
+	for _, rs := range versions[1:] {
+		if rs.effectiveFrom.After(rcpt.PurchaseDate) {
+			break
 		}
-	}
 
-	return total
-}
-
-func oddPurchaseDayPoints(date time.Time) int {
-	if date.Day()%2 == 0 {
-		return 0
+		active = rs
 	}
 
-	return oddDayPoints
+	return active.points(rcpt), nil
 }
 
-func timePurchasePoints(t time.Time) int {
-	if t.Hour() >= 14 && t.Hour() <= 16 {
-		return tPurchasePoints
+// PointsWithRuleSet scores the receipt with the given rule set version.
+func (c Calculator) PointsWithRuleSet(rcpt receipt.Receipt, version string) (*receipt.Points, error) {
+	for _, rs := range c.ruleSets() {
+		if rs.version == version {
+			return rs.points(rcpt), nil
+		}
 	}
 
-	return 0
+	return nil, fmt.Errorf("%s:%w", version, ErrUnknownRuleSet)
 }
@@ -31,7 +31,7 @@ func Test_RetailerNamePoints(t *testing.T) {
 		expectedPoints := c.expectedResult
 
 		t.Run(c.name, func(t *testing.T) {
-			points := retailerNamePoints(rname)
+			points := rulePoints(t, RuleRetailerName, receipt.Receipt{Retailer: rname})
 
 			assert.Equal(t, expectedPoints, points)
 		})
@@ -41,17 +41,17 @@ func Test_RetailerNamePoints(t *testing.T) {
 func Test_roundDollarPoints(t *testing.T) {
 	cases := []struct {
 		name           string
-		total          float64
+		total          receipt.Money
 		expectedResult int
 	}{
 		{
 			name:           "total-rounded",
-			total:          9.00,
+			total:          money(t, "9.00"),
 			expectedResult: 50,
 		},
 		{
 			name:           "total-no-rounded",
-			total:          9.25,
+			total:          money(t, "9.25"),
 			expectedResult: 0,
 		},
 	}
@@ -61,7 +61,7 @@ func Test_roundDollarPoints(t *testing.T) {
 		expectedPoints := c.expectedResult
 
 		t.Run(c.name, func(t *testing.T) {
-			points := roundDollarPoints(total)
+			points := rulePoints(t, RuleRoundDollar, receipt.Receipt{Total: total})
 
 			assert.Equal(t, expectedPoints, points)
 		})
@@ -71,17 +71,17 @@ func Test_roundDollarPoints(t *testing.T) {
 func Test_multipleOf25CentsPoints(t *testing.T) {
 	cases := []struct {
 		name           string
-		total          float64
+		total          receipt.Money
 		expectedResult int
 	}{
 		{
 			name:           "is-multiple",
-			total:          9.0,
+			total:          money(t, "9.00"),
 			expectedResult: 25,
 		},
 		{
 			name:           "is-not-multiple",
-			total:          9.1,
+			total:          money(t, "9.10"),
 			expectedResult: 0,
 		},
 	}
@@ -91,7 +91,7 @@ func Test_multipleOf25CentsPoints(t *testing.T) {
 		expectedPoints := c.expectedResult
 
 		t.Run(c.name, func(t *testing.T) {
-			points := multipleOf25CentsPoints(total)
+			points := rulePoints(t, RuleMultipleOf25, receipt.Receipt{Total: total})
 
 			assert.Equal(t, expectedPoints, points)
 		})
@@ -114,19 +114,19 @@ func Test_itemsPoints(t *testing.T) {
 			items: []receipt.Item{
 				{
 					ShortDescription: "Mountain Dew 12PK",
-					Price:            6.49,
+					Price:            money(t, "6.49"),
 				}, {
 					ShortDescription: "Emils Cheese Pizza",
-					Price:            12.25,
+					Price:            money(t, "12.25"),
 				}, {
 					ShortDescription: "Knorr Creamy Chicken",
-					Price:            1.26,
+					Price:            money(t, "1.26"),
 				}, {
 					ShortDescription: "Doritos Nacho Cheese",
-					Price:            3.35,
+					Price:            money(t, "3.35"),
 				}, {
 					ShortDescription: "   Klarbrunn 12-PK 12 FL OZ  ",
-					Price:            12.00,
+					Price:            money(t, "12.00"),
 				},
 			},
 			expectedResult: 10,
@@ -138,7 +138,7 @@ func Test_itemsPoints(t *testing.T) {
 		expectedPoints := c.expectedResult
 
 		t.Run(c.name, func(t *testing.T) {
-			points := itemsPoints(items)
+			points := rulePoints(t, RuleItemPairs, receipt.Receipt{Items: items})
 
 			assert.Equal(t, expectedPoints, points)
 		})
@@ -156,23 +156,34 @@ func Test_trimmedDescriptionPoints(t *testing.T) {
 			items: []receipt.Item{
 				{
 					ShortDescription: "Mountain Dew 12PK",
-					Price:            6.49,
+					Price:            money(t, "6.49"),
 				}, {
 					ShortDescription: "Emils Cheese Pizza",
-					Price:            12.25,
+					Price:            money(t, "12.25"),
 				}, {
 					ShortDescription: "Knorr Creamy Chicken",
-					Price:            1.26,
+					Price:            money(t, "1.26"),
 				}, {
 					ShortDescription: "Doritos Nacho Cheese",
-					Price:            3.35,
+					Price:            money(t, "3.35"),
 				}, {
 					ShortDescription: "   Klarbrunn 12-PK 12 FL OZ  ",
-					Price:            12.00,
+					Price:            money(t, "12.00"),
 				},
 			},
 			expectedResult: 6,
 		},
+		{
+			name: "exact-product-case",
+			items: []receipt.Item{
+				{
+					// 35.00 * 0.2 is 7.000000000000001 with float64.
+					ShortDescription: "Gatorade 6PK",
+					Price:            money(t, "35.00"),
+				},
+			},
+			expectedResult: 7,
+		},
 	}
 
 	for _, c := range cases {
@@ -180,7 +191,7 @@ func Test_trimmedDescriptionPoints(t *testing.T) {
 		expectedPoints := c.expectedResult
 
 		t.Run(c.name, func(t *testing.T) {
-			points := trimmedDescriptionPoints(items)
+			points := rulePoints(t, RuleItemDescription, receipt.Receipt{Items: items})
 
 			assert.Equal(t, expectedPoints, points)
 		})
@@ -213,7 +224,7 @@ func Test_oddPurchaseDayPoints(t *testing.T) {
 		}
 
 		t.Run(c.name, func(t *testing.T) {
-			points := oddPurchaseDayPoints(date)
+			points := rulePoints(t, RuleOddPurchaseDay, receipt.Receipt{PurchaseDate: date})
 
 			assert.Equal(t, expectedPoints, points)
 		})
@@ -246,7 +257,7 @@ func Test_timePurchasePoints(t *testing.T) {
 		}
 
 		t.Run(c.name, func(t *testing.T) {
-			points := timePurchasePoints(ctime)
+			points := rulePoints(t, RulePurchaseTimeSpan, receipt.Receipt{PurchaseTime: ctime})
 
 			assert.Equal(t, expectedPoints, points)
 		})
@@ -269,26 +280,26 @@ func Test_Points(t *testing.T) {
 				Items: []receipt.Item{
 					{
 						ShortDescription: "Mountain Dew 12PK",
-						Price:            6.49,
+						Price:            money(t, "6.49"),
 					},
 					{
 						ShortDescription: "Emils Cheese Pizza",
-						Price:            12.25,
+						Price:            money(t, "12.25"),
 					},
 					{
 						ShortDescription: "Knorr Creamy Chicken",
-						Price:            1.26,
+						Price:            money(t, "1.26"),
 					},
 					{
 						ShortDescription: "Doritos Nacho Cheese",
-						Price:            3.35,
+						Price:            money(t, "3.35"),
 					},
 					{
 						ShortDescription: "   Klarbrunn 12-PK 12 FL OZ  ",
-						Price:            12.00,
+						Price:            money(t, "12.00"),
 					},
 				},
-				Total: 35.35,
+				Total: money(t, "35.35"),
 			},
 			expectedResult: &receipt.Points{Points: 28},
 			expectedError:  nil,
@@ -302,22 +313,22 @@ func Test_Points(t *testing.T) {
 				Items: []receipt.Item{
 					{
 						ShortDescription: "Gatorade",
-						Price:            2.25,
+						Price:            money(t, "2.25"),
 					},
 					{
 						ShortDescription: "Gatorade",
-						Price:            12.25,
+						Price:            money(t, "12.25"),
 					},
 					{
 						ShortDescription: "Gatorade",
-						Price:            2.25,
+						Price:            money(t, "2.25"),
 					},
 					{
 						ShortDescription: "Gatorade",
-						Price:            12.25,
+						Price:            money(t, "12.25"),
 					},
 				},
-				Total: 9.00,
+				Total: money(t, "9.00"),
 			},
 			expectedResult: &receipt.Points{Points: 109},
 			expectedError:  nil,
@@ -333,12 +344,185 @@ func Test_Points(t *testing.T) {
 		t.Run(c.name, func(t *testing.T) {
 			points, err := cal.Points(r)
 
-			assert.Equal(t, expectedPoints, points)
+			assert.Equal(t, expectedPoints.Points, points.Points)
 			assert.Equal(t, expectedError, err)
 		})
 	}
 }
 
+func Test_PointsBreakdown(t *testing.T) {
+	items := []receipt.Item{
+		{
+			ShortDescription: "Mountain Dew 12PK",
+			Price:            money(t, "6.49"),
+		},
+		{
+			ShortDescription: "Emils Cheese Pizza",
+			Price:            money(t, "12.25"),
+		},
+		{
+			ShortDescription: "Knorr Creamy Chicken",
+			Price:            money(t, "1.26"),
+		},
+		{
+			ShortDescription: "Doritos Nacho Cheese",
+			Price:            money(t, "3.35"),
+		},
+		{
+			ShortDescription: "   Klarbrunn 12-PK 12 FL OZ  ",
+			Price:            money(t, "12.00"),
+		},
+	}
+	rcpt := receipt.Receipt{
+		Retailer:     "Target",
+		PurchaseDate: purchaseDate(t, "2022-01-01"),
+		PurchaseTime: purchaseTime(t, "13:01"),
+		Items:        items,
+		Total:        money(t, "35.35"),
+	}
+
+	points, err := New().Points(rcpt)
+	assert.NoError(t, err)
+
+	type award struct {
+		rule   string
+		points int
+		item   int
+	}
+
+	expected := []award{
+		{rule: RuleRetailerName, points: 6, item: -1},
+		{rule: RuleRoundDollar, points: 0, item: -1},
+		{rule: RuleMultipleOf25, points: 0, item: -1},
+		{rule: RuleItemPairs, points: 10, item: -1},
+		{rule: RuleItemDescription, points: 3, item: 1},
+		{rule: RuleItemDescription, points: 3, item: 4},
+		{rule: RuleOddPurchaseDay, points: 6, item: -1},
+		{rule: RulePurchaseTimeSpan, points: 0, item: -1},
+	}
+
+	result := make([]award, len(points.Breakdown))
+	total := 0
+
+	for i, a := range points.Breakdown {
+		result[i] = award{rule: a.Rule, points: a.Points, item: -1}
+		if a.Item != nil {
+			result[i].item = a.Item.Index
+			assert.Equal(t, items[a.Item.Index], a.Item.Item)
+		}
+
+		assert.NotEmpty(t, a.Description)
+		total += a.Points
+	}
+
+	assert.Equal(t, expected, result)
+	assert.Equal(t, points.Points, total)
+}
+
+func Test_RuleSetVersions(t *testing.T) {
+	v2Rules, err := NewRuleSet(retailerNameRule{
+		ruleInfo: ruleInfo{RuleRetailerName, "two points per character"},
+		points:   2,
+	})
+	assert.NoError(t, err)
+	v2Rules.Versioned("v2", purchaseDate(t, "2023-01-01"))
+
+	cal, err := NewWithRules(v2Rules, DefaultRules())
+	assert.NoError(t, err)
+
+	cases := []struct {
+		name            string
+		date            string
+		version         string
+		expectedPoints  int
+		expectedVersion string
+		expectedError   error
+	}{
+		{
+			name:            "before-v2-case",
+			date:            "2022-12-31",
+			expectedPoints:  6 + 50 + 25 + 6,
+			expectedVersion: DefaultVersion,
+		},
+		{
+			name:            "v2-effective-date-case",
+			date:            "2023-01-01",
+			expectedPoints:  12,
+			expectedVersion: "v2",
+		},
+		{
+			name:            "rescore-with-old-version-case",
+			date:            "2023-01-01",
+			version:         DefaultVersion,
+			expectedPoints:  6 + 50 + 25 + 6,
+			expectedVersion: DefaultVersion,
+		},
+		{
+			name:          "unknown-version-case",
+			date:          "2023-01-01",
+			version:       "v9",
+			expectedError: ErrUnknownRuleSet,
+		},
+	}
+
+	for _, c := range cases {
+		c := c
+		rcpt := receipt.Receipt{
+			Retailer:     "Target",
+			PurchaseDate: purchaseDate(t, c.date),
+			PurchaseTime: purchaseTime(t, "13:01"),
+			Total:        money(t, "10.00"),
+		}
+
+		t.Run(c.name, func(t *testing.T) {
+			var (
+				points *receipt.Points
+				err    error
+			)
+
+			if c.version == "" {
+				points, err = cal.Points(rcpt)
+			} else {
+				points, err = cal.PointsWithRuleSet(rcpt, c.version)
+			}
+
+			if c.expectedError != nil {
+				assert.ErrorIs(t, err, c.expectedError)
+
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, c.expectedPoints, points.Points)
+			assert.Equal(t, c.expectedVersion, points.RuleSetVersion)
+		})
+	}
+
+	_, err = NewWithRules(DefaultRules(), DefaultRules())
+	assert.ErrorIs(t, err, ErrDuplicatedRuleSet)
+}
+
+func rulePoints(t *testing.T, id string, rcpt receipt.Receipt) int {
+	t.Helper()
+
+	for _, rule := range DefaultRules().Rules() {
+		if rule.ID() != id {
+			continue
+		}
+
+		points := 0
+		for _, award := range rule.Apply(rcpt) {
+			points += award.Points
+		}
+
+		return points
+	}
+
+	t.Fatalf("rule %s not found", id)
+
+	return 0
+}
+
 func purchaseDate(t *testing.T, date string) time.Time {
 	tdate, err := time.Parse(receipt.DatePurchaseFormat, date)
 	if err != nil {
@@ -356,3 +540,38 @@ func purchaseTime(t *testing.T, date string) time.Time {
 
 	return tdate
 }
+
+func money(t *testing.T, amount string) receipt.Money {
+	t.Helper()
+
+	m, err := receipt.ParseMoney(amount)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return m
+}
+
+func Test_ZeroCalculator(t *testing.T) {
+	r := receipt.Receipt{
+		Retailer:     "Target",
+		PurchaseDate: purchaseDate(t, "2022-01-01"),
+		PurchaseTime: purchaseTime(t, "13:01"),
+		Items:        []receipt.Item{{ShortDescription: "Mountain Dew 12PK", Price: money(t, "6.49")}},
+		Total:        money(t, "6.49"),
+	}
+
+	expected, err := New().Points(r)
+	assert.NoError(t, err)
+
+	// a zero Calculator scores with the default rules.
+	cal := Calculator{}
+
+	points, err := cal.Points(r)
+	assert.NoError(t, err)
+	assert.Equal(t, expected, points)
+
+	points, err = cal.PointsWithRuleSet(r, expected.RuleSetVersion)
+	assert.NoError(t, err)
+	assert.Equal(t, expected, points)
+}
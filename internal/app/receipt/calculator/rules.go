@@ -0,0 +1,284 @@
+package calculator
+
+import (
+	"errors"
+	"fmt"
+	"receipt-processor-challenge/internal/domain/receipt"
+	"strings"
+	"time"
+	"unicode"
+)
+
+const (
+	RuleRetailerName     string = "retailer-name"
+	RuleRoundDollar      string = "round-dollar"
+	RuleMultipleOf25     string = "multiple-of-25-cents"
+	RuleItemPairs        string = "item-pairs"
+	RuleItemDescription  string = "item-description"
+	RuleOddPurchaseDay   string = "odd-purchase-day"
+	RulePurchaseTimeSpan string = "purchase-time"
+)
+
+// DefaultVersion is the version of the rule set returned by DefaultRules.
+const DefaultVersion string = "v1"
+
+var ErrDuplicatedRule = errors.New("duplicated rule")
+
+// Rule awards points to a receipt. Rules applied to every item of the receipt
+// return one award per item that earned points.
+type Rule interface {
+	ID() string
+	Apply(rcpt receipt.Receipt) []receipt.Award
+}
+
+// RuleSet keeps the rules in the order they were registered. A rule set is
+// identified by its version and applies to receipts purchased from its
+// effective date onwards.
+type RuleSet struct {
+	version       string
+	effectiveFrom time.Time
+	rules         []Rule
+	ids           map[string]struct{}
+}
+
+func NewRuleSet(rules ...Rule) (*RuleSet, error) {
+	rs := &RuleSet{
+		rules: make([]Rule, 0, len(rules)),
+		ids:   make(map[string]struct{}, len(rules)),
+	}
+
+	for _, rule := range rules {
+		if err := rs.Register(rule); err != nil {
+			return nil, err
+		}
+	}
+
+	return rs, nil
+}
+
+// Register appends a rule at the end of the set, rule ids must be unique.
+func (rs *RuleSet) Register(rule Rule) error {
+	if _, ok := rs.ids[rule.ID()]; ok {
+		return fmt.Errorf("%s:%w", rule.ID(), ErrDuplicatedRule)
+	}
+
+	rs.ids[rule.ID()] = struct{}{}
+	rs.rules = append(rs.rules, rule)
+
+	return nil
+}
+
+func (rs *RuleSet) Rules() []Rule {
+	return append([]Rule(nil), rs.rules...)
+}
+
+func (rs *RuleSet) Version() string {
+	return rs.version
+}
+
+func (rs *RuleSet) EffectiveFrom() time.Time {
+	return rs.effectiveFrom
+}
+
+// Versioned sets the version and the effective date of the rule set.
+func (rs *RuleSet) Versioned(version string, effectiveFrom time.Time) *RuleSet {
+	rs.version = version
+	rs.effectiveFrom = effectiveFrom
+
+	return rs
+}
+
+func (rs *RuleSet) points(rcpt receipt.Receipt) *receipt.Points {
+	breakdown := []receipt.Award{}
+	points := 0
+
+	for _, rule := range rs.rules {
+		for _, award := range rule.Apply(rcpt) {
+			points += award.Points
+			breakdown = append(breakdown, award)
+		}
+	}
+
+	return &receipt.Points{
+		Points:         points,
+		Breakdown:      breakdown,
+		RuleSetVersion: rs.version,
+	}
+}
+
+// DefaultRules returns the rules described by the challenge.
+func DefaultRules() *RuleSet {
+	rs, _ := NewRuleSet(
+		retailerNameRule{
+			ruleInfo: ruleInfo{RuleRetailerName, "One point for every alphanumeric character in the retailer name"},
+			points:   1,
+		},
+		roundDollarRule{
+			ruleInfo: ruleInfo{RuleRoundDollar, "50 points if the total is a round dollar amount with no cents"},
+			points:   roundTotalPoints,
+		},
+		totalMultipleRule{
+			ruleInfo: ruleInfo{RuleMultipleOf25, "25 points if the total is a multiple of 0.25"},
+			points:   multipleOf25Points,
+			multiple: multipleOf25Total,
+		},
+		itemGroupRule{
+			ruleInfo: ruleInfo{RuleItemPairs, "5 points for every two items on the receipt"},
+			points:   itemsFactorPoints,
+			every:    numItems,
+		},
+		itemDescriptionRule{
+			ruleInfo: ruleInfo{
+				RuleItemDescription,
+				"Trimmed description length is a multiple of 3, price * 0.2 rounded up",
+			},
+			multiple: descriptionMultiple,
+			factor:   trimmedFactorPoints,
+		},
+		oddPurchaseDayRule{
+			ruleInfo: ruleInfo{RuleOddPurchaseDay, "6 points if the day in the purchase date is odd"},
+			points:   oddDayPoints,
+		},
+		purchaseTimeRule{
+			ruleInfo: ruleInfo{RulePurchaseTimeSpan, "10 points if the time of purchase is after 2:00pm and before 4:00pm"},
+			points:   tPurchasePoints,
+			fromHour: fromPurchaseHour,
+			toHour:   toPurchaseHour,
+		},
+	)
+
+	return rs.Versioned(DefaultVersion, time.Time{})
+}
+
+type ruleInfo struct {
+	id          string
+	description string
+}
+
+func (ri ruleInfo) ID() string {
+	return ri.id
+}
+
+func (ri ruleInfo) award(points int) receipt.Award {
+	return receipt.Award{
+		Rule:        ri.id,
+		Description: ri.description,
+		Points:      points,
+	}
+}
+
+type retailerNameRule struct {
+	ruleInfo
+	points int
+}
+
+func (r retailerNameRule) Apply(rcpt receipt.Receipt) []receipt.Award {
+	points := 0
+
+	for _, c := range rcpt.Retailer {
+		if unicode.IsLetter(c) || unicode.IsNumber(c) {
+			points += r.points
+		}
+	}
+
+	return []receipt.Award{r.award(points)}
+}
+
+type roundDollarRule struct {
+	ruleInfo
+	points int
+}
+
+func (r roundDollarRule) Apply(rcpt receipt.Receipt) []receipt.Award {
+	if rcpt.Total.IsRoundDollar() {
+		return []receipt.Award{r.award(r.points)}
+	}
+
+	return []receipt.Award{r.award(0)}
+}
+
+type totalMultipleRule struct {
+	ruleInfo
+	points   int
+	multiple receipt.Money
+}
+
+func (r totalMultipleRule) Apply(rcpt receipt.Receipt) []receipt.Award {
+	if rcpt.Total.IsMultipleOf(r.multiple) {
+		return []receipt.Award{r.award(r.points)}
+	}
+
+	return []receipt.Award{r.award(0)}
+}
+
+type itemGroupRule struct {
+	ruleInfo
+	points int
+	every  int
+}
+
+func (r itemGroupRule) Apply(rcpt receipt.Receipt) []receipt.Award {
+	return []receipt.Award{r.award((len(rcpt.Items) / r.every) * r.points)}
+}
+
+type itemDescriptionRule struct {
+	ruleInfo
+	multiple int
+	factor   factor
+}
+
+func (r itemDescriptionRule) Apply(rcpt receipt.Receipt) []receipt.Award {
+	awards := make([]receipt.Award, 0, len(rcpt.Items))
+
+	for index, item := range rcpt.Items {
+		points := r.itemPoints(item)
+		if points == 0 {
+			continue
+		}
+
+		award := r.award(points)
+		award.Item = &receipt.AwardedItem{Index: index, Item: item}
+		awards = append(awards, award)
+	}
+
+	return awards
+}
+
+func (r itemDescriptionRule) itemPoints(item receipt.Item) int {
+	x := strings.TrimSpace(item.ShortDescription)
+	l := len(x)
+
+	if l%r.multiple != 0 {
+		return 0
+	}
+
+	return int(r.factor.ceil(item.Price))
+}
+
+type oddPurchaseDayRule struct {
+	ruleInfo
+	points int
+}
+
+func (r oddPurchaseDayRule) Apply(rcpt receipt.Receipt) []receipt.Award {
+	if rcpt.PurchaseDate.Day()%2 == 0 {
+		return []receipt.Award{r.award(0)}
+	}
+
+	return []receipt.Award{r.award(r.points)}
+}
+
+type purchaseTimeRule struct {
+	ruleInfo
+	points   int
+	fromHour int
+	toHour   int
+}
+
+func (r purchaseTimeRule) Apply(rcpt receipt.Receipt) []receipt.Award {
+	if rcpt.PurchaseTime.Hour() >= r.fromHour && rcpt.PurchaseTime.Hour() <= r.toHour {
+		return []receipt.Award{r.award(r.points)}
+	}
+
+	return []receipt.Award{r.award(0)}
+}
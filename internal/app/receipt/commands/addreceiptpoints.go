@@ -2,38 +2,163 @@ package commands
 
 import (
 	"context"
+	"errors"
 	"receipt-processor-challenge/internal/domain/receipt"
+	"receipt-processor-challenge/internal/tracing"
+	"strconv"
 
 	"github.com/google/uuid"
 )
 
+const (
+	fingerprintKeyPrefix string = "fingerprint:"
+	idempotencyKeyPrefix string = "idempotency-key:"
+)
+
 type Calculator interface {
 	Points(r receipt.Receipt) (*receipt.Points, error)
 }
 
+type Validator interface {
+	Validate(r receipt.Receipt) error
+}
+
+// DuplicateDetector applies the duplicate policy to the points of a receipt
+// that looks like a resubmission of a stored one.
+type DuplicateDetector interface {
+	Check(ctx context.Context, r receipt.Receipt, points *receipt.Points) error
+}
+
 type PointsSaver struct {
-	repo receipt.Repository
-	calc Calculator
+	repo      receipt.Repository
+	calc      Calculator
+	validator Validator
+	detector  DuplicateDetector
 }
 
 // NewAddReceiptPointsHandler Initializes an addReceiptPointsHandler.
-func NewSaverReceiptPoint(repo receipt.Repository, calc Calculator) PointsSaver {
+func NewSaverReceiptPoint(
+	repo receipt.Repository, calc Calculator, validator Validator, detector DuplicateDetector,
+) PointsSaver {
 	return PointsSaver{
-		repo: repo,
-		calc: calc,
+		repo:      repo,
+		calc:      calc,
+		validator: validator,
+		detector:  detector,
 	}
 }
 
-func (ps PointsSaver) SavePoints(ctx context.Context, r receipt.Receipt) (uuid.UUID, error) {
+// SavePoints scores and stores a receipt. A receipt with the same content as
+// a stored one, or sent again with the same idempotency key, is not stored
+// twice: the id of the stored receipt is returned with created set to false.
+// Receipts that only look like a stored one are handled by the duplicate
+// detector before they are stored, once no key of the submission is found.
+func (ps PointsSaver) SavePoints(
+	ctx context.Context, r receipt.Receipt, idempotencyKey string,
+) (id uuid.UUID, created bool, err error) {
+	ctx, span := tracing.Start(ctx, "app.SavePoints")
+	defer func() {
+		span.RecordError(err)
+		span.End()
+	}()
+
+	if err := ps.validator.Validate(r); err != nil {
+		return uuid.Nil, false, err
+	}
+
+	fingerprint := r.Fingerprint()
+	keys := []string{fingerprintKeyPrefix + fingerprint}
+
+	if idempotencyKey != "" {
+		keys = append([]string{idempotencyKeyPrefix + idempotencyKey}, keys...)
+	}
+
+	// a resubmission is answered with the stored receipt before the duplicate
+	// detector sees it, only new submissions can be suspected duplicates.
+	id, err = ps.storedKey(ctx, keys)
+	if err == nil {
+		return ps.storedReceipt(ctx, id, fingerprint)
+	}
+
+	if !errors.Is(err, receipt.ErrNotFound) {
+		return uuid.Nil, false, err
+	}
+
+	points, err := ps.points(ctx, r)
+	if err != nil {
+		return uuid.Nil, false, err
+	}
+
+	if err := ps.check(ctx, r, points); err != nil {
+		return uuid.Nil, false, err
+	}
+
+	// a concurrent submission can store one of the keys after the lookup.
+	id, err = ps.repo.Save(ctx, r, *points, keys...)
+	if errors.Is(err, receipt.ErrDuplicated) {
+		return ps.storedReceipt(ctx, id, fingerprint)
+	}
+
+	if err != nil {
+		return uuid.Nil, false, err
+	}
+
+	return id, true, nil
+}
+
+func (ps PointsSaver) points(ctx context.Context, r receipt.Receipt) (*receipt.Points, error) {
+	_, span := tracing.Start(ctx, "calculator.Points")
+	defer span.End()
+
 	points, err := ps.calc.Points(r)
 	if err != nil {
-		return uuid.Nil, err
+		span.RecordError(err)
+
+		return nil, err
 	}
 
-	id, err := ps.repo.Save(ctx, *points)
+	span.SetAttribute("receipt.points", strconv.Itoa(points.Points))
+	span.SetAttribute("receipt.rule_set", points.RuleSetVersion)
+
+	return points, nil
+}
+
+func (ps PointsSaver) check(ctx context.Context, r receipt.Receipt, points *receipt.Points) error {
+	ctx, span := tracing.Start(ctx, "duplicates.Check")
+	defer span.End()
+
+	err := ps.detector.Check(ctx, r, points)
+	span.RecordError(err)
+
+	return err
+}
+
+// storedKey returns the id of the receipt stored with the first of the keys
+// found, ErrNotFound when none of them is stored.
+func (ps PointsSaver) storedKey(ctx context.Context, keys []string) (uuid.UUID, error) {
+	for _, key := range keys {
+		id, err := ps.repo.FindByKey(ctx, key)
+		if !errors.Is(err, receipt.ErrNotFound) {
+			return id, err
+		}
+	}
+
+	return uuid.Nil, receipt.ErrNotFound
+}
+
+// storedReceipt checks that the stored receipt has the same content as the
+// submitted one, it only differs when an idempotency key was reused.
+func (ps PointsSaver) storedReceipt(
+	ctx context.Context, id uuid.UUID, fingerprint string,
+) (uuid.UUID, bool, error) {
+	stored, err := ps.repo.GetReceipt(ctx, id)
 	if err != nil {
-		return uuid.Nil, err
+		return uuid.Nil, false, err
+	}
+
+	if stored.Fingerprint() != fingerprint {
+		return uuid.Nil, false, receipt.ErrIdempotencyKeyReused
 	}
 
-	return id, nil
+	return id, false, nil
 }
@@ -0,0 +1,126 @@
+package commands
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"receipt-processor-challenge/internal/app/receipt/calculator"
+	"receipt-processor-challenge/internal/app/receipt/duplicates"
+	"receipt-processor-challenge/internal/domain/receipt"
+	"receipt-processor-challenge/internal/interfaceadapters/storage/memory"
+	"receipt-processor-challenge/internal/tracing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_SavePointsIdempotency(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	repo := memory.New(ctx)
+	detector, _ := duplicates.New(repo, duplicates.PolicyReject, duplicates.DefaultSimilarity)
+	saver := NewSaverReceiptPoint(repo, calculator.New(), receipt.NewValidator(0), detector)
+
+	newReceipt := func(retailer string) receipt.Receipt {
+		return receipt.Receipt{
+			Retailer:     retailer,
+			PurchaseDate: time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC),
+			PurchaseTime: time.Date(0, 1, 1, 13, 1, 0, 0, time.UTC),
+			Items: []receipt.Item{
+				{ShortDescription: "Mountain Dew 12PK", Price: 649},
+				{ShortDescription: "Emils Cheese Pizza", Price: 1225},
+			},
+			Total: 1874,
+		}
+	}
+
+	firstID, created, err := saver.SavePoints(ctx, newReceipt("Idempotency Market"), "key-1")
+	assert.NoError(t, err)
+	assert.True(t, created)
+
+	// retry with the same key.
+	id, created, err := saver.SavePoints(ctx, newReceipt("Idempotency Market"), "key-1")
+	assert.NoError(t, err)
+	assert.False(t, created)
+	assert.Equal(t, firstID, id)
+
+	// same content without key, items in a different order.
+	reordered := newReceipt("Idempotency  Market ")
+	reordered.Items[0], reordered.Items[1] = reordered.Items[1], reordered.Items[0]
+
+	id, created, err = saver.SavePoints(ctx, reordered, "")
+	assert.NoError(t, err)
+	assert.False(t, created)
+	assert.Equal(t, firstID, id)
+
+	// same key, different receipt.
+	id, created, err = saver.SavePoints(ctx, newReceipt("Another Market"), "key-1")
+	assert.ErrorIs(t, err, receipt.ErrIdempotencyKeyReused)
+	assert.False(t, created)
+	assert.Equal(t, uuid.Nil, id)
+
+	// same key, a receipt similar to the stored one: the key is resolved
+	// before the duplicate detector rejects it.
+	similar := newReceipt("Idempotency Market")
+	similar.Items[1].ShortDescription = "Emils Cheese Pizzas"
+
+	id, created, err = saver.SavePoints(ctx, similar, "key-1")
+	assert.ErrorIs(t, err, receipt.ErrIdempotencyKeyReused)
+	assert.NotErrorIs(t, err, receipt.ErrSuspectedDuplicate)
+	assert.False(t, created)
+	assert.Equal(t, uuid.Nil, id)
+
+	// different receipt, new key.
+	id, created, err = saver.SavePoints(ctx, newReceipt("Another Market"), "key-2")
+	assert.NoError(t, err)
+	assert.True(t, created)
+	assert.NotEqual(t, firstID, id)
+}
+
+// spansExporter keeps the names of the exported spans.
+type spansExporter struct {
+	names []string
+}
+
+func (e *spansExporter) Export(_ context.Context, spans []tracing.SpanData) error {
+	for i := range spans {
+		e.names = append(e.names, spans[i].Name)
+	}
+
+	return nil
+}
+
+func (e *spansExporter) Shutdown(context.Context) error {
+	return nil
+}
+
+func Test_SavePointsTracing(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	repo := tracing.NewRepository(memory.New(ctx))
+	detector, _ := duplicates.New(repo, duplicates.PolicyFlag, duplicates.DefaultSimilarity)
+	saver := NewSaverReceiptPoint(repo, calculator.New(), receipt.NewValidator(0), detector)
+
+	exporter := &spansExporter{}
+	tracer := tracing.New(exporter)
+	spanCtx, root := tracer.Start(ctx, "request", tracing.KindServer, tracing.SpanContext{})
+
+	_, _, err := saver.SavePoints(spanCtx, receipt.Receipt{
+		Retailer:     "Target",
+		PurchaseDate: time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC),
+		PurchaseTime: time.Date(0, 1, 1, 13, 1, 0, 0, time.UTC),
+		Items:        []receipt.Item{{ShortDescription: "Mountain Dew 12PK", Price: 649}},
+		Total:        649,
+	}, "")
+	assert.NoError(t, err)
+
+	root.End()
+	assert.NoError(t, tracer.Shutdown(context.Background()))
+	assert.Equal(t, []string{
+		"storage.FindByKey", "calculator.Points", "storage.FindMatching", "duplicates.Check", "storage.Save",
+		"app.SavePoints", "request",
+	}, exporter.names)
+}
@@ -0,0 +1,139 @@
+package duplicates
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"receipt-processor-challenge/internal/domain/receipt"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// PolicyReject refuses receipts that look like a resubmission.
+	PolicyReject string = "reject"
+	// PolicyFlag stores and scores the receipt, flagging it as a duplicate.
+	PolicyFlag string = "flag"
+	// PolicyScoreZero stores and flags the receipt without awarding points.
+	PolicyScoreZero string = "score-zero"
+
+	// RuleDuplicate is the award that cancels the points of a receipt scored
+	// with PolicyScoreZero.
+	RuleDuplicate string = "duplicate-receipt"
+
+	DefaultPolicy     string  = PolicyFlag
+	DefaultSimilarity float64 = 0.8
+)
+
+var (
+	ErrUnknownPolicy     = errors.New("unknown duplicate policy")
+	ErrInvalidSimilarity = errors.New("similarity must be between 0 and 1")
+)
+
+// Finder is the part of the repository the detector needs.
+type Finder interface {
+	FindMatching(ctx context.Context, rcpt receipt.Receipt) ([]uuid.UUID, error)
+	GetReceipt(ctx context.Context, id uuid.UUID) (*receipt.Receipt, error)
+}
+
+/*
+Detector looks for stored receipts with the same retailer, purchase date and
+time and total as a new receipt, and whose items are at least as similar as the
+configured threshold. When one is found the policy decides what happens to the
+new receipt.
+
+Receipts with exactly the same content are left to the repository, they are
+retries of the same submission and return the stored id.
+*/
+type Detector struct {
+	finder     Finder
+	policy     string
+	similarity float64
+}
+
+func New(finder Finder, policy string, similarity float64) (Detector, error) {
+	switch policy {
+	case PolicyReject, PolicyFlag, PolicyScoreZero:
+	default:
+		return Detector{}, fmt.Errorf("%s:%w", policy, ErrUnknownPolicy)
+	}
+
+	if similarity <= 0 || similarity > 1 {
+		return Detector{}, fmt.Errorf("%v:%w", similarity, ErrInvalidSimilarity)
+	}
+
+	return Detector{
+		finder:     finder,
+		policy:     policy,
+		similarity: similarity,
+	}, nil
+}
+
+// Check applies the policy to the points of the receipt when it looks like a
+// resubmission, PolicyReject returns an error wrapping ErrSuspectedDuplicate.
+func (d Detector) Check(ctx context.Context, rcpt receipt.Receipt, points *receipt.Points) error {
+	duplicate, err := d.find(ctx, rcpt)
+	if err != nil || duplicate == nil {
+		return err
+	}
+
+	switch d.policy {
+	case PolicyReject:
+		return fmt.Errorf("%s:%w", duplicate.Of, receipt.ErrSuspectedDuplicate)
+	case PolicyScoreZero:
+		points.Breakdown = append(points.Breakdown, receipt.Award{
+			Rule:        RuleDuplicate,
+			Description: fmt.Sprintf("Resubmission of receipt %s, no points awarded", duplicate.Of),
+			Points:      -points.Points,
+		})
+		points.Points = 0
+	}
+
+	points.Duplicate = duplicate
+
+	return nil
+}
+
+// find returns the most similar stored receipt over the threshold.
+func (d Detector) find(ctx context.Context, rcpt receipt.Receipt) (*receipt.Duplicate, error) {
+	ids, err := d.finder.FindMatching(ctx, rcpt)
+	if err != nil {
+		return nil, err
+	}
+
+	fingerprint := rcpt.Fingerprint()
+
+	var found *receipt.Duplicate
+
+	for _, id := range ids {
+		stored, err := d.finder.GetReceipt(ctx, id)
+		if errors.Is(err, receipt.ErrNotFound) || errors.Is(err, receipt.ErrEvicted) {
+			// removed by the repository retention since it was matched.
+			continue
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		if stored.Fingerprint() == fingerprint {
+			// a retry of a stored submission, not a duplicate.
+			found = nil
+
+			break
+		}
+
+		similarity := receipt.ItemsSimilarity(rcpt.Items, stored.Items)
+		if similarity < d.similarity || (found != nil && similarity <= found.Similarity) {
+			continue
+		}
+
+		found = &receipt.Duplicate{
+			Of:         id,
+			Similarity: similarity,
+			Policy:     d.policy,
+		}
+	}
+
+	return found, nil
+}
@@ -0,0 +1,124 @@
+package duplicates
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"receipt-processor-challenge/internal/domain/receipt"
+	"receipt-processor-challenge/internal/interfaceadapters/storage/memory"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newReceipt(retailer string, descriptions ...string) receipt.Receipt {
+	rcpt := receipt.Receipt{
+		Retailer:     retailer,
+		PurchaseDate: time.Date(2022, 3, 20, 0, 0, 0, 0, time.UTC),
+		PurchaseTime: time.Date(0, 1, 1, 14, 33, 0, 0, time.UTC),
+	}
+
+	for _, description := range descriptions {
+		rcpt.Items = append(rcpt.Items, receipt.Item{ShortDescription: description, Price: 225})
+		rcpt.Total += 225
+	}
+
+	return rcpt
+}
+
+func Test_New(t *testing.T) {
+	_, err := New(nil, "ignore", DefaultSimilarity)
+	assert.ErrorIs(t, err, ErrUnknownPolicy)
+
+	_, err = New(nil, PolicyFlag, 1.5)
+	assert.ErrorIs(t, err, ErrInvalidSimilarity)
+
+	_, err = New(nil, PolicyScoreZero, DefaultSimilarity)
+	assert.NoError(t, err)
+}
+
+func Test_Check(t *testing.T) {
+	ctx := context.Background()
+	repo := memory.New(ctx)
+
+	stored := newReceipt("Duplicates Market", "Gatorade", "Doritos Nacho Cheese", "Pepsi 12PK")
+	storedID, err := repo.Save(ctx, stored, receipt.Points{Points: 40})
+	assert.NoError(t, err)
+
+	cases := []struct {
+		name              string
+		policy            string
+		receipt           receipt.Receipt
+		expectedPoints    int
+		expectedDuplicate bool
+		expectedError     error
+	}{
+		{
+			name:              "flag-case",
+			policy:            PolicyFlag,
+			receipt:           newReceipt("Duplicates  Market", "Gatorade", "Doritos Nacho Chese", "Pepsi 12PK"),
+			expectedPoints:    40,
+			expectedDuplicate: true,
+		},
+		{
+			name:              "score-zero-case",
+			policy:            PolicyScoreZero,
+			receipt:           newReceipt("Duplicates Market", "Pepsi 12 PK", "Gatorade", "Doritos Nacho Cheese"),
+			expectedPoints:    0,
+			expectedDuplicate: true,
+		},
+		{
+			name:          "reject-case",
+			policy:        PolicyReject,
+			receipt:       newReceipt("duplicates market", "Gatorade", "Doritos Nacho", "Pepsi 12PK"),
+			expectedError: receipt.ErrSuspectedDuplicate,
+		},
+		{
+			name:           "same-content-case",
+			policy:         PolicyReject,
+			receipt:        newReceipt("Duplicates Market", "Pepsi 12PK", "Gatorade", "Doritos Nacho Cheese"),
+			expectedPoints: 40,
+		},
+		{
+			name:           "different-items-case",
+			policy:         PolicyReject,
+			receipt:        newReceipt("Duplicates Market", "Milk", "Bread", "Eggs"),
+			expectedPoints: 40,
+		},
+		{
+			name:           "different-retailer-case",
+			policy:         PolicyReject,
+			receipt:        newReceipt("Another Market", "Gatorade", "Doritos Nacho Cheese", "Pepsi 12PK"),
+			expectedPoints: 40,
+		},
+	}
+
+	for _, c := range cases {
+		c := c
+
+		t.Run(c.name, func(t *testing.T) {
+			detector, err := New(repo, c.policy, DefaultSimilarity)
+			assert.NoError(t, err)
+
+			points := &receipt.Points{Points: 40}
+
+			err = detector.Check(ctx, c.receipt, points)
+			assert.ErrorIs(t, err, c.expectedError)
+
+			if c.expectedError != nil {
+				return
+			}
+
+			assert.Equal(t, c.expectedPoints, points.Points)
+
+			if !c.expectedDuplicate {
+				assert.Nil(t, points.Duplicate)
+
+				return
+			}
+
+			assert.Equal(t, storedID, points.Duplicate.Of)
+			assert.Equal(t, c.policy, points.Duplicate.Policy)
+		})
+	}
+}
@@ -0,0 +1,20 @@
+package queries
+
+import (
+	"context"
+	"receipt-processor-challenge/internal/domain/receipt"
+)
+
+type FlaggedGetter struct {
+	repo receipt.Repository
+}
+
+// NewGetterFlaggedReceipts Constructor.
+func NewGetterFlaggedReceipts(repo receipt.Repository) FlaggedGetter {
+	return FlaggedGetter{repo: repo}
+}
+
+// GetFlagged returns the receipts flagged as possible resubmissions.
+func (fg FlaggedGetter) GetFlagged(ctx context.Context) ([]receipt.FlaggedReceipt, error) {
+	return fg.repo.Flagged(ctx)
+}
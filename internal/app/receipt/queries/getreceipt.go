@@ -0,0 +1,22 @@
+package queries
+
+import (
+	"context"
+	"receipt-processor-challenge/internal/domain/receipt"
+
+	"github.com/google/uuid"
+)
+
+type ReceiptGetter struct {
+	repo receipt.Repository
+}
+
+// NewGetterReceipt Handler Constructor.
+func NewGetterReceipt(repo receipt.Repository) ReceiptGetter {
+	return ReceiptGetter{repo: repo}
+}
+
+// GetReceipt returns the original receipt stored with the given id.
+func (rg ReceiptGetter) GetReceipt(ctx context.Context, id uuid.UUID) (*receipt.Receipt, error) {
+	return rg.repo.GetReceipt(ctx, id)
+}
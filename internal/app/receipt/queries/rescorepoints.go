@@ -0,0 +1,55 @@
+package queries
+
+import (
+	"context"
+	"receipt-processor-challenge/internal/domain/receipt"
+	"receipt-processor-challenge/internal/tracing"
+
+	"github.com/google/uuid"
+)
+
+type RuleSetCalculator interface {
+	PointsWithRuleSet(r receipt.Receipt, version string) (*receipt.Points, error)
+}
+
+type PointsRescorer struct {
+	repo receipt.Repository
+	calc RuleSetCalculator
+}
+
+// NewRescorerReceiptPoints Handler Constructor.
+func NewRescorerReceiptPoints(repo receipt.Repository, calc RuleSetCalculator) PointsRescorer {
+	return PointsRescorer{
+		repo: repo,
+		calc: calc,
+	}
+}
+
+// RescorePoints scores a stored receipt again with the given rule set version,
+// an empty version uses the rule set that produced the stored points. The
+// stored points are not modified.
+func (pr PointsRescorer) RescorePoints(ctx context.Context, id uuid.UUID, version string) (*receipt.Points, error) {
+	rcpt, err := pr.repo.GetReceipt(ctx, id)
+	if err != nil || rcpt == nil {
+		return nil, err
+	}
+
+	if version == "" {
+		points, err := pr.repo.Get(ctx, id)
+		if err != nil || points == nil {
+			return nil, err
+		}
+
+		version = points.RuleSetVersion
+	}
+
+	_, span := tracing.Start(ctx, "calculator.PointsWithRuleSet")
+	defer span.End()
+
+	span.SetAttribute("receipt.rule_set", version)
+
+	points, err := pr.calc.PointsWithRuleSet(*rcpt, version)
+	span.RecordError(err)
+
+	return points, err
+}
@@ -6,16 +6,30 @@ import (
 	"receipt-processor-challenge/internal/domain/receipt"
 )
 
+// Calculator scores receipts with the rules in effect and with a given rule set version.
+type Calculator interface {
+	commands.Calculator
+	queries.RuleSetCalculator
+}
+
 // Services contains all exposed services of the application layer.
 type Service struct {
 	commands.PointsSaver
 	queries.PointsGetter
+	queries.ReceiptGetter
+	queries.PointsRescorer
+	queries.FlaggedGetter
 }
 
 // NewServices Bootstraps Application Layer dependencies.
-func NewServices(repo receipt.Repository, calc commands.Calculator) Service {
+func NewServices(
+	repo receipt.Repository, calc Calculator, validator commands.Validator, detector commands.DuplicateDetector,
+) Service {
 	return Service{
-		commands.NewSaverReceiptPoint(repo, calc),
+		commands.NewSaverReceiptPoint(repo, calc, validator, detector),
 		queries.NewGetterReceiptPoints(repo),
+		queries.NewGetterReceipt(repo),
+		queries.NewRescorerReceiptPoints(repo, calc),
+		queries.NewGetterFlaggedReceipts(repo),
 	}
 }
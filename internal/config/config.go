@@ -0,0 +1,384 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"receipt-processor-challenge/internal/app/receipt/duplicates"
+	"receipt-processor-challenge/internal/domain/receipt"
+	"receipt-processor-challenge/internal/interfaceadapters/storage"
+	"receipt-processor-challenge/internal/tracing"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	LevelDebug string = "debug"
+	LevelInfo  string = "info"
+	LevelWarn  string = "warn"
+	LevelError string = "error"
+
+	FormatText string = "text"
+	FormatJSON string = "json"
+
+	defaultAddr            string        = ":8080"
+	defaultStorageDir      string        = "data"
+	defaultStorageTimeOut  time.Duration = time.Second
+	defaultShutdownTimeOut time.Duration = 10 * time.Second
+
+	defaultBatchMaxSize     = 10000
+	defaultBatchConcurrency = 8
+	defaultMaxBodyBytes     = 16 << 20
+)
+
+var ErrInvalidConfig = errors.New("invalid config")
+
+// Duration is a time.Duration written as text, e.g. "1.5s" or "10m".
+type Duration time.Duration
+
+func (d *Duration) UnmarshalText(text []byte) error {
+	value, err := time.ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+
+	*d = Duration(value)
+
+	return nil
+}
+
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(d).String()), nil
+}
+
+/*
+Config is the configuration of the service. It is loaded by Load from, in
+increasing order of precedence, the defaults, a YAML or JSON file, the
+environment variables and the command line flags.
+*/
+type Config struct {
+	Server     Server     `json:"server"     yaml:"server"`
+	Storage    Storage    `json:"storage"    yaml:"storage"`
+	Rules      Rules      `json:"rules"      yaml:"rules"`
+	Duplicates Duplicates `json:"duplicates" yaml:"duplicates"`
+	Log        Log        `json:"log"        yaml:"log"`
+	Tracing    Tracing    `json:"tracing"    yaml:"tracing"`
+}
+
+type Server struct {
+	Addr            string   `json:"addr"            yaml:"addr"`
+	ReadTimeOut     Duration `json:"readTimeout"     yaml:"readTimeout"`
+	WriteTimeOut    Duration `json:"writeTimeout"    yaml:"writeTimeout"`
+	IdleTimeOut     Duration `json:"idleTimeout"     yaml:"idleTimeout"`
+	ShutdownTimeOut Duration `json:"shutdownTimeout" yaml:"shutdownTimeout"`
+	TLS             TLS      `json:"tls"             yaml:"tls"`
+	Batch           Batch    `json:"batch"           yaml:"batch"`
+	// MaxBodyBytes is the maximum size of a request body.
+	MaxBodyBytes int64 `json:"maxBodyBytes" yaml:"maxBodyBytes"`
+	// AdminToken is the bearer token of the admin endpoints, they are not
+	// served without it.
+	AdminToken string `json:"adminToken" yaml:"adminToken"`
+}
+
+// TLS serves HTTPS when both files are set.
+type TLS struct {
+	CertFile string `json:"certFile" yaml:"certFile"`
+	KeyFile  string `json:"keyFile"  yaml:"keyFile"`
+}
+
+func (t TLS) Enabled() bool {
+	return t.CertFile != "" || t.KeyFile != ""
+}
+
+// Batch limits the receipts of a POST /receipt/process/batch request and how
+// many of them are processed at the same time.
+type Batch struct {
+	MaxSize     int `json:"maxSize"     yaml:"maxSize"`
+	Concurrency int `json:"concurrency" yaml:"concurrency"`
+}
+
+type Storage struct {
+	Backend        string   `json:"backend"        yaml:"backend"`
+	Dir            string   `json:"dir"            yaml:"dir"`
+	DSN            string   `json:"dsn"            yaml:"dsn"`
+	TimeOut        Duration `json:"timeout"        yaml:"timeout"`
+	SnapshotEvery  int      `json:"snapshotEvery"  yaml:"snapshotEvery"`
+	MemorySnapshot string   `json:"memorySnapshot" yaml:"memorySnapshot"`
+	Capacity       int      `json:"capacity"       yaml:"capacity"`
+	MaxBytes       int64    `json:"maxBytes"       yaml:"maxBytes"`
+	TTL            Duration `json:"ttl"            yaml:"ttl"`
+	Cache          Cache    `json:"cache"          yaml:"cache"`
+}
+
+type Cache struct {
+	Backend   string   `json:"backend"   yaml:"backend"`
+	Size      int      `json:"size"      yaml:"size"`
+	TTL       Duration `json:"ttl"       yaml:"ttl"`
+	RedisAddr string   `json:"redisAddr" yaml:"redisAddr"`
+}
+
+type Rules struct {
+	// Path is a rule set file or a directory of rule set files, the default
+	// rules are used when it is empty.
+	Path           string `json:"path"           yaml:"path"`
+	TotalTolerance string `json:"totalTolerance" yaml:"totalTolerance"`
+}
+
+type Duplicates struct {
+	Policy     string  `json:"policy"     yaml:"policy"`
+	Similarity float64 `json:"similarity" yaml:"similarity"`
+}
+
+type Log struct {
+	Level  string `json:"level"  yaml:"level"`
+	Format string `json:"format" yaml:"format"`
+}
+
+// Tracing is disabled when Exporter is empty. File is the output of the file
+// exporter and Endpoint the collector of the OTLP exporter.
+type Tracing struct {
+	Exporter    string  `json:"exporter"    yaml:"exporter"`
+	File        string  `json:"file"        yaml:"file"`
+	Endpoint    string  `json:"endpoint"    yaml:"endpoint"`
+	SampleRatio float64 `json:"sampleRatio" yaml:"sampleRatio"`
+}
+
+// Default returns the configuration used when nothing is set.
+func Default() Config {
+	return Config{
+		Server: Server{
+			Addr:            defaultAddr,
+			ShutdownTimeOut: Duration(defaultShutdownTimeOut),
+			MaxBodyBytes:    defaultMaxBodyBytes,
+			Batch: Batch{
+				MaxSize:     defaultBatchMaxSize,
+				Concurrency: defaultBatchConcurrency,
+			},
+		},
+		Storage: Storage{
+			Backend: storage.BackendMemory,
+			Dir:     defaultStorageDir,
+			TimeOut: Duration(defaultStorageTimeOut),
+		},
+		Rules: Rules{
+			TotalTolerance: "0.00",
+		},
+		Duplicates: Duplicates{
+			Policy:     duplicates.DefaultPolicy,
+			Similarity: duplicates.DefaultSimilarity,
+		},
+		Log: Log{
+			Level:  LevelInfo,
+			Format: FormatText,
+		},
+		Tracing: Tracing{
+			SampleRatio: 1,
+		},
+	}
+}
+
+/*
+Load builds the configuration from the command line arguments (without the
+program name) and the environment, getenv is usually os.Getenv. The file is set
+with the -config flag or the CONFIG_FILE variable. The result is validated.
+*/
+func Load(args []string, getenv func(string) string) (Config, error) {
+	fs := flag.NewFlagSet("receipt-processor-challenge", flag.ContinueOnError)
+	file := fs.String("config", getenv(envConfigFile), "YAML or JSON configuration file")
+	flags := map[string]string{}
+
+	for _, s := range settings {
+		name := s.flag
+		fs.Func(name, s.usage, func(value string) error {
+			flags[name] = value
+
+			return nil
+		})
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return Config{}, fmt.Errorf("%s:%w", err.Error(), ErrInvalidConfig)
+	}
+
+	cfg := Default()
+
+	if *file != "" {
+		if err := cfg.readFile(*file); err != nil {
+			return Config{}, err
+		}
+	}
+
+	errs := []error{}
+
+	for _, s := range settings {
+		if value := getenv(s.env); value != "" {
+			errs = append(errs, s.apply(&cfg, s.env, value))
+		}
+	}
+
+	for _, s := range settings {
+		if value, ok := flags[s.flag]; ok {
+			errs = append(errs, s.apply(&cfg, "-"+s.flag, value))
+		}
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return Config{}, err
+	}
+
+	return cfg, cfg.Validate()
+}
+
+// readFile overrides the configuration with the fields set in the file, the
+// format is JSON for .json files and YAML otherwise.
+func (c *Config) readFile(path string) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		decoder := json.NewDecoder(bytes.NewReader(content))
+		decoder.DisallowUnknownFields()
+		err = decoder.Decode(c)
+	} else {
+		decoder := yaml.NewDecoder(bytes.NewReader(content))
+		decoder.KnownFields(true)
+		err = decoder.Decode(c)
+	}
+
+	if err != nil {
+		return fmt.Errorf("%s %s:%w", path, err.Error(), ErrInvalidConfig)
+	}
+
+	return nil
+}
+
+// Validate returns every invalid setting.
+func (c Config) Validate() error {
+	errs := []error{}
+	invalid := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf("%s:%w", fmt.Sprintf(format, args...), ErrInvalidConfig))
+	}
+
+	if _, _, err := net.SplitHostPort(c.Server.Addr); err != nil {
+		invalid("server addr %q", c.Server.Addr)
+	}
+
+	for name, d := range map[string]Duration{
+		"server readTimeout":     c.Server.ReadTimeOut,
+		"server writeTimeout":    c.Server.WriteTimeOut,
+		"server idleTimeout":     c.Server.IdleTimeOut,
+		"server shutdownTimeout": c.Server.ShutdownTimeOut,
+		"storage ttl":            c.Storage.TTL,
+		"storage cache ttl":      c.Storage.Cache.TTL,
+	} {
+		if d < 0 {
+			invalid("%s is negative", name)
+		}
+	}
+
+	if (c.Server.TLS.CertFile == "") != (c.Server.TLS.KeyFile == "") {
+		invalid("server tls needs both certFile and keyFile")
+	}
+
+	if c.Server.MaxBodyBytes <= 0 {
+		invalid("server maxBodyBytes must be greater than zero")
+	}
+
+	if c.Server.Batch.MaxSize <= 0 || c.Server.Batch.Concurrency <= 0 {
+		invalid("server batch maxSize and concurrency must be greater than zero")
+	}
+
+	switch c.Storage.Backend {
+	case storage.BackendMemory, storage.BackendFile, storage.BackendSQLite:
+	case storage.BackendPostgres:
+		if c.Storage.DSN == "" {
+			invalid("storage postgres backend needs a dsn")
+		}
+	default:
+		invalid("storage backend %q", c.Storage.Backend)
+	}
+
+	switch c.Storage.Cache.Backend {
+	case "", storage.CacheLRU, storage.CacheRedis:
+	default:
+		invalid("storage cache backend %q", c.Storage.Cache.Backend)
+	}
+
+	// the cache would keep answering the receipts the memory storage removed,
+	// instead of 410 Gone.
+	retention := c.Storage.Capacity > 0 || c.Storage.MaxBytes > 0 || c.Storage.TTL > 0
+	if c.Storage.Backend == storage.BackendMemory && retention && c.Storage.Cache.Backend != "" {
+		invalid("storage cache can not be used with the memory retention limits")
+	}
+
+	if c.Storage.TimeOut <= 0 {
+		invalid("storage timeout must be greater than zero")
+	}
+
+	if c.Storage.SnapshotEvery < 0 || c.Storage.Capacity < 0 || c.Storage.MaxBytes < 0 || c.Storage.Cache.Size < 0 {
+		invalid("storage sizes can not be negative")
+	}
+
+	if c.Rules.Path != "" {
+		if _, err := os.Stat(c.Rules.Path); err != nil {
+			invalid("rules path %s", err.Error())
+		}
+	}
+
+	if tolerance, err := receipt.ParseMoney(c.Rules.TotalTolerance); err != nil || tolerance < 0 {
+		invalid("rules totalTolerance %q", c.Rules.TotalTolerance)
+	}
+
+	switch c.Duplicates.Policy {
+	case duplicates.PolicyFlag, duplicates.PolicyReject, duplicates.PolicyScoreZero:
+	default:
+		invalid("duplicates policy %q", c.Duplicates.Policy)
+	}
+
+	if c.Duplicates.Similarity <= 0 || c.Duplicates.Similarity > 1 {
+		invalid("duplicates similarity must be between 0 and 1")
+	}
+
+	switch c.Log.Level {
+	case LevelDebug, LevelInfo, LevelWarn, LevelError:
+	default:
+		invalid("log level %q", c.Log.Level)
+	}
+
+	switch c.Log.Format {
+	case FormatText, FormatJSON:
+	default:
+		invalid("log format %q", c.Log.Format)
+	}
+
+	switch c.Tracing.Exporter {
+	case "", tracing.ExporterStdout:
+	case tracing.ExporterFile:
+		if c.Tracing.File == "" {
+			invalid("tracing file exporter needs a file")
+		}
+	case tracing.ExporterOTLP:
+		if u, err := url.Parse(c.Tracing.Endpoint); err != nil || u.Scheme == "" || u.Host == "" {
+			invalid("tracing otlp endpoint %q", c.Tracing.Endpoint)
+		}
+	default:
+		invalid("tracing exporter %q", c.Tracing.Exporter)
+	}
+
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		invalid("tracing sampleRatio must be between 0 and 1")
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,143 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	. "receipt-processor-challenge/internal/config"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func env(values map[string]string) func(string) string {
+	return func(key string) string {
+		return values[key]
+	}
+}
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func Test_LoadDefaults(t *testing.T) {
+	cfg, err := Load(nil, env(nil))
+	assert.NoError(t, err)
+	assert.Equal(t, Default(), cfg)
+	assert.Equal(t, ":8080", cfg.Server.Addr)
+	assert.Equal(t, "memory", cfg.Storage.Backend)
+}
+
+func Test_LoadPrecedence(t *testing.T) {
+	path := writeFile(t, "config.yaml", `
+server:
+  addr: ":9000"
+  readTimeout: 5s
+storage:
+  backend: sqlite
+  timeout: 2s
+log:
+  level: warn
+`)
+
+	cfg, err := Load(
+		[]string{"-config", path, "-storage", "file", "-read-timeout", "7s"},
+		env(map[string]string{"HTTP_PORT": ":9100", "STORAGE": "postgres", "LOG_LEVEL": "debug"}),
+	)
+	assert.NoError(t, err)
+
+	// flags over environment over file over defaults.
+	assert.Equal(t, ":9100", cfg.Server.Addr)
+	assert.Equal(t, Duration(7*time.Second), cfg.Server.ReadTimeOut)
+	assert.Equal(t, "file", cfg.Storage.Backend)
+	assert.Equal(t, Duration(2*time.Second), cfg.Storage.TimeOut)
+	assert.Equal(t, "debug", cfg.Log.Level)
+	assert.Equal(t, "data", cfg.Storage.Dir)
+}
+
+func Test_LoadJSONFromEnv(t *testing.T) {
+	path := writeFile(t, "config.json", `{
+		"server": {"tls": {"certFile": "cert.pem", "keyFile": "key.pem"}},
+		"storage": {"cache": {"backend": "lru", "size": 10, "ttl": "1m"}},
+		"duplicates": {"policy": "reject", "similarity": 0.9}
+	}`)
+
+	cfg, err := Load(nil, env(map[string]string{"CONFIG_FILE": path, "ADMIN_TOKEN": "secret"}))
+	assert.NoError(t, err)
+	assert.True(t, cfg.Server.TLS.Enabled())
+	assert.Equal(t, "secret", cfg.Server.AdminToken)
+	assert.Equal(t, "lru", cfg.Storage.Cache.Backend)
+	assert.Equal(t, Duration(time.Minute), cfg.Storage.Cache.TTL)
+	assert.Equal(t, "reject", cfg.Duplicates.Policy)
+	assert.Equal(t, 0.9, cfg.Duplicates.Similarity)
+	assert.Equal(t, Batch{MaxSize: 10000, Concurrency: 8}, cfg.Server.Batch)
+}
+
+func Test_LoadErrors(t *testing.T) {
+	cases := []struct {
+		name string
+		args []string
+		env  map[string]string
+		file string
+	}{
+		{name: "unknown-flag-case", args: []string{"-unknown"}},
+		{name: "bad-duration-case", env: map[string]string{"STORAGE_TTL": "forever"}},
+		{name: "bad-number-case", args: []string{"-cache-size", "ten"}},
+		{name: "unknown-field-case", file: "server:\n  port: 8080\n"},
+		{name: "invalid-addr-case", args: []string{"-addr", "8080"}},
+		{name: "unknown-backend-case", env: map[string]string{"STORAGE": "mongo"}},
+		{name: "postgres-without-dsn-case", env: map[string]string{"STORAGE": "postgres"}},
+		{name: "unknown-cache-case", env: map[string]string{"CACHE": "memcached"}},
+		{name: "tls-without-key-case", args: []string{"-tls-cert", "cert.pem"}},
+		{name: "missing-rules-case", args: []string{"-rules", "missing.yaml"}},
+		{name: "bad-tolerance-case", args: []string{"-total-tolerance", "-1"}},
+		{name: "unknown-policy-case", args: []string{"-duplicate-policy", "ignore"}},
+		{name: "bad-similarity-case", args: []string{"-duplicate-similarity", "2"}},
+		{name: "unknown-level-case", args: []string{"-log-level", "trace"}},
+		{name: "unknown-format-case", env: map[string]string{"LOG_FORMAT": "xml"}},
+		{name: "negative-timeout-case", args: []string{"-write-timeout", "-1s"}},
+		{name: "cache-with-retention-case", env: map[string]string{"CACHE": "lru", "STORAGE_TTL": "24h"}},
+		{name: "cache-with-capacity-case", args: []string{"-cache", "lru", "-storage-capacity", "10"}},
+		{name: "zero-storage-timeout-case", args: []string{"-storage-timeout", "0s"}},
+		{name: "zero-max-body-case", env: map[string]string{"HTTP_MAX_BODY_BYTES": "0"}},
+		{name: "zero-batch-concurrency-case", env: map[string]string{"BATCH_CONCURRENCY": "0"}},
+		{name: "negative-batch-size-case", args: []string{"-batch-max-size", "-1"}},
+		{name: "unknown-exporter-case", env: map[string]string{"TRACING_EXPORTER": "jaeger"}},
+		{name: "file-exporter-without-file-case", args: []string{"-tracing-exporter", "file"}},
+		{name: "otlp-without-endpoint-case", args: []string{"-tracing-exporter", "otlp"}},
+		{name: "bad-sample-ratio-case", args: []string{"-tracing-sample-ratio", "1.5"}},
+	}
+
+	for _, c := range cases {
+		c := c
+
+		t.Run(c.name, func(t *testing.T) {
+			args := c.args
+			if c.file != "" {
+				args = append(args, "-config", writeFile(t, "config.yaml", c.file))
+			}
+
+			_, err := Load(args, env(c.env))
+			assert.ErrorIs(t, err, ErrInvalidConfig)
+		})
+	}
+}
+
+func Test_ValidateReportsEveryError(t *testing.T) {
+	cfg := Default()
+	cfg.Storage.Backend = "mongo"
+	cfg.Log.Level = "trace"
+
+	err := cfg.Validate()
+	assert.ErrorIs(t, err, ErrInvalidConfig)
+	assert.Contains(t, err.Error(), "mongo")
+	assert.Contains(t, err.Error(), "trace")
+}
@@ -0,0 +1,206 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+)
+
+const envConfigFile string = "CONFIG_FILE"
+
+// setting is a configuration value that can be set with an environment
+// variable and a command line flag.
+type setting struct {
+	env   string
+	flag  string
+	usage string
+	set   func(cfg *Config, value string) error
+}
+
+func (s setting) apply(cfg *Config, source, value string) error {
+	if err := s.set(cfg, value); err != nil {
+		return fmt.Errorf("%s %q:%w", source, value, ErrInvalidConfig)
+	}
+
+	return nil
+}
+
+func setString(field func(cfg *Config) *string) func(cfg *Config, value string) error {
+	return func(cfg *Config, value string) error {
+		*field(cfg) = value
+
+		return nil
+	}
+}
+
+func setInt(field func(cfg *Config) *int) func(cfg *Config, value string) error {
+	return func(cfg *Config, value string) error {
+		n, err := strconv.Atoi(value)
+		*field(cfg) = n
+
+		return err
+	}
+}
+
+func setInt64(field func(cfg *Config) *int64) func(cfg *Config, value string) error {
+	return func(cfg *Config, value string) error {
+		n, err := strconv.ParseInt(value, 10, 64)
+		*field(cfg) = n
+
+		return err
+	}
+}
+
+func setFloat(field func(cfg *Config) *float64) func(cfg *Config, value string) error {
+	return func(cfg *Config, value string) error {
+		f, err := strconv.ParseFloat(value, 64)
+		*field(cfg) = f
+
+		return err
+	}
+}
+
+func setDuration(field func(cfg *Config) *Duration) func(cfg *Config, value string) error {
+	return func(cfg *Config, value string) error {
+		return field(cfg).UnmarshalText([]byte(value))
+	}
+}
+
+//nolint:gochecknoglobals
+var settings = []setting{
+	{
+		env: "HTTP_PORT", flag: "addr", usage: "address the server listens on, e.g. :8080",
+		set: setString(func(c *Config) *string { return &c.Server.Addr }),
+	},
+	{
+		env: "HTTP_READ_TIMEOUT", flag: "read-timeout", usage: "maximum duration to read a request",
+		set: setDuration(func(c *Config) *Duration { return &c.Server.ReadTimeOut }),
+	},
+	{
+		env: "HTTP_WRITE_TIMEOUT", flag: "write-timeout", usage: "maximum duration to write a response",
+		set: setDuration(func(c *Config) *Duration { return &c.Server.WriteTimeOut }),
+	},
+	{
+		env: "HTTP_IDLE_TIMEOUT", flag: "idle-timeout", usage: "maximum duration of idle keep-alive connections",
+		set: setDuration(func(c *Config) *Duration { return &c.Server.IdleTimeOut }),
+	},
+	{
+		env: "SHUTDOWN_TIMEOUT", flag: "shutdown-timeout", usage: "maximum duration to drain requests on shutdown",
+		set: setDuration(func(c *Config) *Duration { return &c.Server.ShutdownTimeOut }),
+	},
+	{
+		env: "TLS_CERT_FILE", flag: "tls-cert", usage: "TLS certificate file, serves HTTPS with -tls-key",
+		set: setString(func(c *Config) *string { return &c.Server.TLS.CertFile }),
+	},
+	{
+		env: "TLS_KEY_FILE", flag: "tls-key", usage: "TLS private key file",
+		set: setString(func(c *Config) *string { return &c.Server.TLS.KeyFile }),
+	},
+	{
+		env: "HTTP_MAX_BODY_BYTES", flag: "max-body-bytes", usage: "maximum size of a request body",
+		set: setInt64(func(c *Config) *int64 { return &c.Server.MaxBodyBytes }),
+	},
+	{
+		env: "BATCH_MAX_SIZE", flag: "batch-max-size", usage: "maximum number of receipts of a batch",
+		set: setInt(func(c *Config) *int { return &c.Server.Batch.MaxSize }),
+	},
+	{
+		env: "BATCH_CONCURRENCY", flag: "batch-concurrency", usage: "receipts of a batch processed at the same time",
+		set: setInt(func(c *Config) *int { return &c.Server.Batch.Concurrency }),
+	},
+	{
+		env: "ADMIN_TOKEN", flag: "admin-token", usage: "bearer token of the admin endpoints, not served without it",
+		set: setString(func(c *Config) *string { return &c.Server.AdminToken }),
+	},
+	{
+		env: "STORAGE", flag: "storage", usage: "storage backend: memory, file, sqlite or postgres",
+		set: setString(func(c *Config) *string { return &c.Storage.Backend }),
+	},
+	{
+		env: "STORAGE_DIR", flag: "storage-dir", usage: "directory of the file and sqlite backends",
+		set: setString(func(c *Config) *string { return &c.Storage.Dir }),
+	},
+	{
+		env: "STORAGE_DSN", flag: "storage-dsn", usage: "postgres connection string or sqlite database path",
+		set: setString(func(c *Config) *string { return &c.Storage.DSN }),
+	},
+	{
+		env: "STORAGE_TIMEOUT", flag: "storage-timeout", usage: "time out of the memory storage operations",
+		set: setDuration(func(c *Config) *Duration { return &c.Storage.TimeOut }),
+	},
+	{
+		env: "STORAGE_SNAPSHOT_EVERY", flag: "storage-snapshot-every", usage: "receipts between file backend snapshots",
+		set: setInt(func(c *Config) *int { return &c.Storage.SnapshotEvery }),
+	},
+	{
+		env: "STORAGE_MEMORY_SNAPSHOT", flag: "storage-memory-snapshot", usage: "snapshot file of the memory backend",
+		set: setString(func(c *Config) *string { return &c.Storage.MemorySnapshot }),
+	},
+	{
+		env: "STORAGE_CAPACITY", flag: "storage-capacity", usage: "maximum number of receipts in memory",
+		set: setInt(func(c *Config) *int { return &c.Storage.Capacity }),
+	},
+	{
+		env: "STORAGE_MAX_BYTES", flag: "storage-max-bytes", usage: "maximum estimated size of the receipts in memory",
+		set: setInt64(func(c *Config) *int64 { return &c.Storage.MaxBytes }),
+	},
+	{
+		env: "STORAGE_TTL", flag: "storage-ttl", usage: "time the receipts are kept in memory",
+		set: setDuration(func(c *Config) *Duration { return &c.Storage.TTL }),
+	},
+	{
+		env: "CACHE", flag: "cache", usage: "cache backend: lru or redis",
+		set: setString(func(c *Config) *string { return &c.Storage.Cache.Backend }),
+	},
+	{
+		env: "CACHE_SIZE", flag: "cache-size", usage: "lru capacity or redis pool size",
+		set: setInt(func(c *Config) *int { return &c.Storage.Cache.Size }),
+	},
+	{
+		env: "CACHE_TTL", flag: "cache-ttl", usage: "time the reads are cached",
+		set: setDuration(func(c *Config) *Duration { return &c.Storage.Cache.TTL }),
+	},
+	{
+		env: "REDIS_ADDR", flag: "redis-addr", usage: "address of the redis cache",
+		set: setString(func(c *Config) *string { return &c.Storage.Cache.RedisAddr }),
+	},
+	{
+		env: "RULES_FILE", flag: "rules", usage: "rule set file or directory",
+		set: setString(func(c *Config) *string { return &c.Rules.Path }),
+	},
+	{
+		env: "TOTAL_TOLERANCE", flag: "total-tolerance", usage: "accepted difference between the items and the total",
+		set: setString(func(c *Config) *string { return &c.Rules.TotalTolerance }),
+	},
+	{
+		env: "DUPLICATE_POLICY", flag: "duplicate-policy", usage: "duplicate policy: flag, score-zero or reject",
+		set: setString(func(c *Config) *string { return &c.Duplicates.Policy }),
+	},
+	{
+		env: "DUPLICATE_SIMILARITY", flag: "duplicate-similarity", usage: "items similarity of a duplicate, 0 to 1",
+		set: setFloat(func(c *Config) *float64 { return &c.Duplicates.Similarity }),
+	},
+	{
+		env: "LOG_LEVEL", flag: "log-level", usage: "log level: debug, info, warn or error",
+		set: setString(func(c *Config) *string { return &c.Log.Level }),
+	},
+	{
+		env: "LOG_FORMAT", flag: "log-format", usage: "log format: text or json",
+		set: setString(func(c *Config) *string { return &c.Log.Format }),
+	},
+	{
+		env: "TRACING_EXPORTER", flag: "tracing-exporter", usage: "tracing exporter: stdout, file or otlp",
+		set: setString(func(c *Config) *string { return &c.Tracing.Exporter }),
+	},
+	{
+		env: "TRACING_FILE", flag: "tracing-file", usage: "file the spans are appended to",
+		set: setString(func(c *Config) *string { return &c.Tracing.File }),
+	},
+	{
+		env: "OTEL_EXPORTER_OTLP_ENDPOINT", flag: "tracing-endpoint", usage: "OTLP/HTTP collector, e.g. http://localhost:4318",
+		set: setString(func(c *Config) *string { return &c.Tracing.Endpoint }),
+	},
+	{
+		env: "TRACING_SAMPLE_RATIO", flag: "tracing-sample-ratio", usage: "ratio of the new traces recorded, 0 to 1",
+		set: setFloat(func(c *Config) *float64 { return &c.Tracing.SampleRatio }),
+	},
+}
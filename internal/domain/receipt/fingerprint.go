@@ -0,0 +1,37 @@
+package receipt
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Fingerprint is a canonical hash of the receipt content. Receipts with the
+// same retailer, purchase date and time, total and items share it, no matter
+// the order of the items or the spacing of the texts.
+func (r Receipt) Fingerprint() string {
+	items := make([]string, len(r.Items))
+
+	for i, item := range r.Items {
+		items[i] = fmt.Sprintf("%s\x1f%d", canonicalText(item.ShortDescription), item.Price.Cents())
+	}
+
+	sort.Strings(items)
+
+	hash := sha256.New()
+	fmt.Fprintf(hash, "%s\x1e%s\x1e%s\x1e%d\x1e%s",
+		canonicalText(r.Retailer),
+		r.PurchaseDate.Format(DatePurchaseFormat),
+		r.PurchaseTime.Format(TimePurchaseFormat),
+		r.Total.Cents(),
+		strings.Join(items, "\x1e"),
+	)
+
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+func canonicalText(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
@@ -0,0 +1,71 @@
+package receipt_test
+
+import (
+	"testing"
+	"time"
+
+	"receipt-processor-challenge/internal/domain/receipt"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Fingerprint(t *testing.T) {
+	base := func() receipt.Receipt {
+		return receipt.Receipt{
+			Retailer:     "M&M Corner Market",
+			PurchaseDate: time.Date(2022, 3, 20, 0, 0, 0, 0, time.UTC),
+			PurchaseTime: time.Date(0, 1, 1, 14, 33, 0, 0, time.UTC),
+			Items: []receipt.Item{
+				{ShortDescription: "Gatorade", Price: 225},
+				{ShortDescription: "Doritos Nacho Cheese", Price: 335},
+			},
+			Total: 560,
+		}
+	}
+
+	cases := []struct {
+		name          string
+		modify        func(r *receipt.Receipt)
+		expectedEqual bool
+	}{
+		{
+			name:          "same-content-case",
+			modify:        func(r *receipt.Receipt) {},
+			expectedEqual: true,
+		},
+		{
+			name: "items-order-and-spacing-case",
+			modify: func(r *receipt.Receipt) {
+				r.Retailer = "  M&M   Corner Market"
+				r.Items[0], r.Items[1] = r.Items[1], r.Items[0]
+			},
+			expectedEqual: true,
+		},
+		{
+			name:          "different-total-case",
+			modify:        func(r *receipt.Receipt) { r.Total = 561 },
+			expectedEqual: false,
+		},
+		{
+			name:          "different-time-case",
+			modify:        func(r *receipt.Receipt) { r.PurchaseTime = r.PurchaseTime.Add(time.Minute) },
+			expectedEqual: false,
+		},
+		{
+			name:          "different-item-case",
+			modify:        func(r *receipt.Receipt) { r.Items[0].ShortDescription = "Gatorade Zero" },
+			expectedEqual: false,
+		},
+	}
+
+	for _, c := range cases {
+		c := c
+
+		t.Run(c.name, func(t *testing.T) {
+			r := base()
+			c.modify(&r)
+
+			assert.Equal(t, c.expectedEqual, base().Fingerprint() == r.Fingerprint())
+		})
+	}
+}
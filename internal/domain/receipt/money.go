@@ -0,0 +1,92 @@
+package receipt
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const centsPerDollar int64 = 100
+
+var ErrMoneyFormat = errors.New("money format error")
+
+// Money is an exact amount of money expressed in cents.
+type Money int64
+
+// ParseMoney parses a decimal amount with at most two decimals such as
+// "35.35", "12" or "-0.5" without going through a float.
+func ParseMoney(s string) (Money, error) {
+	text := s
+	negative := false
+
+	switch {
+	case strings.HasPrefix(text, "-"):
+		negative = true
+		text = text[1:]
+	case strings.HasPrefix(text, "+"):
+		text = text[1:]
+	}
+
+	dollars, cents, hasCents := strings.Cut(text, ".")
+	if dollars == "" || (hasCents && (cents == "" || len(cents) > 2)) {
+		return 0, fmt.Errorf("%s:%w", s, ErrMoneyFormat)
+	}
+
+	for len(cents) < 2 {
+		cents += "0"
+	}
+
+	value, err := parseDigits(dollars + cents)
+	if err != nil {
+		return 0, fmt.Errorf("%s:%w", s, ErrMoneyFormat)
+	}
+
+	if negative {
+		value = -value
+	}
+
+	return Money(value), nil
+}
+
+func parseDigits(s string) (int64, error) {
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return 0, ErrMoneyFormat
+		}
+	}
+
+	return strconv.ParseInt(s, 10, 64)
+}
+
+// Cents returns the amount in cents.
+func (m Money) Cents() int64 {
+	return int64(m)
+}
+
+// IsRoundDollar reports whether the amount has no cents.
+func (m Money) IsRoundDollar() bool {
+	return int64(m)%centsPerDollar == 0
+}
+
+// IsMultipleOf reports whether the amount is a multiple of unit.
+func (m Money) IsMultipleOf(unit Money) bool {
+	if unit == 0 {
+		return false
+	}
+
+	return m%unit == 0
+}
+
+// String formats the amount with two decimals, e.g. "35.35".
+func (m Money) String() string {
+	sign := ""
+	cents := int64(m)
+
+	if cents < 0 {
+		sign = "-"
+		cents = -cents
+	}
+
+	return fmt.Sprintf("%s%d.%02d", sign, cents/centsPerDollar, cents%centsPerDollar)
+}
@@ -0,0 +1,95 @@
+package receipt_test
+
+import (
+	"errors"
+	"testing"
+
+	"receipt-processor-challenge/internal/domain/receipt"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ParseMoney(t *testing.T) {
+	cases := []struct {
+		name           string
+		input          string
+		expectedResult receipt.Money
+		expectedString string
+		expectedError  error
+	}{
+		{
+			name:           "cents-case",
+			input:          "35.35",
+			expectedResult: 3535,
+			expectedString: "35.35",
+		},
+		{
+			name:           "no-cents-case",
+			input:          "12",
+			expectedResult: 1200,
+			expectedString: "12.00",
+		},
+		{
+			name:           "one-decimal-case",
+			input:          "0.5",
+			expectedResult: 50,
+			expectedString: "0.50",
+		},
+		{
+			name:           "negative-case",
+			input:          "-1.05",
+			expectedResult: -105,
+			expectedString: "-1.05",
+		},
+		{
+			name:          "three-decimals-case",
+			input:         "1.005",
+			expectedError: receipt.ErrMoneyFormat,
+		},
+		{
+			name:          "letters-case",
+			input:         "6.49x",
+			expectedError: receipt.ErrMoneyFormat,
+		},
+		{
+			name:          "missing-dollars-case",
+			input:         ".25",
+			expectedError: receipt.ErrMoneyFormat,
+		},
+		{
+			name:          "empty-case",
+			input:         "",
+			expectedError: receipt.ErrMoneyFormat,
+		},
+	}
+
+	for _, c := range cases {
+		c := c
+
+		t.Run(c.name, func(t *testing.T) {
+			m, err := receipt.ParseMoney(c.input)
+			if c.expectedError != nil {
+				assert.True(t, errors.Is(err, c.expectedError))
+
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, c.expectedResult, m)
+			assert.Equal(t, c.expectedString, m.String())
+		})
+	}
+}
+
+func Test_MoneyArithmetic(t *testing.T) {
+	a, _ := receipt.ParseMoney("0.10")
+	b, _ := receipt.ParseMoney("0.20")
+	c, _ := receipt.ParseMoney("0.30")
+
+	assert.Equal(t, c, a+b)
+	assert.False(t, (a + b).IsRoundDollar())
+	assert.True(t, receipt.Money(900).IsRoundDollar())
+	assert.True(t, receipt.Money(925).IsMultipleOf(25))
+	assert.False(t, receipt.Money(910).IsMultipleOf(25))
+	assert.False(t, receipt.Money(910).IsMultipleOf(0))
+}
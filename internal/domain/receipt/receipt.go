@@ -1,6 +1,10 @@
 package receipt
 
-import "time"
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
 
 const (
 	DatePurchaseFormat string = "2006-01-02"
@@ -12,14 +16,47 @@ type Receipt struct {
 	PurchaseDate time.Time
 	PurchaseTime time.Time
 	Items        []Item
-	Total        float64
+	Total        Money
 }
 
 type Item struct {
 	ShortDescription string
-	Price            float64
+	Price            Money
 }
 
 type Points struct {
-	Points int
+	Points    int
+	Breakdown []Award
+	// RuleSetVersion is the version of the rules that produced the points.
+	RuleSetVersion string
+	// Duplicate is set when the receipt looks like a resubmission of a stored one.
+	Duplicate *Duplicate
+}
+
+// Award is the amount of points granted to a receipt by a single rule.
+type Award struct {
+	Rule        string
+	Description string
+	Points      int
+	// Item is set when the award was triggered by a specific item of the receipt.
+	Item *AwardedItem
+}
+
+type AwardedItem struct {
+	Index int
+	Item  Item
+}
+
+// Duplicate links a receipt to the stored receipt it looks like a resubmission
+// of, Policy is the duplicate policy applied when it was scored.
+type Duplicate struct {
+	Of         uuid.UUID
+	Similarity float64
+	Policy     string
+}
+
+// FlaggedReceipt is a stored receipt whose points have a Duplicate.
+type FlaggedReceipt struct {
+	ID     uuid.UUID
+	Points Points
 }
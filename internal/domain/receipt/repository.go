@@ -2,11 +2,44 @@ package receipt
 
 import (
 	"context"
+	"errors"
 
 	"github.com/google/uuid"
 )
 
+var (
+	// ErrNotFound is returned when no receipt is stored with the given id.
+	ErrNotFound = errors.New("points not found")
+	// ErrEvicted is returned when the receipt was stored but the repository
+	// has removed it to respect its retention limits.
+	ErrEvicted = errors.New("points evicted")
+	// ErrDuplicated is returned by Save when one of the keys is already stored,
+	// the returned id is the id of the stored receipt.
+	ErrDuplicated = errors.New("receipt already stored")
+	// ErrIdempotencyKeyReused is returned when an idempotency key is sent again
+	// with a different receipt.
+	ErrIdempotencyKeyReused = errors.New("idempotency key already used with a different receipt")
+	// ErrSuspectedDuplicate is returned when a receipt is rejected for being
+	// too similar to a stored one.
+	ErrSuspectedDuplicate = errors.New("receipt looks like a resubmission of receipt")
+)
+
 type Repository interface {
-	Save(ctx context.Context, points Points) (uuid.UUID, error)
+	// Save stores the receipt and its points. The optional keys identify the
+	// submission, when any of them is already stored nothing is saved and the
+	// id of the stored receipt is returned together with ErrDuplicated.
+	Save(ctx context.Context, receipt Receipt, points Points, keys ...string) (uuid.UUID, error)
 	Get(ctx context.Context, id uuid.UUID) (*Points, error)
+	GetReceipt(ctx context.Context, id uuid.UUID) (*Receipt, error)
+	// FindByKey returns the id of the receipt saved with the submission key,
+	// ErrNotFound when no receipt was saved with it.
+	FindByKey(ctx context.Context, key string) (uuid.UUID, error)
+	// FindMatching returns the ids of the stored receipts with the same match
+	// key as the given receipt.
+	FindMatching(ctx context.Context, receipt Receipt) ([]uuid.UUID, error)
+	// Flagged returns the stored receipts whose points have a Duplicate, in the
+	// order they were stored.
+	Flagged(ctx context.Context) ([]FlaggedReceipt, error)
+	// Ping reports an error when the repository can not serve requests.
+	Ping(ctx context.Context) error
 }
@@ -0,0 +1,100 @@
+package receipt
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// MatchKey is a hash of the retailer, purchase date and time and total. Receipts
+// that share it are candidates to be the same paper receipt even when their
+// items differ.
+func (r Receipt) MatchKey() string {
+	hash := sha256.New()
+	fmt.Fprintf(hash, "%s\x1e%s\x1e%s\x1e%d",
+		strings.ToLower(canonicalText(r.Retailer)),
+		r.PurchaseDate.Format(DatePurchaseFormat),
+		r.PurchaseTime.Format(TimePurchaseFormat),
+		r.Total.Cents(),
+	)
+
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// ItemsSimilarity compares two lists of items in any order and returns a value
+// between 0 (nothing in common) and 1 (same items). Items only match when they
+// have the same price, the score of a pair is the similarity of the descriptions.
+func ItemsSimilarity(a, b []Item) float64 {
+	if len(a) == 0 && len(b) == 0 {
+		return 1
+	}
+
+	used := make([]bool, len(b))
+	score := 0.0
+
+	for _, itemA := range a {
+		best, bestIndex := 0.0, -1
+
+		for index, itemB := range b {
+			if used[index] || itemA.Price != itemB.Price {
+				continue
+			}
+
+			if s := textSimilarity(itemA.ShortDescription, itemB.ShortDescription); s > best {
+				best, bestIndex = s, index
+			}
+		}
+
+		if bestIndex >= 0 {
+			used[bestIndex] = true
+			score += best
+		}
+	}
+
+	return 2 * score / float64(len(a)+len(b))
+}
+
+// textSimilarity is one minus the edit distance of the canonical texts divided
+// by the length of the longest one, letter case is ignored.
+func textSimilarity(a, b string) float64 {
+	ra := []rune(strings.ToLower(canonicalText(a)))
+	rb := []rune(strings.ToLower(canonicalText(b)))
+
+	longest := len(ra)
+	if len(rb) > longest {
+		longest = len(rb)
+	}
+
+	if longest == 0 {
+		return 1
+	}
+
+	return 1 - float64(levenshtein(ra, rb))/float64(longest)
+}
+
+func levenshtein(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+
+		prev, curr = curr, prev
+	}
+
+	return prev[len(b)]
+}
@@ -0,0 +1,65 @@
+package receipt_test
+
+import (
+	"testing"
+
+	"receipt-processor-challenge/internal/domain/receipt"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ItemsSimilarity(t *testing.T) {
+	items := []receipt.Item{
+		{ShortDescription: "Gatorade", Price: 225},
+		{ShortDescription: "Doritos Nacho Cheese", Price: 335},
+	}
+
+	cases := []struct {
+		name     string
+		other    []receipt.Item
+		expected float64
+	}{
+		{
+			name:     "same-items-case",
+			other:    []receipt.Item{items[1], items[0]},
+			expected: 1,
+		},
+		{
+			name: "case-and-spacing-case",
+			other: []receipt.Item{
+				{ShortDescription: " gatorade", Price: 225},
+				{ShortDescription: "DORITOS  NACHO CHEESE", Price: 335},
+			},
+			expected: 1,
+		},
+		{
+			name:     "missing-item-case",
+			other:    []receipt.Item{items[0]},
+			expected: 2.0 / 3,
+		},
+		{
+			name: "different-price-case",
+			other: []receipt.Item{
+				{ShortDescription: "Gatorade", Price: 226},
+				{ShortDescription: "Doritos Nacho Cheese", Price: 336},
+			},
+			expected: 0,
+		},
+		{
+			name: "typo-case",
+			other: []receipt.Item{
+				{ShortDescription: "Gatorad", Price: 225},
+				items[1],
+			},
+			expected: (7.0/8 + 1) / 2,
+		},
+	}
+
+	for _, c := range cases {
+		c := c
+
+		t.Run(c.name, func(t *testing.T) {
+			assert.InDelta(t, c.expected, receipt.ItemsSimilarity(items, c.other), 1e-9)
+		})
+	}
+}
@@ -0,0 +1,129 @@
+package receipt
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	RuleItemsSum      string = "items-sum"
+	RuleNonNegative   string = "non-negative"
+	RuleNotInFuture   string = "not-in-future"
+	RuleRetailerChars string = "retailer-pattern"
+)
+
+var (
+	ErrInvalidReceipt = errors.New("invalid receipt")
+
+	retailerPattern = regexp.MustCompile(`^[\w\s\-&]+$`) //nolint:gochecknoglobals
+)
+
+// FieldError is a failed check on a single field of the receipt, Field uses
+// the receipt field names, e.g. "total" or "items[2].price".
+type FieldError struct {
+	Field   string
+	Rule    string
+	Message string
+}
+
+// ValidationError contains every check the receipt failed.
+type ValidationError struct {
+	Errors []FieldError
+}
+
+func (ve *ValidationError) Error() string {
+	msgs := make([]string, len(ve.Errors))
+
+	for i, fe := range ve.Errors {
+		msgs[i] = fe.Message
+	}
+
+	return strings.Join(msgs, ", ")
+}
+
+func (ve *ValidationError) Is(target error) bool {
+	return target == ErrInvalidReceipt
+}
+
+// Validator checks that a receipt is consistent before it is scored.
+type Validator struct {
+	tolerance Money
+	now       func() time.Time
+}
+
+// NewValidator returns a validator that accepts a difference between the sum
+// of the item prices and the total of up to tolerance.
+func NewValidator(tolerance Money) Validator {
+	return Validator{
+		tolerance: tolerance,
+		now:       time.Now,
+	}
+}
+
+func (v Validator) Validate(r Receipt) error {
+	errs := []FieldError{}
+
+	if !retailerPattern.MatchString(r.Retailer) {
+		errs = append(errs, FieldError{
+			Field:   "retailer",
+			Rule:    RuleRetailerChars,
+			Message: "retailer can only contain letters, numbers, spaces, '-' and '&'",
+		})
+	}
+
+	if r.PurchaseDate.After(v.lastValidDate()) {
+		errs = append(errs, FieldError{
+			Field:   "purchaseDate",
+			Rule:    RuleNotInFuture,
+			Message: "purchaseDate is in the future",
+		})
+	}
+
+	sum := Money(0)
+
+	for index, item := range r.Items {
+		sum += item.Price
+
+		if item.Price < 0 {
+			field := fmt.Sprintf("items[%d].price", index)
+			errs = append(errs, FieldError{
+				Field:   field,
+				Rule:    RuleNonNegative,
+				Message: fmt.Sprintf("%s is negative", field),
+			})
+		}
+	}
+
+	if r.Total < 0 {
+		errs = append(errs, FieldError{
+			Field:   "total",
+			Rule:    RuleNonNegative,
+			Message: "total is negative",
+		})
+	}
+
+	if diff := sum - r.Total; diff > v.tolerance || -diff > v.tolerance {
+		errs = append(errs, FieldError{
+			Field:   "total",
+			Rule:    RuleItemsSum,
+			Message: fmt.Sprintf("items add up to %s but total is %s", sum, r.Total),
+		})
+	}
+
+	if len(errs) > 0 {
+		return &ValidationError{Errors: errs}
+	}
+
+	return nil
+}
+
+// lastValidDate is tomorrow, purchase dates have no time zone so one day of
+// margin keeps receipts from time zones ahead of the server valid.
+func (v Validator) lastValidDate() time.Time {
+	now := v.now()
+
+	return time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,112 @@
+package receipt_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"receipt-processor-challenge/internal/domain/receipt"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Validate(t *testing.T) {
+	validReceipt := func() receipt.Receipt {
+		return receipt.Receipt{
+			Retailer:     "M&M Corner Market",
+			PurchaseDate: time.Date(2022, 3, 20, 0, 0, 0, 0, time.UTC),
+			PurchaseTime: time.Date(0, 1, 1, 14, 33, 0, 0, time.UTC),
+			Items: []receipt.Item{
+				{ShortDescription: "Gatorade", Price: 225},
+				{ShortDescription: "Gatorade", Price: 225},
+			},
+			Total: 450,
+		}
+	}
+
+	cases := []struct {
+		name           string
+		tolerance      receipt.Money
+		receipt        func() receipt.Receipt
+		expectedErrors []receipt.FieldError
+	}{
+		{
+			name:           "valid-case",
+			receipt:        validReceipt,
+			expectedErrors: nil,
+		},
+		{
+			name: "items-sum-case",
+			receipt: func() receipt.Receipt {
+				r := validReceipt()
+				r.Total = 50000
+
+				return r
+			},
+			expectedErrors: []receipt.FieldError{
+				{Field: "total", Rule: receipt.RuleItemsSum, Message: "items add up to 4.50 but total is 500.00"},
+			},
+		},
+		{
+			name:      "items-sum-within-tolerance-case",
+			tolerance: 5,
+			receipt: func() receipt.Receipt {
+				r := validReceipt()
+				r.Total = 455
+
+				return r
+			},
+			expectedErrors: nil,
+		},
+		{
+			name: "negative-price-case",
+			receipt: func() receipt.Receipt {
+				r := validReceipt()
+				r.Items[1].Price = -225
+				r.Total = 0
+
+				return r
+			},
+			expectedErrors: []receipt.FieldError{
+				{Field: "items[1].price", Rule: receipt.RuleNonNegative, Message: "items[1].price is negative"},
+			},
+		},
+		{
+			name: "future-date-and-retailer-case",
+			receipt: func() receipt.Receipt {
+				r := validReceipt()
+				r.Retailer = "M&M's"
+				r.PurchaseDate = time.Date(2999, 1, 1, 0, 0, 0, 0, time.UTC)
+
+				return r
+			},
+			expectedErrors: []receipt.FieldError{
+				{
+					Field:   "retailer",
+					Rule:    receipt.RuleRetailerChars,
+					Message: "retailer can only contain letters, numbers, spaces, '-' and '&'",
+				},
+				{Field: "purchaseDate", Rule: receipt.RuleNotInFuture, Message: "purchaseDate is in the future"},
+			},
+		},
+	}
+
+	for _, c := range cases {
+		c := c
+
+		t.Run(c.name, func(t *testing.T) {
+			err := receipt.NewValidator(c.tolerance).Validate(c.receipt())
+			if c.expectedErrors == nil {
+				assert.NoError(t, err)
+
+				return
+			}
+
+			var vErr *receipt.ValidationError
+
+			assert.True(t, errors.Is(err, receipt.ErrInvalidReceipt))
+			assert.True(t, errors.As(err, &vErr))
+			assert.Equal(t, c.expectedErrors, vErr.Errors)
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package http
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+const snapshotPath string = "/snapshot"
+
+var (
+	ErrUnauthorized    = errors.New("missing or invalid admin token")
+	ErrSnapshotRunning = errors.New("a snapshot is already running")
+)
+
+// AdminAPI are the operations of the admin endpoints.
+type AdminAPI interface {
+	// Snapshot saves the stored receipts to the configured snapshot.
+	Snapshot(ctx context.Context) error
+}
+
+// requireAdminToken answers 401 to the requests without the admin token in
+// their Authorization header.
+func (s *Server) requireAdminToken(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(eCtx echo.Context) error {
+		token, ok := strings.CutPrefix(eCtx.Request().Header.Get(echo.HeaderAuthorization), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(s.adminToken)) != 1 {
+			eCtx.Response().Header().Set(echo.HeaderWWWAuthenticate, "Bearer")
+
+			return apiReceiptResponse(eCtx, ErrUnauthorized)
+		}
+
+		return next(eCtx)
+	}
+}
+
+// saveSnapshot answers 409 while another snapshot is running, they would both
+// write the same file.
+func (s *Server) saveSnapshot(eCtx echo.Context) error {
+	if !s.snapshotting.CompareAndSwap(false, true) {
+		return apiReceiptResponse(eCtx, ErrSnapshotRunning)
+	}
+
+	defer s.snapshotting.Store(false)
+
+	if err := s.admin.Snapshot(eCtx.Request().Context()); err != nil {
+		return apiReceiptResponse(eCtx, fmt.Errorf("snapshot error:%w", err))
+	}
+
+	return eCtx.NoContent(http.StatusNoContent)
+}
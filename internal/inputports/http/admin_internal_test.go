@@ -0,0 +1,153 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type adminAPIMock struct {
+	mock.Mock
+}
+
+func (admMock *adminAPIMock) Snapshot(ctx context.Context) error {
+	return admMock.Called(ctx).Error(0)
+}
+
+func Test_SaveSnapshot(t *testing.T) {
+	cases := []struct {
+		name             string
+		adminBuilder     func() *adminAPIMock
+		running          bool
+		expectedResponse []byte
+		expectedHTTPCode int
+	}{
+		{
+			name: "success-case",
+			adminBuilder: func() *adminAPIMock {
+				admMock := adminAPIMock{}
+				admMock.On("Snapshot", context.Background()).Return(nil)
+
+				return &admMock
+			},
+			expectedResponse: nil,
+			expectedHTTPCode: http.StatusNoContent,
+		},
+		{
+			name: "snapshot-error-case",
+			adminBuilder: func() *adminAPIMock {
+				admMock := adminAPIMock{}
+				admMock.On("Snapshot", context.Background()).Return(errors.New("disk full"))
+
+				return &admMock
+			},
+			expectedResponse: append([]byte(`{"type":"about:blank","title":"Internal Server Error","status":500,`+
+				`"detail":"unexpected error"}`), paddingLastByte(t)...),
+			expectedHTTPCode: http.StatusInternalServerError,
+		},
+		{
+			name: "already-running-case",
+			adminBuilder: func() *adminAPIMock {
+				return &adminAPIMock{}
+			},
+			running: true,
+			expectedResponse: append([]byte(`{"type":"about:blank","title":"Conflict","status":409,`+
+				`"detail":"a snapshot is already running"}`), paddingLastByte(t)...),
+			expectedHTTPCode: http.StatusConflict,
+		},
+	}
+
+	for _, c := range cases {
+		req := httptest.NewRequest(echo.POST, "http://localhost:8080/admin/snapshot", nil)
+		rec := httptest.NewRecorder()
+		echoContext := echo.New().NewContext(req, rec)
+		admMock := c.adminBuilder()
+		s := Server{
+			admin: admMock,
+		}
+		s.snapshotting.Store(c.running)
+
+		t.Run(c.name, func(t *testing.T) {
+			err := s.saveSnapshot(echoContext)
+			assert.NoError(t, err)
+			assert.Equal(t, c.expectedResponse, rec.Body.Bytes())
+			assert.Equal(t, c.expectedHTTPCode, rec.Code)
+			assert.Equal(t, c.running, s.snapshotting.Load())
+			admMock.AssertExpectations(t)
+		})
+	}
+}
+
+func Test_AdminRoutes(t *testing.T) {
+	admMock := &adminAPIMock{}
+	admMock.On("Snapshot", mock.Anything).Return(nil)
+
+	cases := []struct {
+		name             string
+		options          []Option
+		authorization    string
+		expectedHTTPCode int
+	}{
+		{name: "without-admin-case", expectedHTTPCode: http.StatusNotFound},
+		{
+			name:             "without-token-case",
+			options:          []Option{WithAdmin(admMock, "")},
+			authorization:    "Bearer ",
+			expectedHTTPCode: http.StatusNotFound,
+		},
+		{
+			name:             "missing-token-case",
+			options:          []Option{WithAdmin(admMock, "secret")},
+			expectedHTTPCode: http.StatusUnauthorized,
+		},
+		{
+			name:             "wrong-token-case",
+			options:          []Option{WithAdmin(admMock, "secret")},
+			authorization:    "Bearer guess",
+			expectedHTTPCode: http.StatusUnauthorized,
+		},
+		{
+			name:             "wrong-scheme-case",
+			options:          []Option{WithAdmin(admMock, "secret")},
+			authorization:    "Basic secret",
+			expectedHTTPCode: http.StatusUnauthorized,
+		},
+		{
+			name:             "token-case",
+			options:          []Option{WithAdmin(admMock, "secret")},
+			authorization:    "Bearer secret",
+			expectedHTTPCode: http.StatusNoContent,
+		},
+	}
+
+	for _, c := range cases {
+		c := c
+
+		t.Run(c.name, func(t *testing.T) {
+			s := NewServer(context.Background(), &receiptAPIMock{}, c.options...)
+			s.routes()
+
+			req := httptest.NewRequest(echo.POST, "/admin/snapshot", nil)
+			if c.authorization != "" {
+				req.Header.Set(echo.HeaderAuthorization, c.authorization)
+			}
+
+			rec := httptest.NewRecorder()
+			s.router.ServeHTTP(rec, req)
+
+			assert.Equal(t, c.expectedHTTPCode, rec.Code)
+
+			if c.expectedHTTPCode == http.StatusUnauthorized {
+				assert.Equal(t, "Bearer", rec.Header().Get(echo.HeaderWWWAuthenticate))
+			}
+		})
+	}
+
+	admMock.AssertNumberOfCalls(t, "Snapshot", 1)
+}
@@ -0,0 +1,400 @@
+package http
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"sync"
+
+	rcp "receipt-processor-challenge/internal/domain/receipt"
+	"receipt-processor-challenge/internal/tracing"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	batchPath string = "/process/batch"
+
+	mimeNDJSON string = "application/x-ndjson"
+
+	defaultBatchMaxSize     = 10000
+	defaultBatchConcurrency = 8
+
+	// maxNDJSONLine limits the size of a receipt of an NDJSON batch.
+	maxNDJSONLine = 1 << 20
+)
+
+var ErrBatchTooLarge = errors.New("batch too large")
+
+// batchResult is the outcome of a receipt of a batch: its id and whether it
+// was created, or the problem it was rejected with.
+type batchResult struct {
+	Index  int      `json:"index"`
+	Status int      `json:"status"`
+	ID     string   `json:"id,omitempty"`
+	Error  *problem `json:"error,omitempty"`
+}
+
+type batchResults struct {
+	Results   []batchResult `json:"results"`
+	Succeeded int           `json:"succeeded"`
+	Failed    int           `json:"failed"`
+}
+
+// WithBatchLimits sets the maximum number of receipts of a batch and how many
+// of them are processed at the same time, 10000 and 8 by default. A value that
+// is not positive keeps its default.
+func WithBatchLimits(maxSize, concurrency int) Option {
+	return func(s *Server) {
+		if maxSize > 0 {
+			s.batchMaxSize = maxSize
+		}
+
+		if concurrency > 0 {
+			s.batchConcurrency = concurrency
+		}
+	}
+}
+
+/*
+saveReceiptBatch processes the receipts of a JSON array or of an NDJSON body,
+one receipt per line, concurrently. Every receipt is answered in its result in
+the order of the batch, an invalid receipt does not fail the others. Only a
+body that can not be read as a batch fails the whole request.
+*/
+func (s *Server) saveReceiptBatch(eCtx echo.Context) (err error) {
+	ctx := eCtx.Request().Context()
+	response := new(batchResults)
+
+	defer func() {
+		if err != nil {
+			err = apiReceiptResponse(eCtx, err)
+		} else {
+			err = apiReceiptResponse(eCtx, response)
+		}
+	}()
+
+	entries, err := s.readBatch(eCtx.Request())
+	if err != nil {
+		return err
+	}
+
+	response.Results = make([]batchResult, len(entries))
+	receipts := make([]*rcp.Receipt, len(entries))
+
+	s.batchWorkers(len(entries), func(index int) {
+		_, bind := tracing.Start(ctx, "http.Bind")
+		rcpt, err := s.decodeEntry(entries[index])
+		bind.RecordError(err)
+		bind.End()
+
+		if err != nil {
+			response.Results[index] = s.failedBatchEntry(ctx, index, err)
+
+			return
+		}
+
+		receipts[index] = rcpt
+	})
+
+	// the receipts that may be resubmissions of each other are saved one after
+	// the other in the order of the batch, so the duplicate check of the later
+	// ones sees the earlier ones.
+	groups := groupByMatchKey(receipts)
+
+	s.batchWorkers(len(groups), func(group int) {
+		for _, index := range groups[group] {
+			response.Results[index] = s.saveBatchEntry(ctx, index, receipts[index])
+		}
+	})
+
+	for _, result := range response.Results {
+		if result.Error != nil {
+			response.Failed++
+		} else {
+			response.Succeeded++
+		}
+	}
+
+	return nil
+}
+
+// batchWorkers calls work for every index below n, at most batchConcurrency
+// calls at the same time, and returns once they are done.
+func (s *Server) batchWorkers(n int, work func(index int)) {
+	indexes := make(chan int)
+	wg := sync.WaitGroup{}
+
+	for w := 0; w < min(s.batchConcurrency, n); w++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for index := range indexes {
+				work(index)
+			}
+		}()
+	}
+
+	for index := 0; index < n; index++ {
+		indexes <- index
+	}
+
+	close(indexes)
+	wg.Wait()
+}
+
+// groupByMatchKey returns the indexes of the decoded receipts grouped by their
+// match key, in the order of the batch.
+func groupByMatchKey(receipts []*rcp.Receipt) [][]int {
+	groups := [][]int{}
+	byKey := make(map[string]int)
+
+	for index, rcpt := range receipts {
+		if rcpt == nil {
+			continue
+		}
+
+		key := rcpt.MatchKey()
+
+		group, ok := byKey[key]
+		if !ok {
+			group = len(groups)
+			byKey[key] = group
+			groups = append(groups, nil)
+		}
+
+		groups[group] = append(groups[group], index)
+	}
+
+	return groups
+}
+
+func (s *Server) saveBatchEntry(ctx context.Context, index int, rcpt *rcp.Receipt) batchResult {
+	// the receipts left once the caller is gone are not saved.
+	if err := ctx.Err(); err != nil {
+		return s.failedBatchEntry(ctx, index, err)
+	}
+
+	id, created, err := s.receiptApp.SavePoints(ctx, *rcpt, "")
+	if err != nil {
+		return s.failedBatchEntry(ctx, index, err)
+	}
+
+	result := batchResult{Index: index, ID: id.String(), Status: http.StatusOK}
+
+	if created {
+		result.Status = http.StatusCreated
+	}
+
+	return result
+}
+
+// failedBatchEntry answers a failed receipt of a batch with its problem.
+func (s *Server) failedBatchEntry(ctx context.Context, index int, err error) batchResult {
+	p := newProblem(err)
+	s.logBatchEntry(ctx, index, p.Status, err)
+
+	return batchResult{Index: index, Status: p.Status, Error: &p}
+}
+
+// logBatchEntry logs a failed receipt of a batch, the request log only has the
+// status of the whole batch. As in the request logs the server errors are
+// logged as errors.
+func (s *Server) logBatchEntry(ctx context.Context, index, status int, err error) {
+	level := slog.LevelInfo
+	if status >= http.StatusInternalServerError {
+		level = slog.LevelError
+	}
+
+	s.logger.LogAttrs(ctx, level, "batch receipt failed",
+		slog.String("request_id", RequestID(ctx)),
+		slog.Int("index", index),
+		slog.Int("status", status),
+		slog.String("error", err.Error()),
+	)
+}
+
+// decodeEntry decodes and converts a receipt of a batch, it is checked against
+// the OpenAPI spec when the server enforces it.
+func (s *Server) decodeEntry(entry []byte) (*rcp.Receipt, error) {
+	if s.validateSpec {
+		value, err := decodeJSON(entry)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%w", err.Error(), ErrDecode)
+		}
+
+		if errs := apiSpec.Components.Schemas["Receipt"].validate("", value); len(errs) > 0 {
+			return nil, &requestError{fields: errs}
+		}
+	}
+
+	rcpt := new(receipt)
+
+	if err := json.Unmarshal(entry, rcpt); err != nil {
+		return nil, fmt.Errorf("%s:%w", err.Error(), ErrDecode)
+	}
+
+	return rcpt.toReceiptDomain()
+}
+
+// readBatch splits the body in the raw receipts, according to its content
+// type.
+func (s *Server) readBatch(req *http.Request) ([][]byte, error) {
+	contentType := req.Header.Get(echo.HeaderContentType)
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+
+	switch mediaType {
+	case echo.MIMEApplicationJSON:
+		return s.readJSONBatch(req.Body)
+	case mimeNDJSON:
+		return s.readNDJSONBatch(req.Body)
+	}
+
+	return nil, fmt.Errorf("unsupported content type %q:%w", contentType, ErrDecode)
+}
+
+/*
+readJSONBatch splits a JSON array in its elements without decoding them, so a
+malformed element only fails its own receipt as a malformed NDJSON line does.
+The elements are delimited by the commas outside of strings, objects and
+arrays. Only a body that is not an array, or an array that is not closed,
+fails the whole batch.
+*/
+func (s *Server) readJSONBatch(body io.Reader) ([][]byte, error) {
+	r := bufio.NewReader(body)
+
+	first, err := nextNonSpace(r)
+	if err != nil {
+		return nil, bodyError(err, "batch must be a JSON array")
+	}
+
+	if first != '[' {
+		return nil, fmt.Errorf("batch must be a JSON array:%w", ErrDecode)
+	}
+
+	var (
+		entries  = [][]byte{}
+		entry    []byte
+		depth    int
+		inString bool
+		escaped  bool
+	)
+
+	for {
+		c, err := r.ReadByte()
+		if errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("receipt %d: unexpected EOF:%w", len(entries), ErrDecode)
+		}
+
+		if err != nil {
+			return nil, bodyError(err, err.Error())
+		}
+
+		switch {
+		case inString:
+			switch {
+			case escaped:
+				escaped = false
+			case c == '\\':
+				escaped = true
+			case c == '"':
+				inString = false
+			}
+		case c == '"':
+			inString = true
+		case c == '{' || c == '[':
+			depth++
+		case (c == '}' || c == ']') && depth > 0:
+			depth--
+		case (c == ',' || c == ']') && depth == 0:
+			entry = bytes.TrimSpace(entry)
+
+			// an empty array has no element, an empty element is malformed.
+			if c == ',' || len(entry) > 0 || len(entries) > 0 {
+				if entries = append(entries, entry); len(entries) > s.batchMaxSize {
+					return nil, s.batchTooLarge()
+				}
+			}
+
+			entry = nil
+
+			if c == ']' {
+				return entries, s.endOfBatch(r)
+			}
+
+			continue
+		}
+
+		entry = append(entry, c)
+	}
+}
+
+// nextNonSpace returns the next byte of r that is not JSON white space.
+func nextNonSpace(r *bufio.Reader) (byte, error) {
+	for {
+		c, err := r.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+
+		switch c {
+		case ' ', '\t', '\n', '\r':
+			continue
+		}
+
+		return c, nil
+	}
+}
+
+// endOfBatch checks that nothing but white space follows the array.
+func (s *Server) endOfBatch(r *bufio.Reader) error {
+	_, err := nextNonSpace(r)
+
+	switch {
+	case errors.Is(err, io.EOF):
+		return nil
+	case err != nil:
+		return bodyError(err, err.Error())
+	}
+
+	return fmt.Errorf("unexpected data after the batch:%w", ErrDecode)
+}
+
+// readNDJSONBatch reads a receipt per line, the blank lines are skipped. The
+// lines are decoded on their own, so a malformed line only fails its receipt.
+func (s *Server) readNDJSONBatch(body io.Reader) ([][]byte, error) {
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 0, bufio.MaxScanTokenSize), maxNDJSONLine)
+
+	entries := [][]byte{}
+
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		if entries = append(entries, bytes.Clone(line)); len(entries) > s.batchMaxSize {
+			return nil, s.batchTooLarge()
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, bodyError(err, err.Error())
+	}
+
+	return entries, nil
+}
+
+func (s *Server) batchTooLarge() error {
+	return fmt.Errorf("a batch can not have more than %d receipts:%w", s.batchMaxSize, ErrBatchTooLarge)
+}
@@ -0,0 +1,386 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	rcp "receipt-processor-challenge/internal/domain/receipt"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// batchReceipt is a valid receipt of the given retailer, the mocks tell the
+// receipts of a batch apart by their retailer.
+func batchReceipt(retailer string) string {
+	return `{"retailer":"` + retailer + `","purchaseDate":"2022-01-01","purchaseTime":"13:01",` +
+		`"items":[{"shortDescription":"Mountain Dew 12PK","price":"6.49"}],"total":"6.49"}`
+}
+
+func retailer(name string) interface{} {
+	return mock.MatchedBy(func(r rcp.Receipt) bool { return r.Retailer == name })
+}
+
+func Test_SaveReceiptBatch(t *testing.T) {
+	first := uuid.MustParse("0a25c541-2ab9-41d9-bedb-2d518df5dc43")
+	second := uuid.MustParse("7fb1377b-b223-49d9-a31a-5a02701dd310")
+
+	cases := []struct {
+		name             string
+		contentType      string
+		body             string
+		options          []Option
+		expectedHTTPCode int
+		expectedResponse string
+	}{
+		{
+			name:        "json-case",
+			contentType: echo.MIMEApplicationJSON,
+			body: "[" + batchReceipt("first") + `,{"retailer":"first","total":"1.001"},` +
+				batchReceipt("second") + "," + batchReceipt("duplicate") + "]",
+			expectedHTTPCode: http.StatusOK,
+			expectedResponse: `{"results":[` +
+				`{"index":0,"status":201,"id":"0a25c541-2ab9-41d9-bedb-2d518df5dc43"},` +
+				`{"index":1,"status":400,"error":{"type":"about:blank","title":"Bad Request","status":400,` +
+				`"detail":"purchaseDate is required, purchaseTime is required, items is required, ` +
+				`total must be an amount with up to two decimals","errors":[` +
+				`{"field":"purchaseDate","rule":"required","message":"purchaseDate is required"},` +
+				`{"field":"purchaseTime","rule":"required","message":"purchaseTime is required"},` +
+				`{"field":"items","rule":"required","message":"items is required"},` +
+				`{"field":"total","rule":"money","message":"total must be an amount with up to two decimals"}]}},` +
+				`{"index":2,"status":200,"id":"7fb1377b-b223-49d9-a31a-5a02701dd310"},` +
+				`{"index":3,"status":409,"error":{"type":"about:blank","title":"Conflict","status":409,` +
+				`"detail":"receipt looks like a resubmission of receipt"}}],"succeeded":2,"failed":2}`,
+		},
+		{
+			name:             "ndjson-case",
+			contentType:      mimeNDJSON,
+			body:             batchReceipt("first") + "\n\n{\"retailer\":\n" + batchReceipt("second") + "\n",
+			expectedHTTPCode: http.StatusOK,
+			expectedResponse: `{"results":[` +
+				`{"index":0,"status":201,"id":"0a25c541-2ab9-41d9-bedb-2d518df5dc43"},` +
+				`{"index":1,"status":400,"error":{"type":"about:blank","title":"Bad Request","status":400,` +
+				`"detail":"unexpected end of JSON input"}},` +
+				`{"index":2,"status":200,"id":"7fb1377b-b223-49d9-a31a-5a02701dd310"}],"succeeded":2,"failed":1}`,
+		},
+		{
+			name:        "malformed-element-case",
+			contentType: echo.MIMEApplicationJSON,
+			body: "[" + batchReceipt("first") + `, {"retailer":}, {"retailer":"a,]}\"[{"}` + "," +
+				batchReceipt("second") + "]",
+			expectedHTTPCode: http.StatusOK,
+			expectedResponse: `{"results":[` +
+				`{"index":0,"status":201,"id":"0a25c541-2ab9-41d9-bedb-2d518df5dc43"},` +
+				`{"index":1,"status":400,"error":{"type":"about:blank","title":"Bad Request","status":400,` +
+				`"detail":"invalid character '}' looking for beginning of value"}},` +
+				`{"index":2,"status":400,"error":{"type":"about:blank","title":"Bad Request","status":400,` +
+				`"detail":"purchaseDate is required, purchaseTime is required, items is required, total is required",` +
+				`"errors":[` +
+				`{"field":"purchaseDate","rule":"required","message":"purchaseDate is required"},` +
+				`{"field":"purchaseTime","rule":"required","message":"purchaseTime is required"},` +
+				`{"field":"items","rule":"required","message":"items is required"},` +
+				`{"field":"total","rule":"required","message":"total is required"}]}},` +
+				`{"index":3,"status":200,"id":"7fb1377b-b223-49d9-a31a-5a02701dd310"}],"succeeded":2,"failed":2}`,
+		},
+		{
+			name:             "trailing-comma-case",
+			contentType:      echo.MIMEApplicationJSON,
+			body:             "[" + batchReceipt("first") + ",]",
+			expectedHTTPCode: http.StatusOK,
+			expectedResponse: `{"results":[` +
+				`{"index":0,"status":201,"id":"0a25c541-2ab9-41d9-bedb-2d518df5dc43"},` +
+				`{"index":1,"status":400,"error":{"type":"about:blank","title":"Bad Request","status":400,` +
+				`"detail":"unexpected end of JSON input"}}],"succeeded":1,"failed":1}`,
+		},
+		{
+			name:             "data-after-array-case",
+			contentType:      echo.MIMEApplicationJSON,
+			body:             "[" + batchReceipt("first") + "] []",
+			expectedHTTPCode: http.StatusBadRequest,
+			expectedResponse: `{"type":"about:blank","title":"Bad Request","status":400,` +
+				`"detail":"unexpected data after the batch"}`,
+		},
+		{
+			name:             "empty-case",
+			contentType:      echo.MIMEApplicationJSON,
+			body:             `[]`,
+			expectedHTTPCode: http.StatusOK,
+			expectedResponse: `{"results":[],"succeeded":0,"failed":0}`,
+		},
+		{
+			name:             "not-array-case",
+			contentType:      echo.MIMEApplicationJSON,
+			body:             batchReceipt("first"),
+			expectedHTTPCode: http.StatusBadRequest,
+			expectedResponse: `{"type":"about:blank","title":"Bad Request","status":400,` +
+				`"detail":"batch must be a JSON array"}`,
+		},
+		{
+			name:             "malformed-array-case",
+			contentType:      echo.MIMEApplicationJSON,
+			body:             "[" + batchReceipt("first") + ",{",
+			expectedHTTPCode: http.StatusBadRequest,
+			expectedResponse: `{"type":"about:blank","title":"Bad Request","status":400,` +
+				`"detail":"receipt 1: unexpected EOF"}`,
+		},
+		{
+			name:             "unsupported-content-type-case",
+			contentType:      echo.MIMETextPlain,
+			body:             batchReceipt("first"),
+			expectedHTTPCode: http.StatusBadRequest,
+			expectedResponse: `{"type":"about:blank","title":"Bad Request","status":400,` +
+				`"detail":"unsupported content type \"text/plain\""}`,
+		},
+		{
+			name:             "too-large-case",
+			contentType:      mimeNDJSON,
+			body:             batchReceipt("first") + "\n" + batchReceipt("second") + "\n" + batchReceipt("first"),
+			options:          []Option{WithBatchLimits(2, 1)},
+			expectedHTTPCode: http.StatusRequestEntityTooLarge,
+			expectedResponse: `{"type":"about:blank","title":"Request Entity Too Large","status":413,` +
+				`"detail":"a batch can not have more than 2 receipts"}`,
+		},
+		{
+			name:        "spec-validation-case",
+			contentType: echo.MIMEApplicationJSON,
+			body: "[" + strings.Replace(batchReceipt("first"), `"total"`, `"coupon":"X","total"`, 1) + "," +
+				batchReceipt("second") + "]",
+			options:          []Option{WithSpecValidation()},
+			expectedHTTPCode: http.StatusOK,
+			expectedResponse: `{"results":[` +
+				`{"index":0,"status":400,"error":{"type":"about:blank","title":"Bad Request","status":400,` +
+				`"detail":"coupon is not allowed","errors":[` +
+				`{"field":"coupon","rule":"additionalProperties","message":"coupon is not allowed"}]}},` +
+				`{"index":1,"status":200,"id":"7fb1377b-b223-49d9-a31a-5a02701dd310"}],"succeeded":1,"failed":1}`,
+		},
+		{
+			name:             "spec-validation-malformed-element-case",
+			contentType:      echo.MIMEApplicationJSON,
+			body:             `[{"retailer":},` + batchReceipt("second") + "]",
+			options:          []Option{WithSpecValidation()},
+			expectedHTTPCode: http.StatusOK,
+			expectedResponse: `{"results":[` +
+				`{"index":0,"status":400,"error":{"type":"about:blank","title":"Bad Request","status":400,` +
+				`"detail":"invalid character '}' looking for beginning of value"}},` +
+				`{"index":1,"status":200,"id":"7fb1377b-b223-49d9-a31a-5a02701dd310"}],"succeeded":1,"failed":1}`,
+		},
+	}
+
+	for _, c := range cases {
+		c := c
+
+		t.Run(c.name, func(t *testing.T) {
+			apiMock := &receiptAPIMock{}
+			apiMock.On("SavePoints", mock.Anything, retailer("first"), "").Return(first, true, nil)
+			apiMock.On("SavePoints", mock.Anything, retailer("second"), "").Return(second, false, nil)
+			apiMock.On("SavePoints", mock.Anything, retailer("duplicate"), "").Return(nil, false, rcp.ErrSuspectedDuplicate)
+
+			s := NewServer(context.Background(), apiMock, c.options...)
+			s.routes()
+
+			req := httptest.NewRequest(echo.POST, "/receipt/process/batch", strings.NewReader(c.body))
+			req.Header.Set(echo.HeaderContentType, c.contentType)
+			rec := httptest.NewRecorder()
+
+			s.router.ServeHTTP(rec, req)
+
+			assert.Equal(t, c.expectedHTTPCode, rec.Code)
+			assert.JSONEq(t, c.expectedResponse, rec.Body.String())
+		})
+	}
+}
+
+func Test_SaveReceiptBatchConcurrency(t *testing.T) {
+	const (
+		size        = 40
+		concurrency = 4
+	)
+
+	var running, maxRunning atomic.Int32
+
+	apiMock := &receiptAPIMock{}
+	apiMock.On("SavePoints", mock.Anything, mock.Anything, "").Return(uuid.New(), true, nil).Run(func(mock.Arguments) {
+		n := running.Add(1)
+		defer running.Add(-1)
+
+		for {
+			current := maxRunning.Load()
+			if n <= current || maxRunning.CompareAndSwap(current, n) {
+				break
+			}
+		}
+
+		time.Sleep(time.Millisecond)
+	})
+
+	s := NewServer(context.Background(), apiMock, WithBatchLimits(size, concurrency))
+	s.routes()
+
+	entries := make([]string, size)
+	for i := range entries {
+		entries[i] = batchReceipt(fmt.Sprintf("retailer %d", i))
+	}
+
+	req := httptest.NewRequest(echo.POST, "/receipt/process/batch", strings.NewReader("["+strings.Join(entries, ",")+"]"))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+
+	s.router.ServeHTTP(rec, req)
+
+	response := batchResults{}
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+	assert.Equal(t, size, response.Succeeded)
+
+	for i, result := range response.Results {
+		assert.Equal(t, i, result.Index)
+	}
+
+	assert.LessOrEqual(t, maxRunning.Load(), int32(concurrency))
+	apiMock.AssertNumberOfCalls(t, "SavePoints", size)
+}
+
+func Test_SaveReceiptBatchNearDuplicates(t *testing.T) {
+	original := uuid.New()
+
+	var running, overlapped atomic.Int32
+
+	track := func(mock.Arguments) {
+		if running.Add(1) > 1 {
+			overlapped.Add(1)
+		}
+
+		time.Sleep(time.Millisecond)
+		running.Add(-1)
+	}
+
+	// only the first receipt is new, the others are checked against it.
+	apiMock := &receiptAPIMock{}
+	apiMock.On("SavePoints", mock.Anything, retailer("Target"), "").Return(original, true, nil).Once().Run(track)
+	apiMock.On("SavePoints", mock.Anything, retailer("Target"), "").Return(nil, false, rcp.ErrSuspectedDuplicate).Run(track)
+
+	s := NewServer(context.Background(), apiMock, WithBatchLimits(10, 4))
+	s.routes()
+
+	body := "[" + batchReceipt("Target") + "," + batchReceipt("Target") + "," +
+		strings.Replace(batchReceipt("Target"), "Mountain Dew 12PK", "Mountain Dew 12 PK", 1) + "]"
+	req := httptest.NewRequest(echo.POST, "/receipt/process/batch", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+
+	s.router.ServeHTTP(rec, req)
+
+	response := batchResults{}
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+	assert.Equal(t, 1, response.Succeeded)
+	assert.Equal(t, http.StatusCreated, response.Results[0].Status)
+	assert.Equal(t, original.String(), response.Results[0].ID)
+	assert.Equal(t, http.StatusConflict, response.Results[1].Status)
+	assert.Equal(t, http.StatusConflict, response.Results[2].Status)
+	assert.Zero(t, overlapped.Load())
+	apiMock.AssertNumberOfCalls(t, "SavePoints", 3)
+}
+
+func Test_SaveReceiptBatchCanceled(t *testing.T) {
+	apiMock := &receiptAPIMock{}
+
+	s := NewServer(context.Background(), apiMock)
+	s.routes()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	req := httptest.NewRequest(echo.POST, "/receipt/process/batch", strings.NewReader("["+batchReceipt("first")+"]"))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+
+	s.router.ServeHTTP(rec, req.WithContext(ctx))
+
+	response := batchResults{}
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+	assert.Equal(t, 1, response.Failed)
+	assert.Equal(t, http.StatusInternalServerError, response.Results[0].Status)
+	apiMock.AssertNotCalled(t, "SavePoints", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func Test_SaveReceiptBatchLogsFailures(t *testing.T) {
+	apiMock := &receiptAPIMock{}
+	apiMock.On("SavePoints", mock.Anything, retailer("first"), "").Return(uuid.New(), true, nil)
+	apiMock.On("SavePoints", mock.Anything, retailer("broken"), "").Return(nil, false, errors.New("connection refused"))
+
+	out := bytes.Buffer{}
+	s := NewServer(context.Background(), apiMock, WithLogger(slog.New(slog.NewJSONHandler(&out, nil))))
+	s.routes()
+
+	body := "[" + batchReceipt("first") + `,{"retailer":"first"},` + batchReceipt("broken") + "]"
+	req := httptest.NewRequest(echo.POST, "/receipt/process/batch", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.Header.Set(echo.HeaderXRequestID, "req-1")
+	s.router.ServeHTTP(httptest.NewRecorder(), req)
+
+	failures := map[float64]map[string]any{}
+
+	for _, line := range bytes.Split(bytes.TrimSpace(out.Bytes()), []byte("\n")) {
+		entry := map[string]any{}
+		assert.NoError(t, json.Unmarshal(line, &entry))
+
+		if entry["msg"] == "batch receipt failed" {
+			failures[entry["index"].(float64)] = entry
+		}
+	}
+
+	assert.Len(t, failures, 2)
+
+	assert.Equal(t, "INFO", failures[1]["level"])
+	assert.Equal(t, "req-1", failures[1]["request_id"])
+	assert.Equal(t, float64(http.StatusBadRequest), failures[1]["status"])
+	assert.Contains(t, failures[1]["error"], "purchaseDate is required")
+
+	// the answer hides the cause, the log keeps it.
+	assert.Equal(t, "ERROR", failures[2]["level"])
+	assert.Equal(t, "req-1", failures[2]["request_id"])
+	assert.Equal(t, float64(http.StatusInternalServerError), failures[2]["status"])
+	assert.Equal(t, "connection refused", failures[2]["error"])
+}
+
+func Test_WithBatchLimits(t *testing.T) {
+	cases := []struct {
+		name                string
+		maxSize             int
+		concurrency         int
+		expectedMaxSize     int
+		expectedConcurrency int
+	}{
+		{name: "set-case", maxSize: 10, concurrency: 2, expectedMaxSize: 10, expectedConcurrency: 2},
+		{name: "zero-case", expectedMaxSize: defaultBatchMaxSize, expectedConcurrency: defaultBatchConcurrency},
+		{
+			name:                "negative-case",
+			maxSize:             -1,
+			concurrency:         -1,
+			expectedMaxSize:     defaultBatchMaxSize,
+			expectedConcurrency: defaultBatchConcurrency,
+		},
+	}
+
+	for _, c := range cases {
+		c := c
+
+		t.Run(c.name, func(t *testing.T) {
+			s := NewServer(context.Background(), &receiptAPIMock{}, WithBatchLimits(c.maxSize, c.concurrency))
+
+			assert.Equal(t, c.expectedMaxSize, s.batchMaxSize)
+			assert.Equal(t, c.expectedConcurrency, s.batchConcurrency)
+		})
+	}
+}
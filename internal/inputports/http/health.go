@@ -0,0 +1,111 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	healthPath string = "/healthz"
+	readyPath  string = "/readyz"
+
+	statusUp   string = "up"
+	statusDown string = "down"
+
+	healthTimeOut = 2 * time.Second
+)
+
+var errShuttingDown = errors.New("shutting down")
+
+/*
+HealthCheck is a dependency reported by the health endpoints. Every check is
+run by /readyz, the ones marked Liveness are also run by /healthz: only checks
+of the process itself should be, a restart does not fix a database that can not
+be reached. A failing Optional check is reported without failing the endpoint.
+*/
+type HealthCheck struct {
+	Name     string
+	Check    func(ctx context.Context) error
+	Liveness bool
+	Optional bool
+}
+
+type health struct {
+	Status     string                     `json:"status"`
+	Components map[string]componentHealth `json:"components"`
+}
+
+type componentHealth struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// WithHealthChecks adds the dependencies checked by /healthz and /readyz.
+func WithHealthChecks(checks ...HealthCheck) Option {
+	return func(s *Server) {
+		s.checks = append(s.checks, checks...)
+	}
+}
+
+// getHealth answers whether the service is alive.
+func (s *Server) getHealth(eCtx echo.Context) error {
+	checks := []HealthCheck{}
+
+	for _, check := range s.checks {
+		if check.Liveness {
+			checks = append(checks, check)
+		}
+	}
+
+	return s.healthResponse(eCtx, checks, nil)
+}
+
+// getReadiness answers whether the service can take requests, it is not ready
+// once it starts shutting down.
+func (s *Server) getReadiness(eCtx echo.Context) error {
+	var serverErr error
+	if s.shuttingDown.Load() {
+		serverErr = errShuttingDown
+	}
+
+	return s.healthResponse(eCtx, s.checks, serverErr)
+}
+
+func (s *Server) healthResponse(eCtx echo.Context, checks []HealthCheck, serverErr error) error {
+	ctx, cancel := context.WithTimeout(eCtx.Request().Context(), healthTimeOut)
+	defer cancel()
+
+	response := health{
+		Status:     statusUp,
+		Components: map[string]componentHealth{"server": {Status: statusUp}},
+	}
+
+	if serverErr != nil {
+		response.Status = statusDown
+		response.Components["server"] = componentHealth{Status: statusDown, Error: serverErr.Error()}
+	}
+
+	for _, check := range checks {
+		if err := check.Check(ctx); err != nil {
+			response.Components[check.Name] = componentHealth{Status: statusDown, Error: err.Error()}
+
+			if !check.Optional {
+				response.Status = statusDown
+			}
+
+			continue
+		}
+
+		response.Components[check.Name] = componentHealth{Status: statusUp}
+	}
+
+	if response.Status == statusDown {
+		return eCtx.JSON(http.StatusServiceUnavailable, response)
+	}
+
+	return eCtx.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,95 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Health(t *testing.T) {
+	up := func(context.Context) error { return nil }
+	down := func(context.Context) error { return errors.New("connection refused") }
+
+	cases := []struct {
+		name             string
+		path             string
+		checks           []HealthCheck
+		shuttingDown     bool
+		expectedResponse []byte
+		expectedHTTPCode int
+	}{
+		{
+			name:             "no-checks-case",
+			path:             healthPath,
+			expectedResponse: []byte(`{"status":"up","components":{"server":{"status":"up"}}}`),
+			expectedHTTPCode: http.StatusOK,
+		},
+		{
+			name: "liveness-only-case",
+			path: healthPath,
+			checks: []HealthCheck{
+				{Name: "storage", Check: up, Liveness: true},
+				{Name: "database", Check: down},
+			},
+			expectedResponse: []byte(`{"status":"up","components":{"server":{"status":"up"},"storage":{"status":"up"}}}`),
+			expectedHTTPCode: http.StatusOK,
+		},
+		{
+			name:   "liveness-down-case",
+			path:   healthPath,
+			checks: []HealthCheck{{Name: "storage", Check: down, Liveness: true}},
+			expectedResponse: []byte(`{"status":"down","components":{"server":{"status":"up"},` +
+				`"storage":{"status":"down","error":"connection refused"}}}`),
+			expectedHTTPCode: http.StatusServiceUnavailable,
+		},
+		{
+			name: "ready-case",
+			path: readyPath,
+			checks: []HealthCheck{
+				{Name: "storage", Check: up},
+				{Name: "cache", Check: down, Optional: true},
+			},
+			expectedResponse: []byte(`{"status":"up","components":{"cache":{"status":"down","error":"connection refused"},` +
+				`"server":{"status":"up"},"storage":{"status":"up"}}}`),
+			expectedHTTPCode: http.StatusOK,
+		},
+		{
+			name:   "not-ready-case",
+			path:   readyPath,
+			checks: []HealthCheck{{Name: "storage", Check: down}},
+			expectedResponse: []byte(`{"status":"down","components":{"server":{"status":"up"},` +
+				`"storage":{"status":"down","error":"connection refused"}}}`),
+			expectedHTTPCode: http.StatusServiceUnavailable,
+		},
+		{
+			name:         "shutting-down-case",
+			path:         readyPath,
+			checks:       []HealthCheck{{Name: "storage", Check: up}},
+			shuttingDown: true,
+			expectedResponse: []byte(`{"status":"down","components":{"server":{"status":"down","error":"shutting down"},` +
+				`"storage":{"status":"up"}}}`),
+			expectedHTTPCode: http.StatusServiceUnavailable,
+		},
+	}
+
+	for _, c := range cases {
+		c := c
+
+		t.Run(c.name, func(t *testing.T) {
+			s := NewServer(context.Background(), &receiptAPIMock{}, WithHealthChecks(c.checks...))
+			s.shuttingDown.Store(c.shuttingDown)
+			s.routes()
+
+			rec := httptest.NewRecorder()
+			s.router.ServeHTTP(rec, httptest.NewRequest(echo.GET, c.path, nil))
+
+			assert.Equal(t, append(c.expectedResponse, paddingLastByte(t)...), rec.Body.Bytes())
+			assert.Equal(t, c.expectedHTTPCode, rec.Code)
+		})
+	}
+}
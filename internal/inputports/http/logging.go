@@ -0,0 +1,138 @@
+package http
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"receipt-processor-challenge/internal/tracing"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	// maxRequestIDLength limits the request ids accepted from the callers.
+	maxRequestIDLength = 128
+
+	// keys of the values the handlers leave in the echo context for the
+	// request log.
+	errorKey     string = "error"
+	receiptIDKey string = "receipt_id"
+)
+
+type requestIDKey struct{}
+
+// WithLogger sets the logger of the server and of the request logs,
+// slog.Default() by default.
+func WithLogger(logger *slog.Logger) Option {
+	return func(s *Server) {
+		s.logger = logger
+	}
+}
+
+// RequestID returns the id of the request ctx belongs to, empty outside of a
+// request.
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+
+	return id
+}
+
+// requestID keeps the X-Request-ID of the caller, or generates one, and sends
+// it back in the response.
+func (s *Server) requestID(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(eCtx echo.Context) error {
+		req := eCtx.Request()
+
+		id := req.Header.Get(echo.HeaderXRequestID)
+		if !validRequestID(id) {
+			id = uuid.NewString()
+		}
+
+		eCtx.Response().Header().Set(echo.HeaderXRequestID, id)
+		eCtx.SetRequest(req.WithContext(context.WithValue(req.Context(), requestIDKey{}, id)))
+
+		return next(eCtx)
+	}
+}
+
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+
+	for i := 0; i < len(id); i++ {
+		if id[i] < '!' || id[i] > '~' {
+			return false
+		}
+	}
+
+	return true
+}
+
+/*
+logRequests logs a line per request with its route, status and latency, the
+receipt it is about and the error answered, with its cause. Server errors are
+logged at error level, the probes of the health and metrics endpoints at debug
+level and the rest at info level.
+*/
+func (s *Server) logRequests(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(eCtx echo.Context) error {
+		start := time.Now()
+
+		err := next(eCtx)
+		if err != nil {
+			eCtx.Error(err)
+		}
+
+		req := eCtx.Request()
+		ctx := req.Context()
+		status := eCtx.Response().Status
+
+		route := eCtx.Path()
+		if route == "" {
+			route = unmatchedRoute
+		}
+
+		attrs := []slog.Attr{
+			slog.String("request_id", RequestID(ctx)),
+			slog.String("method", req.Method),
+			slog.String("route", route),
+			slog.Int("status", status),
+			slog.Duration("latency", time.Since(start)),
+		}
+
+		if id, ok := eCtx.Get(receiptIDKey).(string); ok {
+			attrs = append(attrs, slog.String(receiptIDKey, id))
+		} else if id := eCtx.Param("id"); id != "" {
+			attrs = append(attrs, slog.String(receiptIDKey, id))
+		}
+
+		if span := tracing.FromContext(ctx); span != nil {
+			attrs = append(attrs, slog.String("trace_id", span.Context().TraceID.String()))
+		}
+
+		if answered, ok := eCtx.Get(errorKey).(error); ok {
+			err = answered
+		}
+
+		if err != nil {
+			attrs = append(attrs, slog.String("error", err.Error()))
+		}
+
+		level := slog.LevelInfo
+
+		switch {
+		case status >= http.StatusInternalServerError:
+			level = slog.LevelError
+		case route == healthPath || route == readyPath || route == metricsPath:
+			level = slog.LevelDebug
+		}
+
+		s.logger.LogAttrs(ctx, level, "request", attrs...)
+
+		return nil
+	}
+}
@@ -0,0 +1,125 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	rcp "receipt-processor-challenge/internal/domain/receipt"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func Test_RequestID(t *testing.T) {
+	cases := []struct {
+		name      string
+		requestID string
+		kept      bool
+	}{
+		{name: "propagated-case", requestID: "req-42", kept: true},
+		{name: "missing-case", requestID: ""},
+		{name: "invalid-case", requestID: "with spaces"},
+		{name: "too-long-case", requestID: strings.Repeat("a", maxRequestIDLength+1)},
+	}
+
+	for _, c := range cases {
+		c := c
+
+		t.Run(c.name, func(t *testing.T) {
+			var seen string
+
+			handler := (&Server{}).requestID(func(eCtx echo.Context) error {
+				seen = RequestID(eCtx.Request().Context())
+
+				return nil
+			})
+
+			req := httptest.NewRequest(echo.GET, "/healthz", nil)
+			req.Header.Set(echo.HeaderXRequestID, c.requestID)
+			rec := httptest.NewRecorder()
+
+			assert.NoError(t, handler(echo.New().NewContext(req, rec)))
+			assert.Equal(t, seen, rec.Header().Get(echo.HeaderXRequestID))
+
+			if c.kept {
+				assert.Equal(t, c.requestID, seen)
+			} else {
+				_, err := uuid.Parse(seen)
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func Test_LogRequests(t *testing.T) {
+	id := uuid.New()
+	apiMock := &receiptAPIMock{}
+	apiMock.On("GetPoints", mock.Anything, id).Return(&rcp.Points{Points: 28}, nil)
+	apiMock.On("GetReceipt", mock.Anything, id).Return(nil, errors.New("connection refused"))
+
+	out := bytes.Buffer{}
+	logger := slog.New(slog.NewJSONHandler(&out, &slog.HandlerOptions{Level: slog.LevelInfo}))
+
+	s := NewServer(context.Background(), apiMock, WithLogger(logger))
+	s.routes()
+
+	req := httptest.NewRequest(echo.GET, "/receipt/"+id.String()+"/points", nil)
+	req.Header.Set(echo.HeaderXRequestID, "req-1")
+	s.router.ServeHTTP(httptest.NewRecorder(), req)
+	s.router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(echo.GET, "/receipt/"+id.String(), nil))
+	s.router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(echo.GET, "/healthz", nil))
+
+	lines := bytes.Split(bytes.TrimSpace(out.Bytes()), []byte("\n"))
+	assert.Len(t, lines, 2)
+
+	entries := make([]map[string]any, len(lines))
+	for i := range lines {
+		assert.NoError(t, json.Unmarshal(lines[i], &entries[i]))
+	}
+
+	assert.Equal(t, "INFO", entries[0]["level"])
+	assert.Equal(t, "request", entries[0]["msg"])
+	assert.Equal(t, "req-1", entries[0]["request_id"])
+	assert.Equal(t, "GET", entries[0]["method"])
+	assert.Equal(t, "/receipt/:id/points", entries[0]["route"])
+	assert.Equal(t, float64(http.StatusOK), entries[0]["status"])
+	assert.Equal(t, id.String(), entries[0]["receipt_id"])
+	assert.Contains(t, entries[0], "latency")
+	assert.NotContains(t, entries[0], "error")
+
+	// the answer hides the cause, the log keeps it.
+	assert.Equal(t, "ERROR", entries[1]["level"])
+	assert.Equal(t, float64(http.StatusInternalServerError), entries[1]["status"])
+	assert.Equal(t, "storage error:connection refused", entries[1]["error"])
+	assert.NotEmpty(t, entries[1]["request_id"])
+}
+
+func Test_LogSavedReceipt(t *testing.T) {
+	id := uuid.New()
+	apiMock := &receiptAPIMock{}
+	apiMock.On("SavePoints", mock.Anything, mock.Anything, "").Return(id, true, nil)
+
+	out := bytes.Buffer{}
+	s := NewServer(context.Background(), apiMock, WithLogger(slog.New(slog.NewJSONHandler(&out, nil))))
+	s.routes()
+
+	req := httptest.NewRequest(echo.POST, "/receipt/process", strings.NewReader(`{"retailer":"Target",`+
+		`"purchaseDate":"2022-01-01","purchaseTime":"13:01",`+
+		`"items":[{"shortDescription":"Mountain Dew 12PK","price":"6.49"}],"total":"6.49"}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	s.router.ServeHTTP(httptest.NewRecorder(), req)
+
+	entry := map[string]any{}
+	assert.NoError(t, json.Unmarshal(out.Bytes(), &entry))
+	assert.Equal(t, float64(http.StatusCreated), entry["status"])
+	assert.Equal(t, id.String(), entry["receipt_id"])
+}
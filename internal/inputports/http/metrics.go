@@ -0,0 +1,66 @@
+package http
+
+import (
+	"strconv"
+	"time"
+
+	"receipt-processor-challenge/internal/interfaceadapters/metrics"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	metricsPath string = "/metrics"
+
+	// unmatchedRoute is the route label of the requests no route matched.
+	unmatchedRoute string = "unmatched"
+)
+
+type httpMetrics struct {
+	registry *metrics.Registry
+	requests *metrics.Counter
+	latency  *metrics.Histogram
+}
+
+// WithMetrics records the requests of every route in registry and serves the
+// registry at /metrics in the Prometheus text format.
+func WithMetrics(registry *metrics.Registry) Option {
+	return func(s *Server) {
+		s.metrics = &httpMetrics{
+			registry: registry,
+			requests: registry.NewCounter("http_requests_total",
+				"Requests handled by route, method and status code.", "route", "method", "code"),
+			latency: registry.NewHistogram("http_request_duration_seconds",
+				"Latency of the requests by route and method.", metrics.DefaultBuckets(), "route", "method"),
+		}
+	}
+}
+
+// instrument records the requests, the route is the path pattern so the ids do
+// not create a series each.
+func (s *Server) instrument(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(eCtx echo.Context) error {
+		start := time.Now()
+
+		if err := next(eCtx); err != nil {
+			eCtx.Error(err)
+		}
+
+		route := eCtx.Path()
+		if route == "" {
+			route = unmatchedRoute
+		}
+
+		method := eCtx.Request().Method
+		s.metrics.requests.Inc(route, method, strconv.Itoa(eCtx.Response().Status))
+		s.metrics.latency.Observe(time.Since(start).Seconds(), route, method)
+
+		return nil
+	}
+}
+
+func (s *Server) getMetrics(eCtx echo.Context) error {
+	eCtx.Response().Header().Set(echo.HeaderContentType, metrics.ContentType)
+
+	return s.metrics.registry.Expose(eCtx.Response())
+}
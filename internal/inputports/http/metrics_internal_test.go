@@ -0,0 +1,51 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	rcp "receipt-processor-challenge/internal/domain/receipt"
+	"receipt-processor-challenge/internal/interfaceadapters/metrics"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func Test_Metrics(t *testing.T) {
+	id := uuid.New()
+	apiMock := &receiptAPIMock{}
+	apiMock.On("GetPoints", mock.Anything, id).Return(&rcp.Points{Points: 28}, nil)
+
+	registry := metrics.NewRegistry()
+	s := NewServer(context.Background(), apiMock, WithMetrics(registry))
+	s.routes()
+
+	for _, path := range []string{"/receipt/" + id.String() + "/points", "/receipt/" + id.String() + "/points", "/unknown"} {
+		s.router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(echo.GET, path, nil))
+	}
+
+	rec := httptest.NewRecorder()
+	s.router.ServeHTTP(rec, httptest.NewRequest(echo.GET, "/metrics", nil))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, metrics.ContentType, rec.Header().Get(echo.HeaderContentType))
+
+	body := rec.Body.String()
+	assert.Contains(t, body, `http_requests_total{route="/receipt/:id/points",method="GET",code="200"} 2`)
+	assert.Contains(t, body, `http_requests_total{route="unmatched",method="GET",code="404"} 1`)
+	assert.Contains(t, body, `http_request_duration_seconds_count{route="/receipt/:id/points",method="GET"} 2`)
+	assert.NotContains(t, body, id.String())
+}
+
+func Test_MetricsDisabled(t *testing.T) {
+	s := NewServer(context.Background(), &receiptAPIMock{})
+	s.routes()
+
+	rec := httptest.NewRecorder()
+	s.router.ServeHTTP(rec, httptest.NewRequest(echo.GET, "/metrics", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
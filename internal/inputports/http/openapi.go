@@ -0,0 +1,450 @@
+package http
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	specPath string = "/openapi.json"
+	docsPath string = "/docs"
+)
+
+var (
+	ErrInvalidSpec      = errors.New("invalid openapi spec")
+	ErrResponseMismatch = errors.New("response does not match the openapi spec")
+)
+
+// openAPIDocument is the OpenAPI 3 description of the receipt endpoints.
+//
+//go:embed openapi.json
+var openAPIDocument []byte
+
+//nolint:gochecknoglobals
+var apiSpec = mustLoadSpec(openAPIDocument)
+
+// docsPage is the Swagger UI of the spec served at specPath.
+const docsPage string = `<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <title>Receipt Processor API</title>
+  <link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@5/swagger-ui.css">
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="https://unpkg.com/swagger-ui-dist@5/swagger-ui-bundle.js" crossorigin></script>
+  <script>
+    window.onload = () => {
+      window.ui = SwaggerUIBundle({url: "` + specPath + `", dom_id: "#swagger-ui"});
+    };
+  </script>
+</body>
+</html>
+`
+
+// openAPI is the part of the OpenAPI document the server checks the requests
+// and responses with.
+type openAPI struct {
+	Paths      map[string]map[string]*operation `json:"paths"`
+	Components struct {
+		Schemas    map[string]*schema    `json:"schemas"`
+		Parameters map[string]*parameter `json:"parameters"`
+		Responses  map[string]*response  `json:"responses"`
+	} `json:"components"`
+}
+
+type operation struct {
+	Parameters  []*parameter         `json:"parameters"`
+	RequestBody *requestBody         `json:"requestBody"`
+	Responses   map[string]*response `json:"responses"`
+}
+
+type parameter struct {
+	Ref      string  `json:"$ref"`
+	Name     string  `json:"name"`
+	In       string  `json:"in"`
+	Required bool    `json:"required"`
+	Schema   *schema `json:"schema"`
+}
+
+type requestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]mediaType `json:"content"`
+}
+
+type response struct {
+	Ref     string               `json:"$ref"`
+	Content map[string]mediaType `json:"content"`
+}
+
+type mediaType struct {
+	Schema *schema `json:"schema"`
+	// ValidatedByHandler leaves the check of the body to the handler, e.g. a
+	// batch checks every receipt on its own.
+	ValidatedByHandler bool `json:"x-validated-by-handler"`
+}
+
+func mustLoadSpec(document []byte) *openAPI {
+	spec, err := loadSpec(document)
+	if err != nil {
+		panic(err)
+	}
+
+	return spec
+}
+
+// loadSpec decodes the document and resolves its references, so the checks
+// do not have to.
+func loadSpec(document []byte) (*openAPI, error) {
+	spec := new(openAPI)
+
+	if err := json.Unmarshal(document, spec); err != nil {
+		return nil, fmt.Errorf("%s:%w", err.Error(), ErrInvalidSpec)
+	}
+
+	for _, s := range spec.Components.Schemas {
+		if _, err := s.resolve(spec.Components.Schemas); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, methods := range spec.Paths {
+		for _, op := range methods {
+			if err := spec.resolveOperation(op); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return spec, nil
+}
+
+func (spec *openAPI) resolveOperation(op *operation) error {
+	schemas := spec.Components.Schemas
+
+	for i, param := range op.Parameters {
+		if param.Ref != "" {
+			name, _ := strings.CutPrefix(param.Ref, "#/components/parameters/")
+			if spec.Components.Parameters[name] == nil {
+				return fmt.Errorf("%s:%w", param.Ref, ErrInvalidSpec)
+			}
+
+			param = spec.Components.Parameters[name]
+			op.Parameters[i] = param
+		}
+
+		resolved, err := param.Schema.resolve(schemas)
+		if err != nil {
+			return err
+		}
+
+		param.Schema = resolved
+	}
+
+	if op.RequestBody != nil {
+		if err := resolveContent(op.RequestBody.Content, schemas); err != nil {
+			return err
+		}
+	}
+
+	for status, res := range op.Responses {
+		if res.Ref != "" {
+			name, _ := strings.CutPrefix(res.Ref, "#/components/responses/")
+			if spec.Components.Responses[name] == nil {
+				return fmt.Errorf("%s:%w", res.Ref, ErrInvalidSpec)
+			}
+
+			res = spec.Components.Responses[name]
+			op.Responses[status] = res
+		}
+
+		if err := resolveContent(res.Content, schemas); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func resolveContent(content map[string]mediaType, schemas map[string]*schema) error {
+	for name, media := range content {
+		resolved, err := media.Schema.resolve(schemas)
+		if err != nil {
+			return err
+		}
+
+		media.Schema = resolved
+		content[name] = media
+	}
+
+	return nil
+}
+
+// operation returns the operation of an echo route, e.g. "/receipt/:id",
+// false when the spec does not describe it.
+func (spec *openAPI) operation(method, route string) (*operation, bool) {
+	op, ok := spec.Paths[specRoute(route)][strings.ToLower(method)]
+
+	return op, ok
+}
+
+// specRoute writes the parameters of an echo route as in the spec paths, e.g.
+// "/receipt/:id" as "/receipt/{id}".
+func specRoute(route string) string {
+	segments := strings.Split(route, "/")
+
+	for i, segment := range segments {
+		if name, ok := strings.CutPrefix(segment, ":"); ok {
+			segments[i] = "{" + name + "}"
+		}
+	}
+
+	return strings.Join(segments, "/")
+}
+
+/*
+validateRequest checks the parameters and the body of the request and returns
+a requestError with every field that does not match the spec. The body is read
+and put back for the handler.
+*/
+func (op *operation) validateRequest(eCtx echo.Context) error {
+	req := eCtx.Request()
+	errs := []fieldError{}
+
+	for _, param := range op.Parameters {
+		var (
+			value string
+			found bool
+		)
+
+		switch param.In {
+		case "path":
+			value = eCtx.Param(param.Name)
+			found = value != ""
+		case "query":
+			found = eCtx.QueryParams().Has(param.Name)
+			value = eCtx.QueryParam(param.Name)
+		case "header":
+			value = req.Header.Get(param.Name)
+			found = value != ""
+		}
+
+		if !found {
+			if param.Required {
+				errs = append(errs, fieldError{
+					Field:   param.Name,
+					Rule:    "required",
+					Message: fmt.Sprintf("%s is required", param.Name),
+				})
+			}
+
+			continue
+		}
+
+		errs = append(errs, param.Schema.validate(param.Name, parameterValue(param.Schema, value))...)
+	}
+
+	if op.RequestBody != nil {
+		bodyErrs, err := op.validateBody(req)
+		if err != nil {
+			return err
+		}
+
+		errs = append(errs, bodyErrs...)
+	}
+
+	if len(errs) > 0 {
+		return &requestError{fields: errs}
+	}
+
+	return nil
+}
+
+// parameterValue converts a parameter to the type of its schema, the value is
+// left as a string when it does not convert so the check reports it.
+func parameterValue(s *schema, value string) interface{} {
+	if s.Type != "integer" && s.Type != "number" {
+		return value
+	}
+
+	if _, err := strconv.ParseFloat(value, 64); err != nil {
+		return value
+	}
+
+	return json.Number(value)
+}
+
+func (op *operation) validateBody(req *http.Request) ([]fieldError, error) {
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		return nil, bodyError(err, err.Error())
+	}
+
+	req.Body = io.NopCloser(bytes.NewReader(body))
+
+	if len(bytes.TrimSpace(body)) == 0 {
+		if op.RequestBody.Required {
+			return []fieldError{{Field: "body", Rule: "required", Message: "body is required"}}, nil
+		}
+
+		return nil, nil
+	}
+
+	contentType := req.Header.Get(echo.HeaderContentType)
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+
+	content, ok := op.RequestBody.Content[mediaType]
+	if !ok {
+		return nil, fmt.Errorf("unsupported content type %q:%w", contentType, ErrDecode)
+	}
+
+	// the other content types, e.g. NDJSON, are read by the handler.
+	if mediaType != echo.MIMEApplicationJSON || content.ValidatedByHandler {
+		return nil, nil
+	}
+
+	value, err := decodeJSON(body)
+	if err != nil {
+		return nil, fmt.Errorf("%s:%w", err.Error(), ErrDecode)
+	}
+
+	return content.Schema.validate("", value), nil
+}
+
+// validateResponse checks the status, the content type and the body of a
+// response, it returns an ErrResponseMismatch with the differences.
+func (op *operation) validateResponse(status int, contentType string, body []byte) error {
+	res, ok := op.Responses[strconv.Itoa(status)]
+	if !ok {
+		res, ok = op.Responses["default"]
+	}
+
+	if !ok {
+		return fmt.Errorf("undocumented status %d:%w", status, ErrResponseMismatch)
+	}
+
+	if len(res.Content) == 0 {
+		if len(body) > 0 {
+			return fmt.Errorf("undocumented body:%w", ErrResponseMismatch)
+		}
+
+		return nil
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+
+	content, ok := res.Content[mediaType]
+	if !ok {
+		return fmt.Errorf("undocumented content type %q:%w", contentType, ErrResponseMismatch)
+	}
+
+	value, err := decodeJSON(body)
+	if err != nil {
+		return fmt.Errorf("%s:%w", err.Error(), ErrResponseMismatch)
+	}
+
+	if errs := content.Schema.validate("", value); len(errs) > 0 {
+		return fmt.Errorf("%s:%w", (&requestError{fields: errs}).Error(), ErrResponseMismatch)
+	}
+
+	return nil
+}
+
+// decodeJSON decodes a single JSON value keeping the numbers as written.
+func decodeJSON(body []byte) (interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+
+	var value interface{}
+
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+
+	if decoder.More() {
+		return nil, errors.New("unexpected data after the JSON value")
+	}
+
+	return value, nil
+}
+
+// WithSpecValidation checks the requests of the routes described by the
+// OpenAPI spec before they reach the handlers, the invalid ones are answered
+// with a 400 problem. Responses that do not match the spec are logged as
+// errors.
+func WithSpecValidation() Option {
+	return func(s *Server) {
+		s.validateSpec = true
+	}
+}
+
+func (s *Server) enforceSpec(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(eCtx echo.Context) error {
+		op, ok := apiSpec.operation(eCtx.Request().Method, eCtx.Path())
+		if !ok {
+			return next(eCtx)
+		}
+
+		if err := op.validateRequest(eCtx); err != nil {
+			return apiReceiptResponseError(eCtx, err)
+		}
+
+		res := eCtx.Response()
+		recorder := &bodyRecorder{ResponseWriter: res.Writer}
+		res.Writer = recorder
+
+		defer func() {
+			res.Writer = recorder.ResponseWriter
+		}()
+
+		if err := next(eCtx); err != nil {
+			eCtx.Error(err)
+		}
+
+		err := op.validateResponse(res.Status, res.Header().Get(echo.HeaderContentType), recorder.body.Bytes())
+		if err != nil {
+			ctx := eCtx.Request().Context()
+			s.logger.LogAttrs(ctx, slog.LevelError, "response does not match the openapi spec",
+				slog.String("request_id", RequestID(ctx)),
+				slog.String("method", eCtx.Request().Method),
+				slog.String("route", eCtx.Path()),
+				slog.Int("status", res.Status),
+				slog.String("error", err.Error()),
+			)
+		}
+
+		return nil
+	}
+}
+
+// bodyRecorder keeps a copy of the response body for its check.
+type bodyRecorder struct {
+	http.ResponseWriter
+	body bytes.Buffer
+}
+
+func (r *bodyRecorder) Write(b []byte) (int, error) {
+	r.body.Write(b)
+
+	return r.ResponseWriter.Write(b)
+}
+
+func (s *Server) getSpec(eCtx echo.Context) error {
+	return eCtx.Blob(http.StatusOK, echo.MIMEApplicationJSON, openAPIDocument)
+}
+
+func (s *Server) getDocs(eCtx echo.Context) error {
+	return eCtx.HTML(http.StatusOK, docsPage)
+}
@@ -0,0 +1,476 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"receipt-processor-challenge/internal/app/receipt/calculator"
+	rcp "receipt-processor-challenge/internal/domain/receipt"
+	"receipt-processor-challenge/internal/interfaceadapters/storage/memory"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+const specReceipt string = `{"retailer":"Target","purchaseDate":"2022-01-01","purchaseTime":"13:01",` +
+	`"items":[{"shortDescription":"Mountain Dew 12PK","price":"6.49"}],"total":"6.49"}`
+
+// Test_SpecRoutes fails when a receipt route is added to the server or to the
+// spec without the other.
+func Test_SpecRoutes(t *testing.T) {
+	s := NewServer(context.Background(), &receiptAPIMock{})
+	s.routes()
+
+	served := map[string]bool{}
+
+	for _, route := range s.router.Routes() {
+		if strings.HasPrefix(route.Path, "/receipt") {
+			served[strings.ToLower(route.Method)+" "+specRoute(route.Path)] = true
+		}
+	}
+
+	described := map[string]bool{}
+
+	for path, methods := range apiSpec.Paths {
+		for method := range methods {
+			described[method+" "+path] = true
+		}
+	}
+
+	assert.Equal(t, described, served)
+}
+
+// Test_SpecResponses fails when a handler answers a status or a body the spec
+// does not describe.
+func Test_SpecResponses(t *testing.T) {
+	id := uuid.MustParse("0a25c541-2ab9-41d9-bedb-2d518df5dc43")
+	of := uuid.MustParse("7fb1377b-b223-49d9-a31a-5a02701dd310")
+	price, _ := rcp.ParseMoney("6.49")
+
+	pts := &rcp.Points{
+		Points:         15,
+		RuleSetVersion: "v1",
+		Breakdown: []rcp.Award{
+			{Rule: "retailer-name", Description: "retailer", Points: 6},
+			{
+				Rule:        "item-description",
+				Description: "item",
+				Points:      9,
+				Item:        &rcp.AwardedItem{Index: 0, Item: rcp.Item{ShortDescription: "Mountain Dew 12PK", Price: price}},
+			},
+		},
+		Duplicate: &rcp.Duplicate{Of: of, Similarity: 0.9, Policy: "flag"},
+	}
+
+	cases := []struct {
+		name           string
+		method         string
+		target         string
+		body           string
+		route          string
+		apiBuilder     func(apiMock *receiptAPIMock)
+		expectedStatus int
+	}{
+		{
+			name:   "process-created-case",
+			method: echo.POST, target: "/receipt/process", body: specReceipt, route: "/receipt/process",
+			apiBuilder: func(apiMock *receiptAPIMock) {
+				apiMock.On("SavePoints", mock.Anything, mock.Anything, "").Return(id, true, nil)
+			},
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			name:   "process-invalid-case",
+			method: echo.POST, target: "/receipt/process", body: `{"retailer":"Target"}`, route: "/receipt/process",
+			apiBuilder:     func(apiMock *receiptAPIMock) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:   "process-duplicate-case",
+			method: echo.POST, target: "/receipt/process", body: specReceipt, route: "/receipt/process",
+			apiBuilder: func(apiMock *receiptAPIMock) {
+				apiMock.On("SavePoints", mock.Anything, mock.Anything, "").Return(nil, false, rcp.ErrSuspectedDuplicate)
+			},
+			expectedStatus: http.StatusConflict,
+		},
+		{
+			name:   "process-error-case",
+			method: echo.POST, target: "/receipt/process", body: specReceipt, route: "/receipt/process",
+			apiBuilder: func(apiMock *receiptAPIMock) {
+				apiMock.On("SavePoints", mock.Anything, mock.Anything, "").Return(nil, false, errors.New("time out"))
+			},
+			expectedStatus: http.StatusInternalServerError,
+		},
+		{
+			name:   "batch-case",
+			method: echo.POST, target: "/receipt/process/batch", route: "/receipt/process/batch",
+			body: "[" + specReceipt + `,{"retailer":"Target"},` + specReceipt + "]",
+			apiBuilder: func(apiMock *receiptAPIMock) {
+				apiMock.On("SavePoints", mock.Anything, mock.Anything, "").Return(id, true, nil).Once()
+				apiMock.On("SavePoints", mock.Anything, mock.Anything, "").Return(nil, false, rcp.ErrSuspectedDuplicate)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:   "batch-malformed-case",
+			method: echo.POST, target: "/receipt/process/batch", body: specReceipt, route: "/receipt/process/batch",
+			apiBuilder:     func(apiMock *receiptAPIMock) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:   "points-case",
+			method: echo.GET, target: "/receipt/" + id.String() + "/points", route: "/receipt/:id/points",
+			apiBuilder: func(apiMock *receiptAPIMock) {
+				apiMock.On("GetPoints", mock.Anything, id).Return(pts, nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:   "points-not-found-case",
+			method: echo.GET, target: "/receipt/" + id.String() + "/points", route: "/receipt/:id/points",
+			apiBuilder: func(apiMock *receiptAPIMock) {
+				apiMock.On("GetPoints", mock.Anything, id).Return(nil, nil)
+			},
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:   "points-evicted-case",
+			method: echo.GET, target: "/receipt/" + id.String() + "/points", route: "/receipt/:id/points",
+			apiBuilder: func(apiMock *receiptAPIMock) {
+				apiMock.On("GetPoints", mock.Anything, id).Return(nil, memory.ErrEvicted)
+			},
+			expectedStatus: http.StatusGone,
+		},
+		{
+			name:   "points-invalid-id-case",
+			method: echo.GET, target: "/receipt/123/points", route: "/receipt/:id/points",
+			apiBuilder:     func(apiMock *receiptAPIMock) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:   "breakdown-case",
+			method: echo.GET, target: "/receipt/" + id.String() + "/points/breakdown", route: "/receipt/:id/points/breakdown",
+			apiBuilder: func(apiMock *receiptAPIMock) {
+				apiMock.On("GetPoints", mock.Anything, id).Return(pts, nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:   "rescore-case",
+			method: echo.GET, target: "/receipt/" + id.String() + "/points/rescore?version=v1", route: "/receipt/:id/points/rescore",
+			apiBuilder: func(apiMock *receiptAPIMock) {
+				apiMock.On("RescorePoints", mock.Anything, id, "v1").Return(pts, nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:   "rescore-unknown-version-case",
+			method: echo.GET, target: "/receipt/" + id.String() + "/points/rescore?version=v9", route: "/receipt/:id/points/rescore",
+			apiBuilder: func(apiMock *receiptAPIMock) {
+				apiMock.On("RescorePoints", mock.Anything, id, "v9").Return(nil, calculator.ErrUnknownRuleSet)
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:   "flagged-case",
+			method: echo.GET, target: "/receipt/flagged", route: "/receipt/flagged",
+			apiBuilder: func(apiMock *receiptAPIMock) {
+				apiMock.On("GetFlagged", mock.Anything).Return([]rcp.FlaggedReceipt{{ID: id, Points: *pts}}, nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:   "receipt-case",
+			method: echo.GET, target: "/receipt/" + id.String(), route: "/receipt/:id",
+			apiBuilder: func(apiMock *receiptAPIMock) {
+				apiMock.On("GetReceipt", mock.Anything, id).Return(&rcp.Receipt{
+					Retailer:     "Target",
+					PurchaseDate: purchaseDate(t, "2022-01-01"),
+					PurchaseTime: purchaseTime(t, "13:01"),
+					Items:        []rcp.Item{{ShortDescription: "Mountain Dew 12PK", Price: price}},
+					Total:        price,
+				}, nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:   "receipt-not-found-case",
+			method: echo.GET, target: "/receipt/" + id.String(), route: "/receipt/:id",
+			apiBuilder: func(apiMock *receiptAPIMock) {
+				apiMock.On("GetReceipt", mock.Anything, id).Return(nil, memory.ErrNotFound)
+			},
+			expectedStatus: http.StatusNotFound,
+		},
+	}
+
+	exercised := map[string]bool{}
+
+	for _, c := range cases {
+		c := c
+
+		t.Run(c.name, func(t *testing.T) {
+			apiMock := &receiptAPIMock{}
+			c.apiBuilder(apiMock)
+
+			s := NewServer(context.Background(), apiMock)
+			s.routes()
+
+			req := httptest.NewRequest(c.method, c.target, strings.NewReader(c.body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+
+			s.router.ServeHTTP(rec, req)
+
+			assert.Equal(t, c.expectedStatus, rec.Code)
+
+			op, ok := apiSpec.operation(c.method, c.route)
+			assert.True(t, ok)
+
+			if ok {
+				assert.NoError(t, op.validateResponse(rec.Code, rec.Header().Get(echo.HeaderContentType), rec.Body.Bytes()))
+			}
+		})
+
+		exercised[strings.ToLower(c.method)+" "+specRoute(c.route)] = true
+	}
+
+	for path, methods := range apiSpec.Paths {
+		for method := range methods {
+			assert.True(t, exercised[method+" "+path], "%s %s is not exercised", method, path)
+		}
+	}
+}
+
+func Test_ValidateResponse(t *testing.T) {
+	op, _ := apiSpec.operation(echo.GET, "/receipt/:id/points")
+
+	cases := []struct {
+		name        string
+		status      int
+		contentType string
+		body        string
+		expectedErr bool
+	}{
+		{name: "valid-case", status: http.StatusOK, contentType: echo.MIMEApplicationJSONCharsetUTF8, body: `{"points":28}`},
+		{name: "problem-case", status: http.StatusNotFound, contentType: mimeProblemJSON,
+			body: `{"type":"about:blank","title":"Not Found","status":404,"detail":"points not found"}`},
+		{name: "undocumented-status-case", status: http.StatusConflict, contentType: mimeProblemJSON,
+			body: `{"type":"about:blank","title":"Conflict","status":409,"detail":"conflict"}`, expectedErr: true},
+		{name: "undocumented-content-type-case", status: http.StatusOK, contentType: echo.MIMETextPlain,
+			body: `{"points":28}`, expectedErr: true},
+		{name: "missing-field-case", status: http.StatusOK, contentType: echo.MIMEApplicationJSON, body: `{}`, expectedErr: true},
+		{name: "extra-field-case", status: http.StatusOK, contentType: echo.MIMEApplicationJSON,
+			body: `{"points":28,"total":"1.00"}`, expectedErr: true},
+		{name: "wrong-type-case", status: http.StatusOK, contentType: echo.MIMEApplicationJSON,
+			body: `{"points":28.5}`, expectedErr: true},
+		{name: "null-case", status: http.StatusNotFound, contentType: echo.MIMEApplicationJSON, body: `null`, expectedErr: true},
+	}
+
+	for _, c := range cases {
+		c := c
+
+		t.Run(c.name, func(t *testing.T) {
+			err := op.validateResponse(c.status, c.contentType, []byte(c.body))
+
+			if c.expectedErr {
+				assert.ErrorIs(t, err, ErrResponseMismatch)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func Test_EnforceSpec(t *testing.T) {
+	id := uuid.New()
+
+	cases := []struct {
+		name             string
+		method           string
+		target           string
+		contentType      string
+		body             string
+		expectedHTTPCode int
+		expectedErrors   []fieldError
+		expectedDetail   string
+	}{
+		{
+			name:   "valid-case",
+			method: echo.POST, target: "/receipt/process", contentType: echo.MIMEApplicationJSON, body: specReceipt,
+			expectedHTTPCode: http.StatusCreated,
+		},
+		{
+			name:   "missing-field-case",
+			method: echo.POST, target: "/receipt/process", contentType: echo.MIMEApplicationJSON,
+			body:             `{"retailer":"Target","purchaseDate":"2022-01-01","purchaseTime":"13:01","items":[{"price":"6.49"}]}`,
+			expectedHTTPCode: http.StatusBadRequest,
+			expectedErrors: []fieldError{
+				{Field: "total", Rule: "required", Message: "total is required"},
+				{Field: "items[0].shortDescription", Rule: "required", Message: "items[0].shortDescription is required"},
+			},
+		},
+		{
+			name:   "invalid-fields-case",
+			method: echo.POST, target: "/receipt/process", contentType: echo.MIMEApplicationJSON,
+			body: `{"retailer":"","purchaseDate":"2022-13-01","purchaseTime":"25:00",` +
+				`"items":[],"total":"6.499","coupon":"X"}`,
+			expectedHTTPCode: http.StatusBadRequest,
+			expectedErrors: []fieldError{
+				{Field: "coupon", Rule: "additionalProperties", Message: "coupon is not allowed"},
+				{Field: "items", Rule: "minItems", Message: "items must have at least 1 items"},
+				{Field: "purchaseDate", Rule: "date", Message: "purchaseDate must use the 2006-01-02 format"},
+				{Field: "purchaseTime", Rule: "pattern", Message: "purchaseTime must match ^([01][0-9]|2[0-3]):[0-5][0-9]$"},
+				{Field: "retailer", Rule: "required", Message: "retailer is required"},
+				{Field: "total", Rule: "money", Message: "total must be an amount with up to two decimals"},
+			},
+		},
+		{
+			name:   "wrong-type-case",
+			method: echo.POST, target: "/receipt/process", contentType: echo.MIMEApplicationJSON, body: `[]`,
+			expectedHTTPCode: http.StatusBadRequest,
+			expectedErrors:   []fieldError{{Field: "body", Rule: "type", Message: "body must be of type object"}},
+		},
+		{
+			name:   "missing-body-case",
+			method: echo.POST, target: "/receipt/process", contentType: echo.MIMEApplicationJSON,
+			expectedHTTPCode: http.StatusBadRequest,
+			expectedErrors:   []fieldError{{Field: "body", Rule: "required", Message: "body is required"}},
+		},
+		{
+			name:   "malformed-body-case",
+			method: echo.POST, target: "/receipt/process", contentType: echo.MIMEApplicationJSON, body: `{"retailer":`,
+			expectedHTTPCode: http.StatusBadRequest,
+			expectedDetail:   "unexpected EOF",
+		},
+		{
+			name:   "unsupported-content-type-case",
+			method: echo.POST, target: "/receipt/process", contentType: echo.MIMETextPlain, body: specReceipt,
+			expectedHTTPCode: http.StatusBadRequest,
+			expectedDetail:   `unsupported content type "text/plain"`,
+		},
+		{
+			name:   "invalid-id-case",
+			method: echo.GET, target: "/receipt/123/points",
+			expectedHTTPCode: http.StatusBadRequest,
+			expectedErrors:   []fieldError{{Field: "id", Rule: "uuid", Message: "id must be a uuid"}},
+		},
+		{
+			name:   "valid-id-case",
+			method: echo.GET, target: "/receipt/" + id.String() + "/points",
+			expectedHTTPCode: http.StatusOK,
+		},
+	}
+
+	for _, c := range cases {
+		c := c
+
+		t.Run(c.name, func(t *testing.T) {
+			apiMock := &receiptAPIMock{}
+			apiMock.On("SavePoints", mock.Anything, mock.Anything, "").Return(id, true, nil)
+			apiMock.On("GetPoints", mock.Anything, id).Return(&rcp.Points{Points: 28}, nil)
+
+			out := bytes.Buffer{}
+			logger := slog.New(slog.NewJSONHandler(&out, nil))
+
+			s := NewServer(context.Background(), apiMock, WithSpecValidation(), WithLogger(logger))
+			s.routes()
+
+			req := httptest.NewRequest(c.method, c.target, strings.NewReader(c.body))
+			req.Header.Set(echo.HeaderContentType, c.contentType)
+			rec := httptest.NewRecorder()
+
+			s.router.ServeHTTP(rec, req)
+
+			assert.Equal(t, c.expectedHTTPCode, rec.Code)
+			assert.NotContains(t, out.String(), "response does not match the openapi spec")
+
+			if c.expectedHTTPCode < http.StatusBadRequest {
+				return
+			}
+
+			apiMock.AssertNotCalled(t, "SavePoints", mock.Anything, mock.Anything, mock.Anything)
+
+			got := problem{}
+			assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &got))
+			assert.Equal(t, c.expectedErrors, got.Errors)
+
+			if c.expectedDetail != "" {
+				assert.Equal(t, c.expectedDetail, got.Detail)
+			}
+		})
+	}
+}
+
+func Test_EnforceSpecLogsMismatch(t *testing.T) {
+	id := uuid.New()
+
+	out := bytes.Buffer{}
+	logger := slog.New(slog.NewJSONHandler(&out, nil))
+
+	// a handler answering the id under another name.
+	s := NewServer(context.Background(), &receiptAPIMock{}, WithLogger(logger))
+	s.router.Use(s.enforceSpec)
+	s.router.POST("/receipt/process", func(eCtx echo.Context) error {
+		return eCtx.JSON(http.StatusCreated, map[string]string{"uuid": id.String()})
+	})
+
+	req := httptest.NewRequest(echo.POST, "/receipt/process", strings.NewReader(specReceipt))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+
+	s.router.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.Contains(t, out.String(), `"msg":"response does not match the openapi spec"`)
+	assert.Contains(t, out.String(), "id is required, uuid is not allowed")
+}
+
+func Test_GetSpec(t *testing.T) {
+	s := NewServer(context.Background(), &receiptAPIMock{})
+	s.routes()
+
+	rec := httptest.NewRecorder()
+	s.router.ServeHTTP(rec, httptest.NewRequest(echo.GET, "/openapi.json", nil))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, echo.MIMEApplicationJSON, rec.Header().Get(echo.HeaderContentType))
+	assert.Equal(t, openAPIDocument, rec.Body.Bytes())
+
+	rec = httptest.NewRecorder()
+	s.router.ServeHTTP(rec, httptest.NewRequest(echo.GET, "/docs", nil))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `url: "/openapi.json"`)
+}
+
+func Test_LoadSpec(t *testing.T) {
+	cases := []struct {
+		name     string
+		document string
+	}{
+		{name: "malformed-case", document: `{"paths":`},
+		{name: "unknown-schema-case", document: `{"paths":{"/r":{"get":{"responses":{"200":{"content":` +
+			`{"application/json":{"schema":{"$ref":"#/components/schemas/Missing"}}}}}}}}}`},
+		{name: "unknown-parameter-case", document: `{"paths":{"/r":{"get":{"parameters":` +
+			`[{"$ref":"#/components/parameters/Missing"}]}}}}`},
+		{name: "invalid-pattern-case", document: `{"components":{"schemas":{"S":{"type":"string","pattern":"("}}}}`},
+	}
+
+	for _, c := range cases {
+		c := c
+
+		t.Run(c.name, func(t *testing.T) {
+			_, err := loadSpec([]byte(c.document))
+			assert.ErrorIs(t, err, ErrInvalidSpec)
+		})
+	}
+}
@@ -4,35 +4,37 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
-	"strconv"
 	"strings"
 	"time"
 
+	"receipt-processor-challenge/internal/app/receipt/calculator"
 	rcp "receipt-processor-challenge/internal/domain/receipt"
-	"receipt-processor-challenge/internal/interfaceadapters/storage/memory"
+	"receipt-processor-challenge/internal/tracing"
 
-	"github.com/go-playground/validator/v10"
 	"github.com/google/uuid"
 	"github.com/labstack/echo/v4"
 )
 
+const mimeProblemJSON string = "application/problem+json"
+
 var (
 	ErrInvalidRequest = errors.New("invalid request")
 	ErrValidator      = errors.New("validator error")
 	ErrDecode         = errors.New("decode error")
+	ErrBodyTooLarge   = errors.New("body too large")
 )
 
 type receipt struct {
 	Retailer     string `json:"retailer"     validate:"required"`
 	PurchaseDate string `json:"purchaseDate" validate:"required,datetime=2006-01-02"`
 	PurchaseTime string `json:"purchaseTime" validate:"required,datetime=15:04"`
-	Items        []item `json:"items"        validate:"required"`
-	Total        string `json:"total"        validate:"required"`
+	Items        []item `json:"items"        validate:"required,dive"`
+	Total        string `json:"total"        validate:"required,money"`
 }
 
 type item struct {
 	ShortDescription string `json:"shortDescription" validate:"required"`
-	Price            string `json:"price"            validate:"required"`
+	Price            string `json:"price"            validate:"required,money"`
 }
 
 type points struct {
@@ -43,10 +45,50 @@ type id struct {
 	ID string `json:"id" validate:"required"`
 }
 
+// savedID is the response of a submission, created is false when the receipt
+// was already stored.
+type savedID struct {
+	id
+	created bool
+}
+
+type breakdown struct {
+	Points         int        `json:"points"`
+	RuleSetVersion string     `json:"ruleSetVersion"`
+	Breakdown      []award    `json:"breakdown"`
+	Duplicate      *duplicate `json:"duplicate,omitempty"`
+}
+
+type duplicate struct {
+	Of         string  `json:"of"`
+	Similarity float64 `json:"similarity"`
+	Policy     string  `json:"policy"`
+}
+
+type flaggedReceipt struct {
+	ID string `json:"id"`
+	breakdown
+}
+
+type flaggedReceipts struct {
+	Receipts []flaggedReceipt `json:"receipts"`
+}
+
+type award struct {
+	Rule        string       `json:"rule"`
+	Description string       `json:"description"`
+	Points      int          `json:"points"`
+	Item        *awardedItem `json:"item,omitempty"`
+}
+
+type awardedItem struct {
+	Index int `json:"index"`
+	item
+}
+
 func (s *Server) saveReceiptPoints(eCtx echo.Context) (err error) {
 	ctx := eCtx.Request().Context()
-	rcpt := new(receipt)
-	newID := new(id)
+	newID := new(savedID)
 
 	defer func() {
 		if err != nil {
@@ -56,26 +98,50 @@ func (s *Server) saveReceiptPoints(eCtx echo.Context) (err error) {
 		}
 	}()
 
-	bErr := eCtx.Bind(rcpt)
-	if bErr != nil {
-		return fmt.Errorf("%s:%w", bErr.Error(), ErrDecode)
-	}
+	_, bind := tracing.Start(ctx, "http.Bind")
+	receipt, err := bindReceipt(eCtx)
+	bind.RecordError(err)
+	bind.End()
 
-	receipt, err := rcpt.toReceiptDomain()
 	if err != nil {
 		return err
 	}
 
-	uuid, err := s.receiptApp.SavePoints(ctx, *receipt)
+	idempotencyKey := eCtx.Request().Header.Get(headerIdempotencyKey)
+
+	uuid, created, err := s.receiptApp.SavePoints(ctx, *receipt, idempotencyKey)
 	if err != nil {
 		return err
 	}
 
-	*newID = id{ID: uuid.String()}
+	*newID = savedID{id: id{ID: uuid.String()}, created: created}
+	eCtx.Set(receiptIDKey, newID.ID)
 
 	return nil
 }
 
+// bindReceipt decodes and converts the receipt of the request body.
+func bindReceipt(eCtx echo.Context) (*rcp.Receipt, error) {
+	rcpt := new(receipt)
+
+	if err := eCtx.Bind(rcpt); err != nil {
+		return nil, bodyError(err, err.Error())
+	}
+
+	return rcpt.toReceiptDomain()
+}
+
+// bodyError describes a failed read of the request body with detail, or as a
+// body over the limit of the server.
+func bodyError(err error, detail string) error {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		return fmt.Errorf("body can not be larger than %d bytes:%w", tooLarge.Limit, ErrBodyTooLarge)
+	}
+
+	return fmt.Errorf("%s:%w", detail, ErrDecode)
+}
+
 func (s *Server) getReceiptPoints(eCtx echo.Context) (err error) {
 	ctx := eCtx.Request().Context()
 	paramID := eCtx.Param("id")
@@ -89,14 +155,41 @@ func (s *Server) getReceiptPoints(eCtx echo.Context) (err error) {
 		}
 	}()
 
-	err = validate(id{ID: paramID})
+	paramUUID, err := receiptID(paramID)
 	if err != nil {
 		return err
 	}
 
-	paramUUID, err := uuid.Parse(paramID)
+	pts, err := s.receiptApp.GetPoints(ctx, paramUUID)
+	if err != nil {
+		return fmt.Errorf("storage error:%w", err)
+	}
+
+	if pts == nil {
+		response = nil
+	} else {
+		*response = points{Points: pts.Points}
+	}
+
+	return nil
+}
+
+func (s *Server) getReceiptPointsBreakdown(eCtx echo.Context) (err error) {
+	ctx := eCtx.Request().Context()
+	paramID := eCtx.Param("id")
+	response := new(breakdown)
+
+	defer func() {
+		if err != nil {
+			err = apiReceiptResponse(eCtx, err)
+		} else {
+			err = apiReceiptResponse(eCtx, response)
+		}
+	}()
+
+	paramUUID, err := receiptID(paramID)
 	if err != nil {
-		return fmt.Errorf("%s:%w", err.Error(), ErrDecode)
+		return err
 	}
 
 	pts, err := s.receiptApp.GetPoints(ctx, paramUUID)
@@ -107,44 +200,124 @@ func (s *Server) getReceiptPoints(eCtx echo.Context) (err error) {
 	if pts == nil {
 		response = nil
 	} else {
-		*response = points{Points: pts.Points}
+		*response = fromPointsDomain(*pts)
 	}
 
 	return nil
 }
 
-func validate(e interface{}) error {
-	validate := validator.New()
+func (s *Server) rescoreReceiptPoints(eCtx echo.Context) (err error) {
+	ctx := eCtx.Request().Context()
+	paramID := eCtx.Param("id")
+	version := eCtx.QueryParam("version")
+	response := new(breakdown)
+
+	defer func() {
+		if err != nil {
+			err = apiReceiptResponse(eCtx, err)
+		} else {
+			err = apiReceiptResponse(eCtx, response)
+		}
+	}()
+
+	paramUUID, err := receiptID(paramID)
+	if err != nil {
+		return err
+	}
 
-	err := validate.Struct(e)
+	pts, err := s.receiptApp.RescorePoints(ctx, paramUUID, version)
 	if err != nil {
-		if _, ok := err.(*validator.InvalidValidationError); ok {
-			return ErrValidator
+		if errors.Is(err, calculator.ErrUnknownRuleSet) {
+			return fmt.Errorf("rule set version %s is unknown:%w", version, ErrInvalidRequest)
 		}
 
-		var vErr error
+		return fmt.Errorf("storage error:%w", err)
+	}
+
+	if pts == nil {
+		response = nil
+	} else {
+		*response = fromPointsDomain(*pts)
+	}
+
+	return nil
+}
 
-		for _, err := range err.(validator.ValidationErrors) { //nolint:forcetypeassert
-			field := err.StructField()
+func (s *Server) getReceipt(eCtx echo.Context) (err error) {
+	ctx := eCtx.Request().Context()
+	paramID := eCtx.Param("id")
+	response := new(receipt)
 
-			switch err.Tag() {
-			case "required":
-				vErr = fmt.Errorf("%s is required:%w", field, ErrInvalidRequest)
-			case "datetime":
-				vErr = fmt.Errorf("%s date/time format:%w", field, ErrInvalidRequest)
-			case "number":
-				vErr = fmt.Errorf("%s is not numeric:%w", field, ErrInvalidRequest)
-			default:
-				vErr = fmt.Errorf("%s validation error:%w", field, ErrInvalidRequest)
-			}
+	defer func() {
+		if err != nil {
+			err = apiReceiptResponse(eCtx, err)
+		} else {
+			err = apiReceiptResponse(eCtx, response)
+		}
+	}()
+
+	paramUUID, err := receiptID(paramID)
+	if err != nil {
+		return err
+	}
+
+	rcpt, err := s.receiptApp.GetReceipt(ctx, paramUUID)
+	if err != nil {
+		return fmt.Errorf("storage error:%w", err)
+	}
+
+	if rcpt == nil {
+		response = nil
+	} else {
+		*response = fromReceiptDomain(*rcpt)
+	}
+
+	return nil
+}
+
+func (s *Server) getFlaggedReceipts(eCtx echo.Context) (err error) {
+	ctx := eCtx.Request().Context()
+	response := new(flaggedReceipts)
+
+	defer func() {
+		if err != nil {
+			err = apiReceiptResponse(eCtx, err)
+		} else {
+			err = apiReceiptResponse(eCtx, response)
 		}
+	}()
 
-		return vErr
+	flagged, err := s.receiptApp.GetFlagged(ctx)
+	if err != nil {
+		return fmt.Errorf("storage error:%w", err)
+	}
+
+	response.Receipts = make([]flaggedReceipt, len(flagged))
+
+	for index, f := range flagged {
+		response.Receipts[index] = flaggedReceipt{
+			ID:        f.ID.String(),
+			breakdown: fromPointsDomain(f.Points),
+		}
 	}
 
 	return nil
 }
 
+func receiptID(paramID string) (uuid.UUID, error) {
+	err := validate(id{ID: paramID})
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	paramUUID, err := uuid.Parse(paramID)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("%s:%w", err.Error(), ErrDecode)
+	}
+
+	return paramUUID, nil
+}
+
 func (r receipt) toReceiptDomain() (*rcp.Receipt, error) {
 	err := validate(r)
 	if err != nil {
@@ -162,9 +335,9 @@ func (r receipt) toReceiptDomain() (*rcp.Receipt, error) {
 		items[index] = *newItem
 	}
 
-	total, err := strconv.ParseFloat(r.Total, 64)
+	total, err := rcp.ParseMoney(r.Total)
 	if err != nil {
-		return nil, fmt.Errorf("%s format error:%w", "Total", ErrInvalidRequest)
+		return nil, fmt.Errorf("total format error:%w", ErrInvalidRequest)
 	}
 
 	purchaseDate, _ := time.Parse(rcp.DatePurchaseFormat, r.PurchaseDate)
@@ -180,14 +353,9 @@ func (r receipt) toReceiptDomain() (*rcp.Receipt, error) {
 }
 
 func (i item) toItemDomain() (*rcp.Item, error) {
-	err := validate(i)
-	if err != nil {
-		return nil, err
-	}
-
-	price, err := strconv.ParseFloat(i.Price, 64)
+	price, err := rcp.ParseMoney(i.Price)
 	if err != nil {
-		return nil, fmt.Errorf("%s format error:%w", "Price", ErrInvalidRequest)
+		return nil, fmt.Errorf("price format error:%w", ErrInvalidRequest)
 	}
 
 	return &rcp.Item{
@@ -196,8 +364,71 @@ func (i item) toItemDomain() (*rcp.Item, error) {
 	}, nil
 }
 
-type responseErrorMsg struct {
-	Msg string `json:"error"`
+func fromPointsDomain(p rcp.Points) breakdown {
+	awards := make([]award, len(p.Breakdown))
+
+	for index, a := range p.Breakdown {
+		awards[index] = award{
+			Rule:        a.Rule,
+			Description: a.Description,
+			Points:      a.Points,
+		}
+
+		if a.Item != nil {
+			awards[index].Item = &awardedItem{
+				Index: a.Item.Index,
+				item:  fromItemDomain(a.Item.Item),
+			}
+		}
+	}
+
+	response := breakdown{
+		Points:         p.Points,
+		RuleSetVersion: p.RuleSetVersion,
+		Breakdown:      awards,
+	}
+
+	if p.Duplicate != nil {
+		response.Duplicate = &duplicate{
+			Of:         p.Duplicate.Of.String(),
+			Similarity: p.Duplicate.Similarity,
+			Policy:     p.Duplicate.Policy,
+		}
+	}
+
+	return response
+}
+
+func fromItemDomain(i rcp.Item) item {
+	return item{
+		ShortDescription: i.ShortDescription,
+		Price:            i.Price.String(),
+	}
+}
+
+func fromReceiptDomain(r rcp.Receipt) receipt {
+	items := make([]item, len(r.Items))
+
+	for index, i := range r.Items {
+		items[index] = fromItemDomain(i)
+	}
+
+	return receipt{
+		Retailer:     r.Retailer,
+		PurchaseDate: r.PurchaseDate.Format(rcp.DatePurchaseFormat),
+		PurchaseTime: r.PurchaseTime.Format(rcp.TimePurchaseFormat),
+		Items:        items,
+		Total:        r.Total.String(),
+	}
+}
+
+// problem is an RFC 7807 problem details response.
+type problem struct {
+	Type   string       `json:"type"`
+	Title  string       `json:"title"`
+	Status int          `json:"status"`
+	Detail string       `json:"detail"`
+	Errors []fieldError `json:"errors,omitempty"`
 }
 
 func apiReceiptResponse(eCtx echo.Context, r interface{}) error {
@@ -205,38 +436,131 @@ func apiReceiptResponse(eCtx echo.Context, r interface{}) error {
 	case error:
 		return apiReceiptResponseError(eCtx, value)
 
-	case *id:
-		return eCtx.JSON(http.StatusOK, *value)
+	case *savedID:
+		if value.created {
+			return eCtx.JSON(http.StatusCreated, value.id)
+		}
+
+		return eCtx.JSON(http.StatusOK, value.id)
 
 	case *points:
 		if value == nil {
-			return eCtx.JSON(http.StatusNotFound, nil)
+			return apiReceiptResponseError(eCtx, rcp.ErrNotFound)
+		}
+
+		return eCtx.JSON(http.StatusOK, *value)
+
+	case *breakdown:
+		if value == nil {
+			return apiReceiptResponseError(eCtx, rcp.ErrNotFound)
+		}
+
+		return eCtx.JSON(http.StatusOK, *value)
+
+	case *receipt:
+		if value == nil {
+			return apiReceiptResponseError(eCtx, rcp.ErrNotFound)
 		}
 
 		return eCtx.JSON(http.StatusOK, *value)
+
+	case *flaggedReceipts:
+		return eCtx.JSON(http.StatusOK, *value)
+
+	case *batchResults:
+		return eCtx.JSON(http.StatusOK, *value)
 	}
 
 	return eCtx.JSON(http.StatusInternalServerError, nil)
 }
 
 func apiReceiptResponseError(eCtx echo.Context, err error) error {
-	code := http.StatusInternalServerError
-	jsonErr := responseErrorMsg{Msg: "unexpected error"}
+	// the request log reports the error with its cause.
+	eCtx.Set(errorKey, err)
 
-	if errors.Is(err, ErrInvalidRequest) {
-		jsonErr.Msg, _ = strings.CutSuffix(err.Error(), fmt.Sprintf(":%s", ErrInvalidRequest.Error()))
-		code = http.StatusBadRequest
-	}
+	jsonErr := newProblem(err)
+	eCtx.Response().Header().Set(echo.HeaderContentType, mimeProblemJSON)
 
-	if errors.Is(err, ErrDecode) {
-		jsonErr.Msg, _ = strings.CutSuffix(err.Error(), fmt.Sprintf(":%s", ErrDecode.Error()))
-		code = http.StatusBadRequest
+	return eCtx.JSON(jsonErr.Status, jsonErr)
+}
+
+// newProblem describes err with the status it is answered with.
+func newProblem(err error) problem {
+	jsonErr := problem{
+		Type:   "about:blank",
+		Status: http.StatusInternalServerError,
+		Detail: "unexpected error",
 	}
 
-	if errors.Is(err, memory.ErrNotFound) {
-		jsonErr.Msg, _ = strings.CutPrefix(err.Error(), "storage error:")
-		code = http.StatusNotFound
+	var (
+		reqErr  *requestError
+		rcptErr *rcp.ValidationError
+	)
+
+	switch {
+	case errors.As(err, &reqErr):
+		jsonErr.Status = http.StatusBadRequest
+		jsonErr.Detail = reqErr.Error()
+		jsonErr.Errors = reqErr.fields
+
+	case errors.As(err, &rcptErr):
+		jsonErr.Status = http.StatusBadRequest
+		jsonErr.Detail = rcptErr.Error()
+
+		for _, fe := range rcptErr.Errors {
+			jsonErr.Errors = append(jsonErr.Errors, fieldError{
+				Field:   fe.Field,
+				Rule:    fe.Rule,
+				Message: fe.Message,
+			})
+		}
+
+	case errors.Is(err, ErrBatchTooLarge):
+		jsonErr.Detail, _ = strings.CutSuffix(err.Error(), fmt.Sprintf(":%s", ErrBatchTooLarge.Error()))
+		jsonErr.Status = http.StatusRequestEntityTooLarge
+
+	case errors.Is(err, ErrBodyTooLarge):
+		jsonErr.Detail, _ = strings.CutSuffix(err.Error(), fmt.Sprintf(":%s", ErrBodyTooLarge.Error()))
+		jsonErr.Status = http.StatusRequestEntityTooLarge
+
+	case errors.Is(err, ErrInvalidRequest):
+		jsonErr.Detail, _ = strings.CutSuffix(err.Error(), fmt.Sprintf(":%s", ErrInvalidRequest.Error()))
+		jsonErr.Status = http.StatusBadRequest
+
+	case errors.Is(err, ErrDecode):
+		jsonErr.Detail, _ = strings.CutSuffix(err.Error(), fmt.Sprintf(":%s", ErrDecode.Error()))
+		jsonErr.Status = http.StatusBadRequest
+
+	case errors.Is(err, ErrUnauthorized):
+		jsonErr.Detail = err.Error()
+		jsonErr.Status = http.StatusUnauthorized
+
+	case errors.Is(err, ErrSnapshotRunning):
+		jsonErr.Detail = err.Error()
+		jsonErr.Status = http.StatusConflict
+
+	case errors.Is(err, rcp.ErrIdempotencyKeyReused):
+		jsonErr.Detail = err.Error()
+		jsonErr.Status = http.StatusUnprocessableEntity
+
+	case errors.Is(err, rcp.ErrSuspectedDuplicate):
+		// the message of the error is the id of the stored receipt.
+		jsonErr.Detail = rcp.ErrSuspectedDuplicate.Error()
+		if id, found := strings.CutSuffix(err.Error(), fmt.Sprintf(":%s", rcp.ErrSuspectedDuplicate.Error())); found {
+			jsonErr.Detail += " " + id
+		}
+		jsonErr.Status = http.StatusConflict
+
+	case errors.Is(err, rcp.ErrEvicted):
+		jsonErr.Detail, _ = strings.CutPrefix(err.Error(), "storage error:")
+		jsonErr.Status = http.StatusGone
+
+	case errors.Is(err, rcp.ErrNotFound):
+		jsonErr.Detail, _ = strings.CutPrefix(err.Error(), "storage error:")
+		jsonErr.Status = http.StatusNotFound
 	}
 
-	return eCtx.JSON(code, jsonErr)
+	jsonErr.Title = http.StatusText(jsonErr.Status)
+
+	return jsonErr
 }
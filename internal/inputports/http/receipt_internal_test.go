@@ -5,12 +5,15 @@ import (
 	"context"
 	"encoding/hex"
 	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 	"time"
 
+	"receipt-processor-challenge/internal/app/receipt/calculator"
 	rcp "receipt-processor-challenge/internal/domain/receipt"
+	"receipt-processor-challenge/internal/interfaceadapters/storage/memory"
 
 	"github.com/google/uuid"
 	"github.com/labstack/echo/v4"
@@ -22,14 +25,16 @@ type receiptAPIMock struct {
 	mock.Mock
 }
 
-func (rcpMock *receiptAPIMock) SavePoints(ctx context.Context, r rcp.Receipt) (uuid.UUID, error) {
-	args := rcpMock.Called(ctx, r)
+func (rcpMock *receiptAPIMock) SavePoints(
+	ctx context.Context, r rcp.Receipt, idempotencyKey string,
+) (uuid.UUID, bool, error) {
+	args := rcpMock.Called(ctx, r, idempotencyKey)
 
 	if id, ok := args.Get(0).(uuid.UUID); ok {
-		return id, args.Error(1)
+		return id, args.Bool(1), args.Error(2)
 	}
 
-	return uuid.Nil, args.Error(1)
+	return uuid.Nil, false, args.Error(2)
 }
 
 func (rcpMock *receiptAPIMock) GetPoints(ctx context.Context, id uuid.UUID) (*rcp.Points, error) {
@@ -42,6 +47,36 @@ func (rcpMock *receiptAPIMock) GetPoints(ctx context.Context, id uuid.UUID) (*rc
 	return nil, args.Error(1)
 }
 
+func (rcpMock *receiptAPIMock) GetReceipt(ctx context.Context, id uuid.UUID) (*rcp.Receipt, error) {
+	args := rcpMock.Called(ctx, id)
+
+	if rcpt, ok := args.Get(0).(*rcp.Receipt); ok {
+		return rcpt, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
+
+func (rcpMock *receiptAPIMock) RescorePoints(ctx context.Context, id uuid.UUID, version string) (*rcp.Points, error) {
+	args := rcpMock.Called(ctx, id, version)
+
+	if pnts, ok := args.Get(0).(*rcp.Points); ok {
+		return pnts, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
+
+func (rcpMock *receiptAPIMock) GetFlagged(ctx context.Context) ([]rcp.FlaggedReceipt, error) {
+	args := rcpMock.Called(ctx)
+
+	if flagged, ok := args.Get(0).([]rcp.FlaggedReceipt); ok {
+		return flagged, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
+
 func purchaseDate(t *testing.T, date string) time.Time {
 	tdate, err := time.Parse(rcp.DatePurchaseFormat, date)
 	if err != nil {
@@ -119,7 +154,8 @@ func Test_SaveReceiptPoints(t *testing.T) {
 			apiBuilder: func() *receiptAPIMock {
 				return &receiptAPIMock{}
 			},
-			expectedResponse: []byte(`{"error":"Retailer is required"}`),
+			expectedResponse: []byte(`{"type":"about:blank","title":"Bad Request","status":400,"detail":"retailer is required","errors":[` +
+				`{"field":"retailer","rule":"required","message":"retailer is required"}]}`),
 			expectedHTTPCode: http.StatusBadRequest,
 			expectedError:    nil,
 		},
@@ -162,7 +198,8 @@ func Test_SaveReceiptPoints(t *testing.T) {
 			apiBuilder: func() *receiptAPIMock {
 				return &receiptAPIMock{}
 			},
-			expectedResponse: []byte(`{"error":"PurchaseDate date/time format"}`),
+			expectedResponse: []byte(`{"type":"about:blank","title":"Bad Request","status":400,"detail":"purchaseDate must use the 2006-01-02 format","errors":[` +
+				`{"field":"purchaseDate","rule":"datetime","message":"purchaseDate must use the 2006-01-02 format"}]}`),
 			expectedHTTPCode: http.StatusBadRequest,
 			expectedError:    nil,
 		},
@@ -205,7 +242,8 @@ func Test_SaveReceiptPoints(t *testing.T) {
 			apiBuilder: func() *receiptAPIMock {
 				return &receiptAPIMock{}
 			},
-			expectedResponse: []byte(`{"error":"PurchaseTime date/time format"}`),
+			expectedResponse: []byte(`{"type":"about:blank","title":"Bad Request","status":400,"detail":"purchaseTime must use the 15:04 format","errors":[` +
+				`{"field":"purchaseTime","rule":"datetime","message":"purchaseTime must use the 15:04 format"}]}`),
 			expectedHTTPCode: http.StatusBadRequest,
 			expectedError:    nil,
 		},
@@ -230,7 +268,8 @@ func Test_SaveReceiptPoints(t *testing.T) {
 			apiBuilder: func() *receiptAPIMock {
 				return &receiptAPIMock{}
 			},
-			expectedResponse: []byte(`{"error":"Items is required"}`),
+			expectedResponse: []byte(`{"type":"about:blank","title":"Bad Request","status":400,"detail":"items is required","errors":[` +
+				`{"field":"items","rule":"required","message":"items is required"}]}`),
 			expectedHTTPCode: http.StatusBadRequest,
 			expectedError:    nil,
 		},
@@ -273,7 +312,8 @@ func Test_SaveReceiptPoints(t *testing.T) {
 			apiBuilder: func() *receiptAPIMock {
 				return &receiptAPIMock{}
 			},
-			expectedResponse: []byte(`{"error":"Total format error"}`),
+			expectedResponse: []byte(`{"type":"about:blank","title":"Bad Request","status":400,"detail":"total must be an amount with up to two decimals","errors":[` +
+				`{"field":"total","rule":"money","message":"total must be an amount with up to two decimals"}]}`),
 			expectedHTTPCode: http.StatusBadRequest,
 			expectedError:    nil,
 		},
@@ -316,7 +356,8 @@ func Test_SaveReceiptPoints(t *testing.T) {
 			apiBuilder: func() *receiptAPIMock {
 				return &receiptAPIMock{}
 			},
-			expectedResponse: []byte(`{"error":"ShortDescription is required"}`),
+			expectedResponse: []byte(`{"type":"about:blank","title":"Bad Request","status":400,"detail":"items[0].shortDescription is required","errors":[` +
+				`{"field":"items[0].shortDescription","rule":"required","message":"items[0].shortDescription is required"}]}`),
 			expectedHTTPCode: http.StatusBadRequest,
 			expectedError:    nil,
 		},
@@ -359,7 +400,8 @@ func Test_SaveReceiptPoints(t *testing.T) {
 			apiBuilder: func() *receiptAPIMock {
 				return &receiptAPIMock{}
 			},
-			expectedResponse: []byte(`{"error":"Price format error"}`),
+			expectedResponse: []byte(`{"type":"about:blank","title":"Bad Request","status":400,"detail":"items[0].price must be an amount with up to two decimals","errors":[` +
+				`{"field":"items[0].price","rule":"money","message":"items[0].price must be an amount with up to two decimals"}]}`),
 			expectedHTTPCode: http.StatusBadRequest,
 			expectedError:    nil,
 		},
@@ -398,11 +440,48 @@ func Test_SaveReceiptPoints(t *testing.T) {
 					Items: []rcp.Item{
 						{
 							ShortDescription: "Mountain Dew 12PK",
-							Price:            6.49,
+							Price:            money(t, "6.49"),
 						},
 					},
-					Total: 35.35,
-				}).Return(id, nil)
+					Total: money(t, "35.35"),
+				}, "").Return(id, true, nil)
+
+				return &apiMock
+			},
+			expectedResponse: []byte(`{"id":"0a25c541-2ab9-41d9-bedb-2d518df5dc43"}`),
+			expectedHTTPCode: http.StatusCreated,
+			expectedError:    nil,
+		},
+		{
+			name: "idempotent-replay-case",
+			contextBuilder: func() (echo.Context, *httptest.ResponseRecorder) {
+				reqText := []byte(`
+							{
+								"retailer": "Test",
+								"purchaseDate": "2022-01-01",
+								"purchaseTime": "13:01",
+								"items": [
+								  {
+									"shortDescription": "Mountain Dew 12PK",
+									"price": "6.49"
+								  }
+								],
+								"total": "6.49"
+							  }
+							`)
+
+				req := httptest.NewRequest(echo.POST, "http://localhost:8080/process", bytes.NewReader(reqText))
+				req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+				req.Header.Set("Idempotency-Key", "retry-1")
+				rec := httptest.NewRecorder()
+
+				return echo.New().NewContext(req, rec), rec
+			},
+			apiBuilder: func() *receiptAPIMock {
+				id, _ := uuid.Parse("0a25c541-2ab9-41d9-bedb-2d518df5dc43")
+
+				apiMock := receiptAPIMock{}
+				apiMock.On("SavePoints", context.Background(), mock.Anything, "retry-1").Return(id, false, nil)
 
 				return &apiMock
 			},
@@ -410,6 +489,122 @@ func Test_SaveReceiptPoints(t *testing.T) {
 			expectedHTTPCode: http.StatusOK,
 			expectedError:    nil,
 		},
+		{
+			name: "idempotency-key-reused-case",
+			contextBuilder: func() (echo.Context, *httptest.ResponseRecorder) {
+				reqText := []byte(`
+							{
+								"retailer": "Test",
+								"purchaseDate": "2022-01-01",
+								"purchaseTime": "13:01",
+								"items": [
+								  {
+									"shortDescription": "Mountain Dew 12PK",
+									"price": "6.49"
+								  }
+								],
+								"total": "6.49"
+							  }
+							`)
+
+				req := httptest.NewRequest(echo.POST, "http://localhost:8080/process", bytes.NewReader(reqText))
+				req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+				req.Header.Set("Idempotency-Key", "retry-1")
+				rec := httptest.NewRecorder()
+
+				return echo.New().NewContext(req, rec), rec
+			},
+			apiBuilder: func() *receiptAPIMock {
+				apiMock := receiptAPIMock{}
+				apiMock.On("SavePoints", context.Background(), mock.Anything, "retry-1").
+					Return(nil, false, rcp.ErrIdempotencyKeyReused)
+
+				return &apiMock
+			},
+			expectedResponse: []byte(`{"type":"about:blank","title":"Unprocessable Entity","status":422,` +
+				`"detail":"idempotency key already used with a different receipt"}`),
+			expectedHTTPCode: http.StatusUnprocessableEntity,
+			expectedError:    nil,
+		},
+		{
+			name: "inconsistent-receipt-case",
+			contextBuilder: func() (echo.Context, *httptest.ResponseRecorder) {
+				reqText := []byte(`
+							{
+								"retailer": "Test",
+								"purchaseDate": "2022-01-01",
+								"purchaseTime": "13:01",
+								"items": [
+								  {
+									"shortDescription": "Mountain Dew 12PK",
+									"price": "6.49"
+								  }
+								],
+								"total": "500.00"
+							  }
+							`)
+
+				req := httptest.NewRequest(echo.POST, "http://localhost:8080/process", bytes.NewReader(reqText))
+				req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+				rec := httptest.NewRecorder()
+
+				return echo.New().NewContext(req, rec), rec
+			},
+			apiBuilder: func() *receiptAPIMock {
+				apiMock := receiptAPIMock{}
+				apiMock.On("SavePoints", context.Background(), mock.Anything, "").Return(nil, false, &rcp.ValidationError{
+					Errors: []rcp.FieldError{
+						{
+							Field:   "total",
+							Rule:    rcp.RuleItemsSum,
+							Message: "items add up to 6.49 but total is 500.00",
+						},
+					},
+				})
+
+				return &apiMock
+			},
+			expectedResponse: []byte(`{"type":"about:blank","title":"Bad Request","status":400,"detail":"items add up to 6.49 but total is 500.00","errors":[` +
+				`{"field":"total","rule":"items-sum","message":"items add up to 6.49 but total is 500.00"}]}`),
+			expectedHTTPCode: http.StatusBadRequest,
+			expectedError:    nil,
+		},
+		{
+			name: "suspected-duplicate-case",
+			contextBuilder: func() (echo.Context, *httptest.ResponseRecorder) {
+				reqText := []byte(`
+							{
+								"retailer": "Test",
+								"purchaseDate": "2022-01-01",
+								"purchaseTime": "13:01",
+								"items": [
+								  {
+									"shortDescription": "Mountain Dew 12 PK",
+									"price": "6.49"
+								  }
+								],
+								"total": "6.49"
+							  }
+							`)
+
+				req := httptest.NewRequest(echo.POST, "http://localhost:8080/process", bytes.NewReader(reqText))
+				req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+				rec := httptest.NewRecorder()
+
+				return echo.New().NewContext(req, rec), rec
+			},
+			apiBuilder: func() *receiptAPIMock {
+				apiMock := receiptAPIMock{}
+				apiMock.On("SavePoints", context.Background(), mock.Anything, "").Return(nil, false,
+					fmt.Errorf("%s:%w", "0a25c541-2ab9-41d9-bedb-2d518df5dc43", rcp.ErrSuspectedDuplicate))
+
+				return &apiMock
+			},
+			expectedResponse: []byte(`{"type":"about:blank","title":"Conflict","status":409,` +
+				`"detail":"receipt looks like a resubmission of receipt 0a25c541-2ab9-41d9-bedb-2d518df5dc43"}`),
+			expectedHTTPCode: http.StatusConflict,
+			expectedError:    nil,
+		},
 	}
 
 	for _, c := range cases {
@@ -429,6 +624,54 @@ func Test_SaveReceiptPoints(t *testing.T) {
 	}
 }
 
+func Test_SaveReceiptPointsAllErrors(t *testing.T) {
+	reqText := []byte(`
+		{
+			"purchaseDate": "2022-01-01",
+			"purchaseTime": "1:01pm",
+			"items": [
+				{
+					"shortDescription": "Mountain Dew 12PK",
+					"price": "6.49"
+				},{
+					"shortDescription": "Emils Cheese Pizza",
+					"price": "12.25"
+				},{
+					"shortDescription": "Knorr Creamy Chicken",
+					"price": "1,26"
+				}
+			],
+			"total": "20"
+		}
+	`)
+
+	req := httptest.NewRequest(echo.POST, "http://localhost:8080/process", bytes.NewReader(reqText))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	s := Server{receiptApp: &receiptAPIMock{}}
+
+	err := s.saveReceiptPoints(echo.New().NewContext(req, rec))
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Equal(t, mimeProblemJSON, rec.Header().Get(echo.HeaderContentType))
+	assert.JSONEq(t, `{
+		"type": "about:blank",
+		"title": "Bad Request",
+		"status": 400,
+		"detail": "retailer is required, purchaseTime must use the 15:04 format, `+
+		`items[2].price must be an amount with up to two decimals",
+		"errors": [
+			{"field": "retailer", "rule": "required", "message": "retailer is required"},
+			{"field": "purchaseTime", "rule": "datetime", "message": "purchaseTime must use the 15:04 format"},
+			{
+				"field": "items[2].price",
+				"rule": "money",
+				"message": "items[2].price must be an amount with up to two decimals"
+			}
+		]
+	}`, rec.Body.String())
+}
+
 func Test_GetReceiptPoints(t *testing.T) {
 	cases := []struct {
 		name             string
@@ -450,7 +693,8 @@ func Test_GetReceiptPoints(t *testing.T) {
 			apiBuilder: func() *receiptAPIMock {
 				return &receiptAPIMock{}
 			},
-			expectedResponse: []byte(`{"error":"ID is required"}`),
+			expectedResponse: []byte(`{"type":"about:blank","title":"Bad Request","status":400,"detail":"id is required","errors":[` +
+				`{"field":"id","rule":"required","message":"id is required"}]}`),
 			expectedHTTPCode: http.StatusBadRequest,
 			expectedError:    nil,
 		},
@@ -470,7 +714,7 @@ func Test_GetReceiptPoints(t *testing.T) {
 			apiBuilder: func() *receiptAPIMock {
 				return &receiptAPIMock{}
 			},
-			expectedResponse: []byte(`{"error":"invalid UUID length: 6"}`),
+			expectedResponse: []byte(`{"type":"about:blank","title":"Bad Request","status":400,"detail":"invalid UUID length: 6"}`),
 			expectedHTTPCode: http.StatusBadRequest,
 			expectedError:    nil,
 		},
@@ -497,7 +741,7 @@ func Test_GetReceiptPoints(t *testing.T) {
 				return &apiMock
 			},
 
-			expectedResponse: []byte(`{"error":"unexpected error"}`),
+			expectedResponse: []byte(`{"type":"about:blank","title":"Internal Server Error","status":500,"detail":"unexpected error"}`),
 			expectedHTTPCode: http.StatusInternalServerError,
 			expectedError:    nil,
 		},
@@ -524,10 +768,37 @@ func Test_GetReceiptPoints(t *testing.T) {
 				return &apiMock
 			},
 
-			expectedResponse: []byte(`null`),
+			expectedResponse: []byte(`{"type":"about:blank","title":"Not Found","status":404,"detail":"points not found"}`),
 			expectedHTTPCode: http.StatusNotFound,
 			expectedError:    nil,
 		},
+		{
+			name: "store-evicted-case",
+			contextBuilder: func() (echo.Context, *httptest.ResponseRecorder) {
+				req := httptest.NewRequest(echo.GET, "http://localhost:8080/:id/points", nil)
+				req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+				rec := httptest.NewRecorder()
+				e := echo.New().NewContext(req, rec)
+				e.SetPath("/:id/points")
+				e.SetParamNames("id")
+				e.SetParamValues("0a25c541-2ab9-41d9-bedb-2d518df5dc43")
+
+				return e, rec
+			},
+
+			apiBuilder: func() *receiptAPIMock {
+				id, _ := uuid.Parse("0a25c541-2ab9-41d9-bedb-2d518df5dc43")
+
+				apiMock := receiptAPIMock{}
+				apiMock.On("GetPoints", context.Background(), id).Return(nil, memory.ErrEvicted)
+
+				return &apiMock
+			},
+
+			expectedResponse: []byte(`{"type":"about:blank","title":"Gone","status":410,"detail":"points evicted"}`),
+			expectedHTTPCode: http.StatusGone,
+			expectedError:    nil,
+		},
 		{
 			name: "success-case",
 			contextBuilder: func() (echo.Context, *httptest.ResponseRecorder) {
@@ -574,3 +845,396 @@ func Test_GetReceiptPoints(t *testing.T) {
 	}
 
 }
+
+func Test_GetReceipt(t *testing.T) {
+	cases := []struct {
+		name             string
+		contextBuilder   func() (echo.Context, *httptest.ResponseRecorder)
+		apiBuilder       func() *receiptAPIMock
+		expectedResponse []byte
+		expectedHTTPCode int
+		expectedError    error
+	}{
+		{
+			name: "invalid-id-case",
+			contextBuilder: func() (echo.Context, *httptest.ResponseRecorder) {
+				req := httptest.NewRequest(echo.GET, "http://localhost:8080/:id", nil)
+				rec := httptest.NewRecorder()
+				e := echo.New().NewContext(req, rec)
+				e.SetPath("/:id")
+				e.SetParamNames("id")
+				e.SetParamValues("010101")
+
+				return e, rec
+			},
+			apiBuilder: func() *receiptAPIMock {
+				return &receiptAPIMock{}
+			},
+			expectedResponse: []byte(`{"type":"about:blank","title":"Bad Request","status":400,"detail":"invalid UUID length: 6"}`),
+			expectedHTTPCode: http.StatusBadRequest,
+			expectedError:    nil,
+		},
+		{
+			name: "store-not-found-case",
+			contextBuilder: func() (echo.Context, *httptest.ResponseRecorder) {
+				req := httptest.NewRequest(echo.GET, "http://localhost:8080/:id", nil)
+				rec := httptest.NewRecorder()
+				e := echo.New().NewContext(req, rec)
+				e.SetPath("/:id")
+				e.SetParamNames("id")
+				e.SetParamValues("0a25c541-2ab9-41d9-bedb-2d518df5dc43")
+
+				return e, rec
+			},
+			apiBuilder: func() *receiptAPIMock {
+				id, _ := uuid.Parse("0a25c541-2ab9-41d9-bedb-2d518df5dc43")
+
+				apiMock := receiptAPIMock{}
+				apiMock.On("GetReceipt", context.Background(), id).Return(nil, memory.ErrNotFound)
+
+				return &apiMock
+			},
+			expectedResponse: []byte(`{"type":"about:blank","title":"Not Found","status":404,"detail":"points not found"}`),
+			expectedHTTPCode: http.StatusNotFound,
+			expectedError:    nil,
+		},
+		{
+			name: "success-case",
+			contextBuilder: func() (echo.Context, *httptest.ResponseRecorder) {
+				req := httptest.NewRequest(echo.GET, "http://localhost:8080/:id", nil)
+				rec := httptest.NewRecorder()
+				e := echo.New().NewContext(req, rec)
+				e.SetPath("/:id")
+				e.SetParamNames("id")
+				e.SetParamValues("0a25c541-2ab9-41d9-bedb-2d518df5dc43")
+
+				return e, rec
+			},
+			apiBuilder: func() *receiptAPIMock {
+				id, _ := uuid.Parse("0a25c541-2ab9-41d9-bedb-2d518df5dc43")
+
+				apiMock := receiptAPIMock{}
+				apiMock.On("GetReceipt", context.Background(), id).Return(&rcp.Receipt{
+					Retailer:     "Target",
+					PurchaseDate: purchaseDate(t, "2022-01-01"),
+					PurchaseTime: purchaseTime(t, "13:01"),
+					Items: []rcp.Item{
+						{
+							ShortDescription: "Mountain Dew 12PK",
+							Price:            money(t, "6.49"),
+						},
+					},
+					Total: money(t, "6.49"),
+				}, nil)
+
+				return &apiMock
+			},
+			expectedResponse: []byte(`{"retailer":"Target","purchaseDate":"2022-01-01","purchaseTime":"13:01",` +
+				`"items":[{"shortDescription":"Mountain Dew 12PK","price":"6.49"}],"total":"6.49"}`),
+			expectedHTTPCode: http.StatusOK,
+			expectedError:    nil,
+		},
+	}
+
+	for _, c := range cases {
+		expectedError := c.expectedError
+		expectedResponse := append(c.expectedResponse, paddingLastByte(t)...)
+		echoContext, rec := c.contextBuilder()
+		s := Server{
+			receiptApp: c.apiBuilder(),
+		}
+
+		t.Run(c.name, func(t *testing.T) {
+			err := s.getReceipt(echoContext)
+			assert.Equal(t, expectedError, err)
+			assert.Equal(t, expectedResponse, rec.Body.Bytes())
+			assert.Equal(t, c.expectedHTTPCode, rec.Code)
+		})
+	}
+}
+
+func Test_GetReceiptPointsBreakdown(t *testing.T) {
+	cases := []struct {
+		name             string
+		contextBuilder   func() (echo.Context, *httptest.ResponseRecorder)
+		apiBuilder       func() *receiptAPIMock
+		expectedResponse []byte
+		expectedHTTPCode int
+		expectedError    error
+	}{
+		{
+			name: "store-not-found-case",
+			contextBuilder: func() (echo.Context, *httptest.ResponseRecorder) {
+				req := httptest.NewRequest(echo.GET, "http://localhost:8080/:id/points/breakdown", nil)
+				rec := httptest.NewRecorder()
+				e := echo.New().NewContext(req, rec)
+				e.SetPath("/:id/points/breakdown")
+				e.SetParamNames("id")
+				e.SetParamValues("0a25c541-2ab9-41d9-bedb-2d518df5dc43")
+
+				return e, rec
+			},
+			apiBuilder: func() *receiptAPIMock {
+				id, _ := uuid.Parse("0a25c541-2ab9-41d9-bedb-2d518df5dc43")
+
+				apiMock := receiptAPIMock{}
+				apiMock.On("GetPoints", context.Background(), id).Return(nil, nil)
+
+				return &apiMock
+			},
+			expectedResponse: []byte(`{"type":"about:blank","title":"Not Found","status":404,"detail":"points not found"}`),
+			expectedHTTPCode: http.StatusNotFound,
+			expectedError:    nil,
+		},
+		{
+			name: "success-case",
+			contextBuilder: func() (echo.Context, *httptest.ResponseRecorder) {
+				req := httptest.NewRequest(echo.GET, "http://localhost:8080/:id/points/breakdown", nil)
+				rec := httptest.NewRecorder()
+				e := echo.New().NewContext(req, rec)
+				e.SetPath("/:id/points/breakdown")
+				e.SetParamNames("id")
+				e.SetParamValues("0a25c541-2ab9-41d9-bedb-2d518df5dc43")
+
+				return e, rec
+			},
+			apiBuilder: func() *receiptAPIMock {
+				id, _ := uuid.Parse("0a25c541-2ab9-41d9-bedb-2d518df5dc43")
+
+				apiMock := receiptAPIMock{}
+				apiMock.On("GetPoints", context.Background(), id).Return(&rcp.Points{
+					Points:         9,
+					RuleSetVersion: "v1",
+					Breakdown: []rcp.Award{
+						{
+							Rule:        "retailer-name",
+							Description: "retailer",
+							Points:      6,
+						},
+						{
+							Rule:        "item-description",
+							Description: "description",
+							Points:      3,
+							Item: &rcp.AwardedItem{
+								Index: 1,
+								Item: rcp.Item{
+									ShortDescription: "Emils Cheese Pizza",
+									Price:            money(t, "12.25"),
+								},
+							},
+						},
+					},
+				}, nil)
+
+				return &apiMock
+			},
+			expectedResponse: []byte(`{"points":9,"ruleSetVersion":"v1","breakdown":[` +
+				`{"rule":"retailer-name","description":"retailer","points":6},` +
+				`{"rule":"item-description","description":"description","points":3,` +
+				`"item":{"index":1,"shortDescription":"Emils Cheese Pizza","price":"12.25"}}]}`),
+			expectedHTTPCode: http.StatusOK,
+			expectedError:    nil,
+		},
+	}
+
+	for _, c := range cases {
+		expectedError := c.expectedError
+		expectedResponse := append(c.expectedResponse, paddingLastByte(t)...)
+		echoContext, rec := c.contextBuilder()
+		s := Server{
+			receiptApp: c.apiBuilder(),
+		}
+
+		t.Run(c.name, func(t *testing.T) {
+			err := s.getReceiptPointsBreakdown(echoContext)
+			assert.Equal(t, expectedError, err)
+			assert.Equal(t, expectedResponse, rec.Body.Bytes())
+			assert.Equal(t, c.expectedHTTPCode, rec.Code)
+		})
+	}
+}
+
+func Test_RescoreReceiptPoints(t *testing.T) {
+	cases := []struct {
+		name             string
+		contextBuilder   func() (echo.Context, *httptest.ResponseRecorder)
+		apiBuilder       func() *receiptAPIMock
+		expectedResponse []byte
+		expectedHTTPCode int
+		expectedError    error
+	}{
+		{
+			name: "unknown-version-case",
+			contextBuilder: func() (echo.Context, *httptest.ResponseRecorder) {
+				req := httptest.NewRequest(echo.GET, "http://localhost:8080/:id/points/rescore?version=v9", nil)
+				rec := httptest.NewRecorder()
+				e := echo.New().NewContext(req, rec)
+				e.SetPath("/:id/points/rescore")
+				e.SetParamNames("id")
+				e.SetParamValues("0a25c541-2ab9-41d9-bedb-2d518df5dc43")
+
+				return e, rec
+			},
+			apiBuilder: func() *receiptAPIMock {
+				id, _ := uuid.Parse("0a25c541-2ab9-41d9-bedb-2d518df5dc43")
+
+				apiMock := receiptAPIMock{}
+				apiMock.On("RescorePoints", context.Background(), id, "v9").
+					Return(nil, fmt.Errorf("v9:%w", calculator.ErrUnknownRuleSet))
+
+				return &apiMock
+			},
+			expectedResponse: []byte(`{"type":"about:blank","title":"Bad Request","status":400,"detail":"rule set version v9 is unknown"}`),
+			expectedHTTPCode: http.StatusBadRequest,
+			expectedError:    nil,
+		},
+		{
+			name: "success-case",
+			contextBuilder: func() (echo.Context, *httptest.ResponseRecorder) {
+				req := httptest.NewRequest(echo.GET, "http://localhost:8080/:id/points/rescore?version=v2", nil)
+				rec := httptest.NewRecorder()
+				e := echo.New().NewContext(req, rec)
+				e.SetPath("/:id/points/rescore")
+				e.SetParamNames("id")
+				e.SetParamValues("0a25c541-2ab9-41d9-bedb-2d518df5dc43")
+
+				return e, rec
+			},
+			apiBuilder: func() *receiptAPIMock {
+				id, _ := uuid.Parse("0a25c541-2ab9-41d9-bedb-2d518df5dc43")
+
+				apiMock := receiptAPIMock{}
+				apiMock.On("RescorePoints", context.Background(), id, "v2").Return(&rcp.Points{
+					Points:         12,
+					RuleSetVersion: "v2",
+					Breakdown: []rcp.Award{
+						{
+							Rule:        "retailer-name",
+							Description: "retailer",
+							Points:      12,
+						},
+					},
+				}, nil)
+
+				return &apiMock
+			},
+			expectedResponse: []byte(`{"points":12,"ruleSetVersion":"v2","breakdown":[` +
+				`{"rule":"retailer-name","description":"retailer","points":12}]}`),
+			expectedHTTPCode: http.StatusOK,
+			expectedError:    nil,
+		},
+	}
+
+	for _, c := range cases {
+		expectedError := c.expectedError
+		expectedResponse := append(c.expectedResponse, paddingLastByte(t)...)
+		echoContext, rec := c.contextBuilder()
+		s := Server{
+			receiptApp: c.apiBuilder(),
+		}
+
+		t.Run(c.name, func(t *testing.T) {
+			err := s.rescoreReceiptPoints(echoContext)
+			assert.Equal(t, expectedError, err)
+			assert.Equal(t, expectedResponse, rec.Body.Bytes())
+			assert.Equal(t, c.expectedHTTPCode, rec.Code)
+		})
+	}
+}
+
+func Test_GetFlaggedReceipts(t *testing.T) {
+	cases := []struct {
+		name             string
+		apiBuilder       func() *receiptAPIMock
+		expectedResponse []byte
+		expectedHTTPCode int
+		expectedError    error
+	}{
+		{
+			name: "no-flagged-case",
+			apiBuilder: func() *receiptAPIMock {
+				apiMock := receiptAPIMock{}
+				apiMock.On("GetFlagged", context.Background()).Return([]rcp.FlaggedReceipt{}, nil)
+
+				return &apiMock
+			},
+			expectedResponse: []byte(`{"receipts":[]}`),
+			expectedHTTPCode: http.StatusOK,
+			expectedError:    nil,
+		},
+		{
+			name: "store-error-case",
+			apiBuilder: func() *receiptAPIMock {
+				apiMock := receiptAPIMock{}
+				apiMock.On("GetFlagged", context.Background()).Return(nil, errors.New("time out"))
+
+				return &apiMock
+			},
+			expectedResponse: []byte(`{"type":"about:blank","title":"Internal Server Error","status":500,` +
+				`"detail":"unexpected error"}`),
+			expectedHTTPCode: http.StatusInternalServerError,
+			expectedError:    nil,
+		},
+		{
+			name: "success-case",
+			apiBuilder: func() *receiptAPIMock {
+				id, _ := uuid.Parse("0a25c541-2ab9-41d9-bedb-2d518df5dc43")
+				of, _ := uuid.Parse("7fb1377b-b223-49d9-a31a-5a02701dd310")
+
+				apiMock := receiptAPIMock{}
+				apiMock.On("GetFlagged", context.Background()).Return([]rcp.FlaggedReceipt{
+					{
+						ID: id,
+						Points: rcp.Points{
+							Points:         0,
+							RuleSetVersion: "v1",
+							Breakdown: []rcp.Award{
+								{Rule: "retailer-name", Description: "retailer", Points: 4},
+								{Rule: "duplicate-receipt", Description: "duplicate", Points: -4},
+							},
+							Duplicate: &rcp.Duplicate{Of: of, Similarity: 0.9, Policy: "score-zero"},
+						},
+					},
+				}, nil)
+
+				return &apiMock
+			},
+			expectedResponse: []byte(`{"receipts":[{"id":"0a25c541-2ab9-41d9-bedb-2d518df5dc43",` +
+				`"points":0,"ruleSetVersion":"v1","breakdown":[` +
+				`{"rule":"retailer-name","description":"retailer","points":4},` +
+				`{"rule":"duplicate-receipt","description":"duplicate","points":-4}],` +
+				`"duplicate":{"of":"7fb1377b-b223-49d9-a31a-5a02701dd310","similarity":0.9,"policy":"score-zero"}}]}`),
+			expectedHTTPCode: http.StatusOK,
+			expectedError:    nil,
+		},
+	}
+
+	for _, c := range cases {
+		expectedError := c.expectedError
+		expectedResponse := append(c.expectedResponse, paddingLastByte(t)...)
+		req := httptest.NewRequest(echo.GET, "http://localhost:8080/receipt/flagged", nil)
+		rec := httptest.NewRecorder()
+		echoContext := echo.New().NewContext(req, rec)
+		s := Server{
+			receiptApp: c.apiBuilder(),
+		}
+
+		t.Run(c.name, func(t *testing.T) {
+			err := s.getFlaggedReceipts(echoContext)
+			assert.Equal(t, expectedError, err)
+			assert.Equal(t, expectedResponse, rec.Body.Bytes())
+			assert.Equal(t, c.expectedHTTPCode, rec.Code)
+		})
+	}
+}
+
+func money(t *testing.T, amount string) rcp.Money {
+	t.Helper()
+
+	m, err := rcp.ParseMoney(amount)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return m
+}
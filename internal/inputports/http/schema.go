@@ -0,0 +1,332 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	rcp "receipt-processor-challenge/internal/domain/receipt"
+
+	"github.com/google/uuid"
+)
+
+/*
+schema is the subset of the OpenAPI 3.0 schema object used by the API spec.
+Values are checked as decoded by encoding/json with UseNumber, every keyword
+missing from this struct is ignored.
+*/
+type schema struct {
+	Ref                  string             `json:"$ref"`
+	Type                 string             `json:"type"`
+	Format               string             `json:"format"`
+	Pattern              string             `json:"pattern"`
+	Enum                 []string           `json:"enum"`
+	Nullable             bool               `json:"nullable"`
+	MinLength            *int               `json:"minLength"`
+	MinItems             *int               `json:"minItems"`
+	Minimum              *float64           `json:"minimum"`
+	Maximum              *float64           `json:"maximum"`
+	Required             []string           `json:"required"`
+	Properties           map[string]*schema `json:"properties"`
+	AdditionalProperties *bool              `json:"additionalProperties"`
+	Items                *schema            `json:"items"`
+
+	pattern *regexp.Regexp
+}
+
+// resolve replaces the references of s and of its subschemas by the schemas
+// of components, and compiles the patterns.
+func (s *schema) resolve(components map[string]*schema) (*schema, error) {
+	if s == nil {
+		return nil, nil //nolint:nilnil
+	}
+
+	if s.Ref != "" {
+		name, ok := strings.CutPrefix(s.Ref, "#/components/schemas/")
+		if !ok || components[name] == nil {
+			return nil, fmt.Errorf("%s:%w", s.Ref, ErrInvalidSpec)
+		}
+
+		// the components are resolved on their own.
+		return components[name], nil
+	}
+
+	if s.Pattern != "" && s.pattern == nil {
+		pattern, err := regexp.Compile(s.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%w", err.Error(), ErrInvalidSpec)
+		}
+
+		s.pattern = pattern
+	}
+
+	for name, property := range s.Properties {
+		resolved, err := property.resolve(components)
+		if err != nil {
+			return nil, err
+		}
+
+		s.Properties[name] = resolved
+	}
+
+	items, err := s.Items.resolve(components)
+	if err != nil {
+		return nil, err
+	}
+
+	s.Items = items
+
+	return s, nil
+}
+
+// validate checks value and returns a fieldError for every failed keyword,
+// path is the JSON path of value. Only the first failed keyword of a value is
+// reported.
+func (s *schema) validate(path string, value interface{}) []fieldError {
+	if value == nil {
+		// a schema without type accepts any value.
+		if s.Nullable || s.Type == "" {
+			return nil
+		}
+
+		return []fieldError{typeError(path, s.Type)}
+	}
+
+	switch s.Type {
+	case "object":
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return []fieldError{typeError(path, s.Type)}
+		}
+
+		return s.validateObject(path, object)
+
+	case "array":
+		array, ok := value.([]interface{})
+		if !ok {
+			return []fieldError{typeError(path, s.Type)}
+		}
+
+		return s.validateArray(path, array)
+
+	case "string":
+		str, ok := value.(string)
+		if !ok {
+			return []fieldError{typeError(path, s.Type)}
+		}
+
+		return s.validateString(path, str)
+
+	case "integer", "number":
+		number, ok := value.(json.Number)
+		if !ok {
+			return []fieldError{typeError(path, s.Type)}
+		}
+
+		return s.validateNumber(path, number)
+
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return []fieldError{typeError(path, s.Type)}
+		}
+	}
+
+	return nil
+}
+
+func (s *schema) validateObject(path string, object map[string]interface{}) []fieldError {
+	errs := []fieldError{}
+
+	for _, name := range s.Required {
+		if _, ok := object[name]; !ok {
+			field := fieldPath(path, name)
+			errs = append(errs, fieldError{
+				Field:   field,
+				Rule:    "required",
+				Message: fmt.Sprintf("%s is required", field),
+			})
+		}
+	}
+
+	// sorted so the errors are reported in the same order every time.
+	names := make([]string, 0, len(object))
+	for name := range object {
+		names = append(names, name)
+	}
+
+	slices.Sort(names)
+
+	for _, name := range names {
+		field := fieldPath(path, name)
+
+		property, ok := s.Properties[name]
+		if !ok {
+			if s.AdditionalProperties != nil && !*s.AdditionalProperties {
+				errs = append(errs, fieldError{
+					Field:   field,
+					Rule:    "additionalProperties",
+					Message: fmt.Sprintf("%s is not allowed", field),
+				})
+			}
+
+			continue
+		}
+
+		errs = append(errs, property.validate(field, object[name])...)
+	}
+
+	return errs
+}
+
+func (s *schema) validateArray(path string, array []interface{}) []fieldError {
+	if s.MinItems != nil && len(array) < *s.MinItems {
+		return []fieldError{{
+			Field:   path,
+			Rule:    "minItems",
+			Message: fmt.Sprintf("%s must have at least %d items", path, *s.MinItems),
+		}}
+	}
+
+	if s.Items == nil {
+		return nil
+	}
+
+	errs := []fieldError{}
+
+	for index, value := range array {
+		errs = append(errs, s.Items.validate(fmt.Sprintf("%s[%d]", path, index), value)...)
+	}
+
+	return errs
+}
+
+func (s *schema) validateString(path, str string) []fieldError {
+	switch {
+	case s.MinLength != nil && utf8.RuneCountInString(str) < *s.MinLength:
+		if *s.MinLength == 1 {
+			return []fieldError{{Field: path, Rule: "required", Message: fmt.Sprintf("%s is required", path)}}
+		}
+
+		return []fieldError{{
+			Field:   path,
+			Rule:    "minLength",
+			Message: fmt.Sprintf("%s must have at least %d characters", path, *s.MinLength),
+		}}
+
+	case !validFormat(s.Format, str):
+		return []fieldError{formatError(path, s.Format)}
+
+	case s.pattern != nil && !s.pattern.MatchString(str):
+		return []fieldError{{
+			Field:   path,
+			Rule:    "pattern",
+			Message: fmt.Sprintf("%s must match %s", path, s.Pattern),
+		}}
+
+	case len(s.Enum) > 0 && !slices.Contains(s.Enum, str):
+		return []fieldError{{
+			Field:   path,
+			Rule:    "enum",
+			Message: fmt.Sprintf("%s must be one of %s", path, strings.Join(s.Enum, ", ")),
+		}}
+	}
+
+	return nil
+}
+
+func (s *schema) validateNumber(path string, number json.Number) []fieldError {
+	if s.Type == "integer" {
+		if _, err := strconv.ParseInt(number.String(), 10, 64); err != nil {
+			return []fieldError{typeError(path, s.Type)}
+		}
+	}
+
+	value, err := number.Float64()
+	if err != nil {
+		return []fieldError{typeError(path, s.Type)}
+	}
+
+	switch {
+	case s.Minimum != nil && value < *s.Minimum:
+		return []fieldError{{
+			Field:   path,
+			Rule:    "minimum",
+			Message: fmt.Sprintf("%s must be at least %v", path, *s.Minimum),
+		}}
+
+	case s.Maximum != nil && value > *s.Maximum:
+		return []fieldError{{
+			Field:   path,
+			Rule:    "maximum",
+			Message: fmt.Sprintf("%s must be at most %v", path, *s.Maximum),
+		}}
+	}
+
+	return nil
+}
+
+// validFormat checks the formats the API uses, the others are accepted.
+func validFormat(format, str string) bool {
+	switch format {
+	case "uuid":
+		_, err := uuid.Parse(str)
+
+		return err == nil
+	case "date":
+		_, err := time.Parse(rcp.DatePurchaseFormat, str)
+
+		return err == nil
+	case "money":
+		_, err := rcp.ParseMoney(str)
+
+		return err == nil
+	}
+
+	return true
+}
+
+func formatError(path, format string) fieldError {
+	var msg string
+
+	switch format {
+	case "uuid":
+		msg = fmt.Sprintf("%s must be a uuid", path)
+	case "date":
+		msg = fmt.Sprintf("%s must use the %s format", path, rcp.DatePurchaseFormat)
+	case "money":
+		msg = fmt.Sprintf("%s must be an amount with up to two decimals", path)
+	}
+
+	return fieldError{Field: path, Rule: format, Message: msg}
+}
+
+func typeError(path, typ string) fieldError {
+	return fieldError{
+		Field:   fieldName(path),
+		Rule:    "type",
+		Message: fmt.Sprintf("%s must be of type %s", fieldName(path), typ),
+	}
+}
+
+// fieldPath is the JSON path of the property name of the object at path.
+func fieldPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+
+	return path + "." + name
+}
+
+// fieldName names the value at path in the errors, the root is the body.
+func fieldName(path string) string {
+	if path == "" {
+		return "body"
+	}
+
+	return path
+}
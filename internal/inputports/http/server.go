@@ -2,52 +2,227 @@ package http
 
 import (
 	"context"
-	"log"
-	"os"
+	"errors"
+	"log/slog"
+	"net/http"
+	"sync/atomic"
+	"time"
 
 	rcp "receipt-processor-challenge/internal/domain/receipt"
+	"receipt-processor-challenge/internal/tracing"
 
 	"github.com/google/uuid"
 	"github.com/labstack/echo/v4"
 )
 
 const (
-	processPath string = "/process"
-	pointsPath  string = "/:id/points"
+	processPath   string = "/process"
+	pointsPath    string = "/:id/points"
+	receiptPath   string = "/:id"
+	breakdownPath string = "/:id/points/breakdown"
+	rescorePath   string = "/:id/points/rescore"
+	flaggedPath   string = "/flagged"
 
-	envPort string = "HTTP_PORT"
+	defaultAddr            = ":8080"
+	defaultShutdownTimeOut = 10 * time.Second
+	defaultMaxBodyBytes    = 16 << 20
+
+	headerIdempotencyKey string = "Idempotency-Key"
 )
 
 type ReceiptAPI interface {
-	SavePoints(ctx context.Context, r rcp.Receipt) (uuid.UUID, error)
+	SavePoints(ctx context.Context, r rcp.Receipt, idempotencyKey string) (uuid.UUID, bool, error)
 	GetPoints(ctx context.Context, id uuid.UUID) (*rcp.Points, error)
+	GetReceipt(ctx context.Context, id uuid.UUID) (*rcp.Receipt, error)
+	RescorePoints(ctx context.Context, id uuid.UUID, version string) (*rcp.Points, error)
+	GetFlagged(ctx context.Context) ([]rcp.FlaggedReceipt, error)
 }
 
 type Server struct {
-	receiptApp ReceiptAPI
-	router     *echo.Echo
+	receiptApp       ReceiptAPI
+	admin            AdminAPI
+	adminToken       string
+	snapshotting     atomic.Bool
+	router           *echo.Echo
+	addr             string
+	certFile         string
+	keyFile          string
+	shutdownTimeOut  time.Duration
+	checks           []HealthCheck
+	metrics          *httpMetrics
+	tracer           *tracing.Tracer
+	logger           *slog.Logger
+	validateSpec     bool
+	batchMaxSize     int
+	batchConcurrency int
+	maxBodyBytes     int64
+	shuttingDown     atomic.Bool
+}
+
+// Option configures a Server.
+type Option func(s *Server)
+
+// WithAddr sets the address the server listens on, ":8080" by default.
+func WithAddr(addr string) Option {
+	return func(s *Server) {
+		s.addr = addr
+	}
+}
+
+// WithTimeOuts limits the time to read a request, to write its response and
+// to keep an idle connection open, zero means no limit.
+func WithTimeOuts(read, write, idle time.Duration) Option {
+	return func(s *Server) {
+		s.router.Server.ReadTimeout = read
+		s.router.Server.WriteTimeout = write
+		s.router.Server.IdleTimeout = idle
+	}
+}
+
+// WithTLS serves HTTPS with the given certificate and key files.
+func WithTLS(certFile, keyFile string) Option {
+	return func(s *Server) {
+		s.certFile = certFile
+		s.keyFile = keyFile
+	}
+}
+
+// WithShutdownTimeOut sets how long Start waits for the requests in flight
+// once its context is done, 10 seconds by default.
+func WithShutdownTimeOut(timeOut time.Duration) Option {
+	return func(s *Server) {
+		s.shutdownTimeOut = timeOut
+	}
+}
+
+// WithBodyLimit sets the maximum size of a request body, 16 MiB by default. A
+// limit that is not positive keeps the default.
+func WithBodyLimit(maxBytes int64) Option {
+	return func(s *Server) {
+		if maxBytes > 0 {
+			s.maxBodyBytes = maxBytes
+		}
+	}
+}
+
+// WithAdmin serves the admin endpoints under /admin to the requests with the
+// bearer token, they are disabled by default and without a token.
+func WithAdmin(admin AdminAPI, token string) Option {
+	return func(s *Server) {
+		s.admin = admin
+		s.adminToken = token
+	}
 }
 
-func NewServer(_ context.Context, app ReceiptAPI) *Server {
-	return &Server{
-		receiptApp: app,
-		router:     echo.New(),
+func NewServer(_ context.Context, app ReceiptAPI, opts ...Option) *Server {
+	s := &Server{
+		receiptApp:       app,
+		router:           echo.New(),
+		addr:             defaultAddr,
+		shutdownTimeOut:  defaultShutdownTimeOut,
+		logger:           slog.Default(),
+		batchMaxSize:     defaultBatchMaxSize,
+		batchConcurrency: defaultBatchConcurrency,
+		maxBodyBytes:     defaultMaxBodyBytes,
+	}
+
+	// the server logs through its logger instead.
+	s.router.HideBanner = true
+	s.router.HidePort = true
+
+	for _, opt := range opts {
+		opt(s)
 	}
+
+	return s
 }
 
 func (s *Server) routes() {
+	s.router.Use(s.requestID, s.logRequests, s.limitBody)
+
+	if s.tracer != nil {
+		s.router.Use(s.trace)
+	}
+
+	if s.metrics != nil {
+		s.router.Use(s.instrument)
+		s.router.GET(metricsPath, s.getMetrics)
+	}
+
+	if s.validateSpec {
+		s.router.Use(s.enforceSpec)
+	}
+
+	s.router.GET(healthPath, s.getHealth)
+	s.router.GET(readyPath, s.getReadiness)
+	s.router.GET(specPath, s.getSpec)
+	s.router.GET(docsPath, s.getDocs)
+
 	gReceipt := s.router.Group("/receipt")
 	gReceipt.POST(processPath, s.saveReceiptPoints)
+	gReceipt.POST(batchPath, s.saveReceiptBatch)
 	gReceipt.GET(pointsPath, s.getReceiptPoints)
+	gReceipt.GET(breakdownPath, s.getReceiptPointsBreakdown)
+	gReceipt.GET(rescorePath, s.rescoreReceiptPoints)
+	gReceipt.GET(flaggedPath, s.getFlaggedReceipts)
+	gReceipt.GET(receiptPath, s.getReceipt)
+
+	if s.admin != nil && s.adminToken != "" {
+		gAdmin := s.router.Group("/admin", s.requireAdminToken)
+		gAdmin.POST(snapshotPath, s.saveSnapshot)
+	}
 }
 
-func (s *Server) Start() {
+// limitBody stops reading a request body after maxBodyBytes, so no handler
+// buffers an unbounded body.
+func (s *Server) limitBody(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(eCtx echo.Context) error {
+		req := eCtx.Request()
+		req.Body = http.MaxBytesReader(eCtx.Response(), req.Body, s.maxBodyBytes)
+
+		return next(eCtx)
+	}
+}
+
+/*
+Start serves the API until ctx is done, then stops accepting connections and
+waits for the requests in flight during the shutdown time out. It returns nil
+after a clean shutdown.
+*/
+func (s *Server) Start(ctx context.Context) error {
 	s.routes()
 
-	port := os.Getenv(envPort)
-	if port == "" {
-		port = ":8080"
+	started := make(chan error, 1)
+
+	go func() {
+		if s.certFile != "" {
+			started <- s.router.StartTLS(s.addr, s.certFile, s.keyFile)
+		} else {
+			started <- s.router.Start(s.addr)
+		}
+	}()
+
+	s.logger.Info("http server starting", slog.String("addr", s.addr), slog.Bool("tls", s.certFile != ""))
+
+	select {
+	case err := <-started:
+		return err
+	case <-ctx.Done():
+	}
+
+	s.shuttingDown.Store(true)
+	s.logger.Info("http server shutting down", slog.Duration("timeout", s.shutdownTimeOut))
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), s.shutdownTimeOut)
+	defer cancel()
+
+	if err := s.router.Shutdown(shutdownCtx); err != nil {
+		return err
+	}
+
+	if err := <-started; !errors.Is(err, http.ErrServerClosed) {
+		return err
 	}
 
-	log.Fatal(s.router.Start(port))
+	return nil
 }
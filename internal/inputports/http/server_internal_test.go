@@ -0,0 +1,155 @@
+package http
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	rcp "receipt-processor-challenge/internal/domain/receipt"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func Test_StartShutdown(t *testing.T) {
+	requested := make(chan struct{})
+	apiMock := &receiptAPIMock{}
+	apiMock.On("GetFlagged", mock.Anything).Run(func(mock.Arguments) {
+		close(requested)
+		time.Sleep(100 * time.Millisecond)
+	}).Return([]rcp.FlaggedReceipt{}, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	s := NewServer(ctx, apiMock, WithAddr("127.0.0.1:0"), WithShutdownTimeOut(time.Second))
+	s.router.HideBanner = true
+	s.router.HidePort = true
+
+	stopped := make(chan error, 1)
+
+	go func() {
+		stopped <- s.Start(ctx)
+	}()
+
+	var addr net.Addr
+
+	if !assert.Eventually(t, func() bool {
+		addr = s.router.ListenerAddr()
+
+		return addr != nil
+	}, time.Second, time.Millisecond) {
+		return
+	}
+
+	response := make(chan int, 1)
+
+	go func() {
+		resp, err := http.Get("http://" + addr.String() + "/receipt/flagged")
+		if err != nil {
+			response <- 0
+
+			return
+		}
+
+		resp.Body.Close()
+		response <- resp.StatusCode
+	}()
+
+	// the request in flight finishes before Start returns.
+	<-requested
+	cancel()
+
+	assert.NoError(t, <-stopped)
+	assert.Equal(t, http.StatusOK, <-response)
+}
+
+func Test_StartError(t *testing.T) {
+	s := NewServer(context.Background(), &receiptAPIMock{}, WithAddr("invalid-address"))
+	s.router.HideBanner = true
+
+	assert.Error(t, s.Start(context.Background()))
+}
+
+func Test_BodyLimit(t *testing.T) {
+	// the receipt is valid but its padding puts it over the limit.
+	large := strings.Replace(specReceipt, `"Target"`, `"Target`+strings.Repeat(" ", 256)+`"`, 1)
+
+	cases := []struct {
+		name             string
+		path             string
+		contentType      string
+		body             string
+		options          []Option
+		expectedHTTPCode int
+	}{
+		{
+			name:             "under-limit-case",
+			path:             "/receipt/process",
+			contentType:      echo.MIMEApplicationJSON,
+			body:             specReceipt,
+			expectedHTTPCode: http.StatusOK,
+		},
+		{
+			name:             "process-case",
+			path:             "/receipt/process",
+			contentType:      echo.MIMEApplicationJSON,
+			body:             large,
+			expectedHTTPCode: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:             "spec-validation-case",
+			path:             "/receipt/process",
+			contentType:      echo.MIMEApplicationJSON,
+			body:             large,
+			options:          []Option{WithSpecValidation()},
+			expectedHTTPCode: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:             "json-batch-case",
+			path:             "/receipt/process/batch",
+			contentType:      echo.MIMEApplicationJSON,
+			body:             "[" + specReceipt + "," + specReceipt + "]",
+			expectedHTTPCode: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:             "ndjson-batch-case",
+			path:             "/receipt/process/batch",
+			contentType:      mimeNDJSON,
+			body:             specReceipt + "\n" + specReceipt + "\n",
+			expectedHTTPCode: http.StatusRequestEntityTooLarge,
+		},
+	}
+
+	for _, c := range cases {
+		c := c
+
+		t.Run(c.name, func(t *testing.T) {
+			apiMock := &receiptAPIMock{}
+			apiMock.On("SavePoints", mock.Anything, mock.Anything, "").Return(uuid.New(), false, nil)
+
+			s := NewServer(context.Background(), apiMock, append(c.options, WithBodyLimit(200))...)
+			s.routes()
+
+			req := httptest.NewRequest(echo.POST, c.path, strings.NewReader(c.body))
+			req.Header.Set(echo.HeaderContentType, c.contentType)
+			rec := httptest.NewRecorder()
+
+			s.router.ServeHTTP(rec, req)
+
+			assert.Equal(t, c.expectedHTTPCode, rec.Code)
+
+			if c.expectedHTTPCode == http.StatusRequestEntityTooLarge {
+				assert.Equal(t, mimeProblemJSON, rec.Header().Get(echo.HeaderContentType))
+				assert.JSONEq(t, `{"type":"about:blank","title":"Request Entity Too Large","status":413,`+
+					`"detail":"body can not be larger than 200 bytes"}`, rec.Body.String())
+			}
+		})
+	}
+}
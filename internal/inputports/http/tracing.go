@@ -0,0 +1,57 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"receipt-processor-challenge/internal/tracing"
+
+	"github.com/labstack/echo/v4"
+)
+
+// WithTracer records a span for every request, continuing the trace of the
+// traceparent header when the caller sends one. The health and metrics
+// endpoints are not traced.
+func WithTracer(tracer *tracing.Tracer) Option {
+	return func(s *Server) {
+		s.tracer = tracer
+	}
+}
+
+func (s *Server) trace(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(eCtx echo.Context) error {
+		route := eCtx.Path()
+
+		switch route {
+		case healthPath, readyPath, metricsPath:
+			return next(eCtx)
+		case "":
+			route = unmatchedRoute
+		}
+
+		req := eCtx.Request()
+		remote, _ := tracing.ParseTraceparent(req.Header.Get(tracing.HeaderTraceparent))
+
+		ctx, span := s.tracer.Start(req.Context(), req.Method+" "+route, tracing.KindServer, remote)
+		defer span.End()
+
+		eCtx.SetRequest(req.WithContext(ctx))
+
+		if err := next(eCtx); err != nil {
+			eCtx.Error(err)
+		}
+
+		status := eCtx.Response().Status
+
+		span.SetAttribute("http.method", req.Method)
+		span.SetAttribute("http.route", route)
+		span.SetAttribute("http.status_code", strconv.Itoa(status))
+
+		if status >= http.StatusInternalServerError {
+			span.RecordError(errors.New(http.StatusText(status)))
+		}
+
+		return nil
+	}
+}
@@ -0,0 +1,69 @@
+package http
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	rcp "receipt-processor-challenge/internal/domain/receipt"
+	"receipt-processor-challenge/internal/tracing"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// spansExporter keeps the exported spans.
+type spansExporter struct {
+	spans []tracing.SpanData
+}
+
+func (e *spansExporter) Export(_ context.Context, spans []tracing.SpanData) error {
+	e.spans = append(e.spans, spans...)
+
+	return nil
+}
+
+func (e *spansExporter) Shutdown(context.Context) error {
+	return nil
+}
+
+func Test_Tracing(t *testing.T) {
+	id := uuid.New()
+	apiMock := &receiptAPIMock{}
+
+	var traced tracing.SpanContext
+
+	apiMock.On("GetPoints", mock.Anything, id).Run(func(args mock.Arguments) {
+		ctx, _ := args.Get(0).(context.Context)
+		traced = tracing.FromContext(ctx).Context()
+	}).Return(&rcp.Points{Points: 28}, nil)
+
+	exporter := &spansExporter{}
+	tracer := tracing.New(exporter)
+	s := NewServer(context.Background(), apiMock, WithTracer(tracer))
+	s.routes()
+
+	req := httptest.NewRequest(echo.GET, "/receipt/"+id.String()+"/points", nil)
+	req.Header.Set(tracing.HeaderTraceparent, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	s.router.ServeHTTP(httptest.NewRecorder(), req)
+
+	s.router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(echo.GET, "/healthz", nil))
+	s.router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(echo.GET, "/unknown", nil))
+
+	assert.NoError(t, tracer.Shutdown(context.Background()))
+	assert.Len(t, exporter.spans, 2)
+
+	span := exporter.spans[0]
+	assert.Equal(t, "GET /receipt/:id/points", span.Name)
+	assert.Equal(t, tracing.KindServer, span.Kind)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", span.TraceID.String())
+	assert.Equal(t, "00f067aa0ba902b7", span.Parent.String())
+	assert.Equal(t, span.SpanContext, traced)
+	assert.Contains(t, span.Attributes, tracing.Attribute{Key: "http.status_code", Value: "200"})
+
+	assert.Equal(t, "GET unmatched", exporter.spans[1].Name)
+	assert.Contains(t, exporter.spans[1].Attributes, tracing.Attribute{Key: "http.status_code", Value: "404"})
+	assert.NotEqual(t, span.TraceID, exporter.spans[1].TraceID)
+}
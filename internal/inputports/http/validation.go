@@ -0,0 +1,103 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	rcp "receipt-processor-challenge/internal/domain/receipt"
+
+	"github.com/go-playground/validator/v10"
+)
+
+//nolint:gochecknoglobals
+var requestValidator = newRequestValidator()
+
+// fieldError is a failed check of the request, Field is the JSON path of the
+// field, e.g. "items[2].price".
+type fieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// requestError contains every field of the request that failed validation.
+type requestError struct {
+	fields []fieldError
+}
+
+func (re *requestError) Error() string {
+	msgs := make([]string, len(re.fields))
+
+	for i, fe := range re.fields {
+		msgs[i] = fe.Message
+	}
+
+	return strings.Join(msgs, ", ")
+}
+
+func (re *requestError) Is(target error) bool {
+	return target == ErrInvalidRequest
+}
+
+func newRequestValidator() *validator.Validate {
+	validate := validator.New()
+
+	validate.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			return ""
+		}
+
+		return name
+	})
+
+	_ = validate.RegisterValidation("money", func(fl validator.FieldLevel) bool {
+		_, err := rcp.ParseMoney(fl.Field().String())
+
+		return err == nil
+	})
+
+	return validate
+}
+
+// validate checks the whole request and returns every failed field at once.
+func validate(e interface{}) error {
+	err := requestValidator.Struct(e)
+	if err == nil {
+		return nil
+	}
+
+	var vErrs validator.ValidationErrors
+	if !errors.As(err, &vErrs) {
+		return ErrValidator
+	}
+
+	reqErr := &requestError{fields: make([]fieldError, 0, len(vErrs))}
+
+	for _, vErr := range vErrs {
+		_, field, _ := strings.Cut(vErr.Namespace(), ".")
+
+		var msg string
+
+		switch vErr.Tag() {
+		case "required":
+			msg = fmt.Sprintf("%s is required", field)
+		case "datetime":
+			msg = fmt.Sprintf("%s must use the %s format", field, vErr.Param())
+		case "money":
+			msg = fmt.Sprintf("%s must be an amount with up to two decimals", field)
+		default:
+			msg = fmt.Sprintf("%s validation error", field)
+		}
+
+		reqErr.fields = append(reqErr.fields, fieldError{
+			Field:   field,
+			Rule:    vErr.Tag(),
+			Message: msg,
+		})
+	}
+
+	return reqErr
+}
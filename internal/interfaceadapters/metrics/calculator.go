@@ -0,0 +1,60 @@
+package metrics
+
+import (
+	"receipt-processor-challenge/internal/domain/receipt"
+)
+
+// Scorer is the calculator wrapped by Calculator.
+type Scorer interface {
+	Points(r receipt.Receipt) (*receipt.Points, error)
+	PointsWithRuleSet(r receipt.Receipt, version string) (*receipt.Points, error)
+}
+
+// PointsBuckets are the upper bounds of the points histogram.
+func PointsBuckets() []float64 {
+	return []float64{0, 10, 25, 50, 75, 100, 150, 200, 300, 500, 1000}
+}
+
+/*
+Calculator records the points awarded to the new receipts and how many of them
+every rule awarded points to, a rule awarding points to several items of a
+receipt is counted once. Rescoring a receipt with PointsWithRuleSet is not
+recorded.
+*/
+type Calculator struct {
+	Scorer
+	points *Histogram
+	hits   *Counter
+}
+
+func NewCalculator(calc Scorer, registry *Registry) *Calculator {
+	return &Calculator{
+		Scorer: calc,
+		points: registry.NewHistogram("receipt_points_awarded",
+			"Points awarded to the processed receipts.", PointsBuckets()),
+		hits: registry.NewCounter("receipt_rule_hits_total",
+			"Processed receipts a rule awarded points to.", "rule"),
+	}
+}
+
+func (c *Calculator) Points(r receipt.Receipt) (*receipt.Points, error) {
+	points, err := c.Scorer.Points(r)
+	if err != nil {
+		return nil, err
+	}
+
+	c.points.Observe(float64(points.Points))
+
+	hit := map[string]bool{}
+
+	for _, award := range points.Breakdown {
+		if award.Points == 0 || hit[award.Rule] {
+			continue
+		}
+
+		hit[award.Rule] = true
+		c.hits.Inc(award.Rule)
+	}
+
+	return points, nil
+}
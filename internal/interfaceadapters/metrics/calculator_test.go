@@ -0,0 +1,63 @@
+package metrics_test
+
+import (
+	"errors"
+	"testing"
+
+	"receipt-processor-challenge/internal/domain/receipt"
+	. "receipt-processor-challenge/internal/interfaceadapters/metrics"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fixedScorer awards the same points to every receipt.
+type fixedScorer struct {
+	points *receipt.Points
+	err    error
+}
+
+func (f fixedScorer) Points(receipt.Receipt) (*receipt.Points, error) {
+	return f.points, f.err
+}
+
+func (f fixedScorer) PointsWithRuleSet(receipt.Receipt, string) (*receipt.Points, error) {
+	return f.points, f.err
+}
+
+func Test_Calculator(t *testing.T) {
+	registry := NewRegistry()
+	calc := NewCalculator(fixedScorer{points: &receipt.Points{
+		Points: 28,
+		Breakdown: []receipt.Award{
+			{Rule: "retailer-name", Points: 6},
+			{Rule: "item-description", Points: 3},
+			{Rule: "item-description", Points: 3},
+			{Rule: "odd-day", Points: 0},
+		},
+	}}, registry)
+
+	for i := 0; i < 2; i++ {
+		points, err := calc.Points(receipt.Receipt{})
+		assert.NoError(t, err)
+		assert.Equal(t, 28, points.Points)
+	}
+
+	_, err := calc.PointsWithRuleSet(receipt.Receipt{}, "v1")
+	assert.NoError(t, err)
+
+	assert.Equal(t, float64(2), counterValue(t, registry, "receipt_rule_hits_total", "retailer-name"))
+	assert.Equal(t, float64(2), counterValue(t, registry, "receipt_rule_hits_total", "item-description"))
+	assert.Equal(t, float64(0), counterValue(t, registry, "receipt_rule_hits_total", "odd-day"))
+	assert.Contains(t, expose(t, registry), "receipt_points_awarded_count 2\n")
+	assert.Contains(t, expose(t, registry), `receipt_points_awarded_bucket{le="25"} 0`)
+	assert.Contains(t, expose(t, registry), `receipt_points_awarded_bucket{le="50"} 2`)
+}
+
+func Test_CalculatorError(t *testing.T) {
+	registry := NewRegistry()
+	calc := NewCalculator(fixedScorer{err: errors.New("invalid rule set")}, registry)
+
+	_, err := calc.Points(receipt.Receipt{})
+	assert.Error(t, err)
+	assert.Contains(t, expose(t, registry), "receipt_points_awarded_count 0\n")
+}
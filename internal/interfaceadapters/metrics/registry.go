@@ -0,0 +1,339 @@
+/*
+Package metrics keeps the counters and histograms of the service and writes
+them in the Prometheus text exposition format.
+*/
+package metrics
+
+import (
+	"bufio"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ContentType is the content type of the text exposition format.
+const ContentType string = "text/plain; version=0.0.4; charset=utf-8"
+
+const (
+	typeCounter   string = "counter"
+	typeGauge     string = "gauge"
+	typeHistogram string = "histogram"
+
+	// labelSeparator joins the label values of a series into its key, it can
+	// not be part of a valid UTF-8 value.
+	labelSeparator string = "\xff"
+)
+
+// DefaultBuckets are the upper bounds of the latency histograms, in seconds.
+func DefaultBuckets() []float64 {
+	return []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+}
+
+type metric interface {
+	write(w *bufio.Writer)
+}
+
+// Registry keeps the metrics in the order they were created. Names must be
+// unique and follow the Prometheus naming rules, they are not checked.
+type Registry struct {
+	mu      sync.Mutex
+	metrics []metric
+}
+
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+func (r *Registry) add(m metric) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.metrics = append(r.metrics, m)
+}
+
+// Expose writes every metric to w in the text exposition format.
+func (r *Registry) Expose(w io.Writer) error {
+	r.mu.Lock()
+	metrics := append([]metric(nil), r.metrics...)
+	r.mu.Unlock()
+
+	buf := bufio.NewWriter(w)
+
+	for _, m := range metrics {
+		m.write(buf)
+	}
+
+	return buf.Flush()
+}
+
+// NewCounter creates a counter with one series per combination of values of
+// the labels.
+func (r *Registry) NewCounter(name, help string, labels ...string) *Counter {
+	c := &Counter{desc: desc{name: name, help: help, labels: labels}, series: map[string]*counterSeries{}}
+	if len(labels) == 0 {
+		c.get(nil)
+	}
+
+	r.add(c)
+
+	return c
+}
+
+/*
+NewHistogram creates a histogram with one series per combination of values of
+the labels, buckets are the sorted upper bounds of the buckets and the +Inf
+bucket is added to them.
+*/
+func (r *Registry) NewHistogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	h := &Histogram{
+		desc:    desc{name: name, help: help, labels: labels},
+		buckets: buckets,
+		series:  map[string]*histogramSeries{},
+	}
+	if len(labels) == 0 {
+		h.get(nil)
+	}
+
+	r.add(h)
+
+	return h
+}
+
+// NewCounterFunc creates a counter whose value is read from value when the
+// metrics are written, value must never decrease.
+func (r *Registry) NewCounterFunc(name, help string, value func() float64) {
+	r.add(&valueFunc{desc: desc{name: name, help: help}, kind: typeCounter, value: value})
+}
+
+// NewGaugeFunc creates a gauge whose value is read from value when the metrics
+// are written.
+func (r *Registry) NewGaugeFunc(name, help string, value func() float64) {
+	r.add(&valueFunc{desc: desc{name: name, help: help}, kind: typeGauge, value: value})
+}
+
+type desc struct {
+	name   string
+	help   string
+	labels []string
+}
+
+func (d desc) header(w *bufio.Writer, kind string) {
+	w.WriteString("# HELP " + d.name + " " + escapeHelp(d.help) + "\n")
+	w.WriteString("# TYPE " + d.name + " " + kind + "\n")
+}
+
+// sample writes a line of the metric, extra is an additional label such as the
+// bucket bound of a histogram.
+func (d desc) sample(w *bufio.Writer, suffix string, values []string, extra string, value float64) {
+	w.WriteString(d.name + suffix)
+
+	pairs := []string{}
+
+	for i, label := range d.labels {
+		v := ""
+		if i < len(values) {
+			v = values[i]
+		}
+
+		pairs = append(pairs, label+`="`+escapeLabel(v)+`"`)
+	}
+
+	if extra != "" {
+		pairs = append(pairs, extra)
+	}
+
+	if len(pairs) > 0 {
+		w.WriteString("{" + strings.Join(pairs, ",") + "}")
+	}
+
+	w.WriteString(" " + formatFloat(value) + "\n")
+}
+
+// Counter is a value that only increases.
+type Counter struct {
+	desc
+	mu     sync.Mutex
+	series map[string]*counterSeries
+}
+
+type counterSeries struct {
+	values []string
+	value  float64
+}
+
+// Inc adds one to the series of the label values, given in the order of the
+// labels.
+func (c *Counter) Inc(values ...string) {
+	c.Add(1, values...)
+}
+
+// Add adds delta to the series of the label values, negative deltas are
+// ignored.
+func (c *Counter) Add(delta float64, values ...string) {
+	if delta < 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.get(values).value += delta
+}
+
+// Value returns the value of the series of the label values.
+func (c *Counter) Value(values ...string) float64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if s, ok := c.series[strings.Join(values, labelSeparator)]; ok {
+		return s.value
+	}
+
+	return 0
+}
+
+func (c *Counter) get(values []string) *counterSeries {
+	key := strings.Join(values, labelSeparator)
+
+	s, ok := c.series[key]
+	if !ok {
+		s = &counterSeries{values: append([]string(nil), values...)}
+		c.series[key] = s
+	}
+
+	return s
+}
+
+func (c *Counter) write(w *bufio.Writer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.header(w, typeCounter)
+
+	for _, key := range sortedKeys(c.series) {
+		s := c.series[key]
+		c.sample(w, "", s.values, "", s.value)
+	}
+}
+
+// Histogram counts observed values in buckets.
+type Histogram struct {
+	desc
+	buckets []float64
+	mu      sync.Mutex
+	series  map[string]*histogramSeries
+}
+
+type histogramSeries struct {
+	values []string
+	// counts are the observations of every bucket, not cumulative.
+	counts []uint64
+	count  uint64
+	sum    float64
+}
+
+// Observe adds v to the series of the label values, given in the order of the
+// labels.
+func (h *Histogram) Observe(v float64, values ...string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	s := h.get(values)
+	if i := sort.SearchFloat64s(h.buckets, v); i < len(h.buckets) {
+		s.counts[i]++
+	}
+
+	s.count++
+	s.sum += v
+}
+
+// Count returns the number of observations of the series of the label values.
+func (h *Histogram) Count(values ...string) uint64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if s, ok := h.series[strings.Join(values, labelSeparator)]; ok {
+		return s.count
+	}
+
+	return 0
+}
+
+func (h *Histogram) get(values []string) *histogramSeries {
+	key := strings.Join(values, labelSeparator)
+
+	s, ok := h.series[key]
+	if !ok {
+		s = &histogramSeries{values: append([]string(nil), values...), counts: make([]uint64, len(h.buckets))}
+		h.series[key] = s
+	}
+
+	return s
+}
+
+func (h *Histogram) write(w *bufio.Writer) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.header(w, typeHistogram)
+
+	for _, key := range sortedKeys(h.series) {
+		s := h.series[key]
+		cumulative := uint64(0)
+
+		for i, bound := range h.buckets {
+			cumulative += s.counts[i]
+			h.sample(w, "_bucket", s.values, `le="`+formatFloat(bound)+`"`, float64(cumulative))
+		}
+
+		h.sample(w, "_bucket", s.values, `le="+Inf"`, float64(s.count))
+		h.sample(w, "_sum", s.values, "", s.sum)
+		h.sample(w, "_count", s.values, "", float64(s.count))
+	}
+}
+
+type valueFunc struct {
+	desc
+	kind  string
+	value func() float64
+}
+
+func (f *valueFunc) write(w *bufio.Writer) {
+	f.header(w, f.kind)
+	f.sample(w, "", nil, "", f.value())
+}
+
+func sortedKeys[T any](series map[string]T) []string {
+	keys := make([]string, 0, len(series))
+	for key := range series {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	default:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	}
+}
+
+func escapeHelp(help string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(help)
+}
+
+func escapeLabel(value string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`).Replace(value)
+}
@@ -0,0 +1,75 @@
+package metrics_test
+
+import (
+	"bytes"
+	"math"
+	"testing"
+
+	. "receipt-processor-challenge/internal/interfaceadapters/metrics"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Expose(t *testing.T) {
+	registry := NewRegistry()
+
+	requests := registry.NewCounter("requests_total", "Handled requests.", "route", "code")
+	requests.Inc("/b", "200")
+	requests.Inc("/a", "500")
+	requests.Add(2, "/a", "500")
+	requests.Add(-1, "/a", "500")
+
+	registry.NewCounter("errors_total", "Failures\nof everything.")
+
+	latency := registry.NewHistogram("latency_seconds", "Latency.", []float64{0.1, 1}, "route")
+	latency.Observe(0.05, `/"quoted"`)
+	latency.Observe(0.5, `/"quoted"`)
+	latency.Observe(2, `/"quoted"`)
+
+	registry.NewGaugeFunc("entries", "Stored entries.", func() float64 { return 3 })
+	registry.NewCounterFunc("infinite_total", "Infinite.", func() float64 { return math.Inf(1) })
+
+	out := bytes.Buffer{}
+	assert.NoError(t, registry.Expose(&out))
+	assert.Equal(t, `# HELP requests_total Handled requests.
+# TYPE requests_total counter
+requests_total{route="/a",code="500"} 3
+requests_total{route="/b",code="200"} 1
+# HELP errors_total Failures\nof everything.
+# TYPE errors_total counter
+errors_total 0
+# HELP latency_seconds Latency.
+# TYPE latency_seconds histogram
+latency_seconds_bucket{route="/\"quoted\"",le="0.1"} 1
+latency_seconds_bucket{route="/\"quoted\"",le="1"} 2
+latency_seconds_bucket{route="/\"quoted\"",le="+Inf"} 3
+latency_seconds_sum{route="/\"quoted\""} 2.55
+latency_seconds_count{route="/\"quoted\""} 3
+# HELP entries Stored entries.
+# TYPE entries gauge
+entries 3
+# HELP infinite_total Infinite.
+# TYPE infinite_total counter
+infinite_total +Inf
+`, out.String())
+
+	assert.Equal(t, float64(3), requests.Value("/a", "500"))
+	assert.Equal(t, float64(0), requests.Value("/c", "200"))
+	assert.Equal(t, uint64(3), latency.Count(`/"quoted"`))
+	assert.Equal(t, uint64(0), latency.Count("/c"))
+}
+
+func Test_ExposeEmptyHistogram(t *testing.T) {
+	registry := NewRegistry()
+	registry.NewHistogram("points", "Points.", []float64{10})
+
+	out := bytes.Buffer{}
+	assert.NoError(t, registry.Expose(&out))
+	assert.Equal(t, `# HELP points Points.
+# TYPE points histogram
+points_bucket{le="10"} 0
+points_bucket{le="+Inf"} 0
+points_sum 0
+points_count 0
+`, out.String())
+}
@@ -0,0 +1,134 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"receipt-processor-challenge/internal/domain/receipt"
+
+	"github.com/google/uuid"
+)
+
+const (
+	opSave         string = "save"
+	opGet          string = "get"
+	opGetReceipt   string = "get_receipt"
+	opFindByKey    string = "find_by_key"
+	opFindMatching string = "find_matching"
+	opFlagged      string = "flagged"
+	opPing         string = "ping"
+)
+
+/*
+Repository records the latency, the errors and the time outs of every
+operation of the repository it wraps. An operation times out when its error is
+context.DeadlineExceeded or reports Timeout() like the net errors do. Not found,
+evicted and duplicated receipts are answers, not errors.
+*/
+type Repository struct {
+	receipt.Repository
+	latency  *Histogram
+	errors   *Counter
+	timeOuts *Counter
+}
+
+func NewRepository(repo receipt.Repository, registry *Registry) *Repository {
+	return &Repository{
+		Repository: repo,
+		latency: registry.NewHistogram("receipt_storage_operation_duration_seconds",
+			"Latency of the storage operations.", DefaultBuckets(), "operation"),
+		errors: registry.NewCounter("receipt_storage_operation_errors_total",
+			"Storage operations that failed, time outs included.", "operation"),
+		timeOuts: registry.NewCounter("receipt_storage_operation_timeouts_total",
+			"Storage operations that timed out.", "operation"),
+	}
+}
+
+func (r *Repository) Save(
+	ctx context.Context, rcpt receipt.Receipt, points receipt.Points, keys ...string,
+) (uuid.UUID, error) {
+	defer r.observe(opSave, time.Now())
+
+	id, err := r.Repository.Save(ctx, rcpt, points, keys...)
+	r.fail(opSave, err)
+
+	return id, err
+}
+
+func (r *Repository) Get(ctx context.Context, id uuid.UUID) (*receipt.Points, error) {
+	defer r.observe(opGet, time.Now())
+
+	points, err := r.Repository.Get(ctx, id)
+	r.fail(opGet, err)
+
+	return points, err
+}
+
+func (r *Repository) GetReceipt(ctx context.Context, id uuid.UUID) (*receipt.Receipt, error) {
+	defer r.observe(opGetReceipt, time.Now())
+
+	rcpt, err := r.Repository.GetReceipt(ctx, id)
+	r.fail(opGetReceipt, err)
+
+	return rcpt, err
+}
+
+func (r *Repository) FindByKey(ctx context.Context, key string) (uuid.UUID, error) {
+	defer r.observe(opFindByKey, time.Now())
+
+	id, err := r.Repository.FindByKey(ctx, key)
+	r.fail(opFindByKey, err)
+
+	return id, err
+}
+
+func (r *Repository) FindMatching(ctx context.Context, rcpt receipt.Receipt) ([]uuid.UUID, error) {
+	defer r.observe(opFindMatching, time.Now())
+
+	ids, err := r.Repository.FindMatching(ctx, rcpt)
+	r.fail(opFindMatching, err)
+
+	return ids, err
+}
+
+func (r *Repository) Flagged(ctx context.Context) ([]receipt.FlaggedReceipt, error) {
+	defer r.observe(opFlagged, time.Now())
+
+	flagged, err := r.Repository.Flagged(ctx)
+	r.fail(opFlagged, err)
+
+	return flagged, err
+}
+
+func (r *Repository) Ping(ctx context.Context) error {
+	defer r.observe(opPing, time.Now())
+
+	err := r.Repository.Ping(ctx)
+	r.fail(opPing, err)
+
+	return err
+}
+
+func (r *Repository) observe(operation string, start time.Time) {
+	r.latency.Observe(time.Since(start).Seconds(), operation)
+}
+
+func (r *Repository) fail(operation string, err error) {
+	if err == nil || errors.Is(err, receipt.ErrNotFound) || errors.Is(err, receipt.ErrEvicted) ||
+		errors.Is(err, receipt.ErrDuplicated) {
+		return
+	}
+
+	r.errors.Inc(operation)
+
+	if timedOut(err) {
+		r.timeOuts.Inc(operation)
+	}
+}
+
+func timedOut(err error) bool {
+	var timeOut interface{ Timeout() bool }
+
+	return errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &timeOut) && timeOut.Timeout())
+}
@@ -0,0 +1,130 @@
+package metrics_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"receipt-processor-challenge/internal/domain/receipt"
+	. "receipt-processor-challenge/internal/interfaceadapters/metrics"
+	"receipt-processor-challenge/internal/interfaceadapters/storage/memory"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+// failingRepository fails every read with err.
+type failingRepository struct {
+	receipt.Repository
+	err error
+}
+
+func (f failingRepository) Get(context.Context, uuid.UUID) (*receipt.Points, error) {
+	return nil, f.err
+}
+
+func Test_Repository(t *testing.T) {
+	registry := NewRegistry()
+	engine := memory.New(context.Background())
+	t.Cleanup(func() { engine.Close() })
+
+	repo := NewRepository(engine, registry)
+
+	id, err := repo.Save(context.Background(), receipt.Receipt{Retailer: "Target"}, receipt.Points{Points: 6}, "key")
+	assert.NoError(t, err)
+
+	_, err = repo.Save(context.Background(), receipt.Receipt{Retailer: "Target"}, receipt.Points{Points: 6}, "key")
+	assert.ErrorIs(t, err, receipt.ErrDuplicated)
+
+	_, err = repo.Get(context.Background(), id)
+	assert.NoError(t, err)
+
+	_, err = repo.Get(context.Background(), uuid.New())
+	assert.ErrorIs(t, err, receipt.ErrNotFound)
+
+	// the memory engine times out the reads whose context is done.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err = repo.Get(ctx, id)
+	assert.Error(t, err)
+
+	out := expose(t, registry)
+	assert.Contains(t, out, `receipt_storage_operation_duration_seconds_count{operation="save"} 2`)
+	assert.Contains(t, out, `receipt_storage_operation_duration_seconds_count{operation="get"} 3`)
+	assert.Equal(t, float64(0), counterValue(t, registry, "receipt_storage_operation_errors_total", "save"))
+	assert.Equal(t, float64(1), counterValue(t, registry, "receipt_storage_operation_errors_total", "get"))
+	assert.Equal(t, float64(1), counterValue(t, registry, "receipt_storage_operation_timeouts_total", "get"))
+}
+
+func Test_RepositoryErrors(t *testing.T) {
+	cases := []struct {
+		name             string
+		err              error
+		expectedErrors   float64
+		expectedTimeOuts float64
+	}{
+		{
+			name:             "deadline-case",
+			err:              context.DeadlineExceeded,
+			expectedErrors:   1,
+			expectedTimeOuts: 1,
+		},
+		{
+			name:             "failure-case",
+			err:              errors.New("connection refused"),
+			expectedErrors:   1,
+			expectedTimeOuts: 0,
+		},
+		{
+			name:             "evicted-case",
+			err:              receipt.ErrEvicted,
+			expectedErrors:   0,
+			expectedTimeOuts: 0,
+		},
+	}
+
+	for _, c := range cases {
+		c := c
+
+		t.Run(c.name, func(t *testing.T) {
+			registry := NewRegistry()
+			repo := NewRepository(failingRepository{err: c.err}, registry)
+
+			_, err := repo.Get(context.Background(), uuid.New())
+			assert.ErrorIs(t, err, c.err)
+			assert.Equal(t, c.expectedErrors, counterValue(t, registry, "receipt_storage_operation_errors_total", "get"))
+			assert.Equal(t, c.expectedTimeOuts, counterValue(t, registry, "receipt_storage_operation_timeouts_total", "get"))
+		})
+	}
+}
+
+func expose(t *testing.T, registry *Registry) string {
+	t.Helper()
+
+	out := bytes.Buffer{}
+	assert.NoError(t, registry.Expose(&out))
+
+	return out.String()
+}
+
+// counterValue reads the value of a series with a single label from the
+// exposition, zero when it is missing.
+func counterValue(t *testing.T, registry *Registry, name, label string) float64 {
+	t.Helper()
+
+	for _, line := range strings.Split(expose(t, registry), "\n") {
+		if strings.HasPrefix(line, name+`{`) && strings.Contains(line, `="`+label+`"}`) {
+			value := 0.0
+			_, err := fmt.Sscan(line[strings.LastIndex(line, " ")+1:], &value)
+			assert.NoError(t, err)
+
+			return value
+		}
+	}
+
+	return 0
+}
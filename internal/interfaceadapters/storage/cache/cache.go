@@ -0,0 +1,215 @@
+/*
+Package cache keeps the points and receipts read from a repository in a cache
+backend so repeated reads do not reach the repository. Stored receipts never
+change, so entries are only dropped when their time to live expires or the
+backend evicts them.
+*/
+package cache
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"errors"
+	"io"
+	"sync/atomic"
+	"time"
+
+	"receipt-processor-challenge/internal/domain/receipt"
+
+	"github.com/google/uuid"
+)
+
+const (
+	pointsKeyPrefix  string = "points:"
+	receiptKeyPrefix string = "receipt:"
+
+	DefaultTTL time.Duration = 5 * time.Minute
+)
+
+// Backend stores values by key for a limited time. Get reports false when the
+// key is not stored or has expired.
+type Backend interface {
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+}
+
+// Stats are the cache counters since the repository was created.
+type Stats struct {
+	Hits   uint64
+	Misses uint64
+	// Errors counts the backend failures, the repository is read instead.
+	Errors uint64
+}
+
+// Repository is a receipt.Repository that reads points and receipts through
+// the cache backend before reaching the wrapped repository.
+type Repository struct {
+	repo    receipt.Repository
+	backend Backend
+	ttl     time.Duration
+
+	hits   atomic.Uint64
+	misses atomic.Uint64
+	errors atomic.Uint64
+}
+
+// New wraps repo with the cache backend, entries live for ttl (DefaultTTL when
+// it is not positive).
+func New(repo receipt.Repository, backend Backend, ttl time.Duration) *Repository {
+	if ttl <= 0 {
+		ttl = DefaultTTL
+	}
+
+	return &Repository{
+		repo:    repo,
+		backend: backend,
+		ttl:     ttl,
+	}
+}
+
+// Stats returns the hit, miss and error counters.
+func (r *Repository) Stats() Stats {
+	return Stats{
+		Hits:   r.hits.Load(),
+		Misses: r.misses.Load(),
+		Errors: r.errors.Load(),
+	}
+}
+
+// Save stores the receipt in the wrapped repository and primes the cache with
+// it, a receipt is usually read right after it is submitted.
+func (r *Repository) Save(
+	ctx context.Context, rcpt receipt.Receipt, points receipt.Points, keys ...string,
+) (uuid.UUID, error) {
+	id, err := r.repo.Save(ctx, rcpt, points, keys...)
+	if err != nil {
+		return id, err
+	}
+
+	r.set(ctx, pointsKeyPrefix+id.String(), points)
+	r.set(ctx, receiptKeyPrefix+id.String(), rcpt)
+
+	return id, nil
+}
+
+func (r *Repository) Get(ctx context.Context, id uuid.UUID) (*receipt.Points, error) {
+	points := &receipt.Points{}
+	if r.get(ctx, pointsKeyPrefix+id.String(), points) {
+		return points, nil
+	}
+
+	points, err := r.repo.Get(ctx, id)
+	if err != nil || points == nil {
+		return points, err
+	}
+
+	r.set(ctx, pointsKeyPrefix+id.String(), *points)
+
+	return points, nil
+}
+
+func (r *Repository) GetReceipt(ctx context.Context, id uuid.UUID) (*receipt.Receipt, error) {
+	rcpt := &receipt.Receipt{}
+	if r.get(ctx, receiptKeyPrefix+id.String(), rcpt) {
+		return rcpt, nil
+	}
+
+	rcpt, err := r.repo.GetReceipt(ctx, id)
+	if err != nil || rcpt == nil {
+		return rcpt, err
+	}
+
+	r.set(ctx, receiptKeyPrefix+id.String(), *rcpt)
+
+	return rcpt, nil
+}
+
+// FindByKey is not cached, a key is only looked up before its receipt is saved.
+func (r *Repository) FindByKey(ctx context.Context, key string) (uuid.UUID, error) {
+	return r.repo.FindByKey(ctx, key)
+}
+
+// FindMatching is not cached, the matching receipts change with every save.
+func (r *Repository) FindMatching(ctx context.Context, rcpt receipt.Receipt) ([]uuid.UUID, error) {
+	return r.repo.FindMatching(ctx, rcpt)
+}
+
+// Flagged is not cached, the flagged receipts change with every save.
+func (r *Repository) Flagged(ctx context.Context) ([]receipt.FlaggedReceipt, error) {
+	return r.repo.Flagged(ctx)
+}
+
+// Ping checks the wrapped repository, the cache is checked by PingCache.
+func (r *Repository) Ping(ctx context.Context) error {
+	return r.repo.Ping(ctx)
+}
+
+// Pinger is implemented by the backends that can be checked.
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// PingCache checks the backend when it can be checked, a failing cache does
+// not stop the repository from serving requests.
+func (r *Repository) PingCache(ctx context.Context) error {
+	if pinger, ok := r.backend.(Pinger); ok {
+		return pinger.Ping(ctx)
+	}
+
+	return ctx.Err()
+}
+
+// Close closes the backend and the wrapped repository when they hold resources.
+func (r *Repository) Close() error {
+	var errs []error
+
+	if closer, ok := r.backend.(io.Closer); ok {
+		errs = append(errs, closer.Close())
+	}
+
+	if closer, ok := r.repo.(io.Closer); ok {
+		errs = append(errs, closer.Close())
+	}
+
+	return errors.Join(errs...)
+}
+
+// get decodes the cached value of key into value and reports whether it was
+// found.
+func (r *Repository) get(ctx context.Context, key string, value any) bool {
+	data, ok, err := r.backend.Get(ctx, key)
+	if err == nil && ok {
+		err = gob.NewDecoder(bytes.NewReader(data)).Decode(value)
+	}
+
+	switch {
+	case err != nil:
+		r.errors.Add(1)
+		r.misses.Add(1)
+
+		return false
+	case !ok:
+		r.misses.Add(1)
+
+		return false
+	}
+
+	r.hits.Add(1)
+
+	return true
+}
+
+func (r *Repository) set(ctx context.Context, key string, value any) {
+	buf := bytes.Buffer{}
+
+	if err := gob.NewEncoder(&buf).Encode(value); err != nil {
+		r.errors.Add(1)
+
+		return
+	}
+
+	if err := r.backend.Set(ctx, key, buf.Bytes(), r.ttl); err != nil {
+		r.errors.Add(1)
+	}
+}
@@ -0,0 +1,139 @@
+package cache_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"receipt-processor-challenge/internal/domain/receipt"
+	. "receipt-processor-challenge/internal/interfaceadapters/storage/cache"
+	"receipt-processor-challenge/internal/interfaceadapters/storage/memory"
+	"receipt-processor-challenge/internal/interfaceadapters/storage/storagetest"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+// countingRepository counts the reads that reach the repository.
+type countingRepository struct {
+	receipt.Repository
+	gets        int
+	receiptGets int
+}
+
+func (c *countingRepository) Get(ctx context.Context, id uuid.UUID) (*receipt.Points, error) {
+	c.gets++
+
+	return c.Repository.Get(ctx, id)
+}
+
+func (c *countingRepository) GetReceipt(ctx context.Context, id uuid.UUID) (*receipt.Receipt, error) {
+	c.receiptGets++
+
+	return c.Repository.GetReceipt(ctx, id)
+}
+
+// failingBackend fails every operation.
+type failingBackend struct{}
+
+func (failingBackend) Get(context.Context, string) ([]byte, bool, error) {
+	return nil, false, errors.New("connection refused")
+}
+
+func (failingBackend) Set(context.Context, string, []byte, time.Duration) error {
+	return errors.New("connection refused")
+}
+
+func newReceipt() receipt.Receipt {
+	return receipt.Receipt{
+		Retailer:     "Target",
+		PurchaseDate: time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC),
+		PurchaseTime: time.Date(0, 1, 1, 13, 1, 0, 0, time.UTC),
+		Items:        []receipt.Item{{ShortDescription: "Mountain Dew 12PK", Price: 649}},
+		Total:        649,
+	}
+}
+
+func newPoints() receipt.Points {
+	return receipt.Points{
+		Points:         6,
+		RuleSetVersion: "v1",
+		Breakdown:      []receipt.Award{{Rule: "retailer-name", Description: "retailer", Points: 6}},
+	}
+}
+
+func Test_Repository(t *testing.T) {
+	ctx := context.Background()
+	inner := &countingRepository{Repository: memory.New(ctx)}
+	repo := New(inner, NewLRU(10), time.Minute)
+
+	id, err := repo.Save(ctx, newReceipt(), newPoints())
+	assert.NoError(t, err)
+
+	// the saved receipt is already cached.
+	for i := 0; i < 3; i++ {
+		points, err := repo.Get(ctx, id)
+		assert.NoError(t, err)
+		assert.Equal(t, newPoints(), *points)
+
+		rcpt, err := repo.GetReceipt(ctx, id)
+		assert.NoError(t, err)
+		assert.Equal(t, newReceipt(), *rcpt)
+	}
+
+	assert.Zero(t, inner.gets)
+	assert.Zero(t, inner.receiptGets)
+	assert.Equal(t, Stats{Hits: 6}, repo.Stats())
+
+	// not found is returned by the repository and not cached.
+	_, err = repo.Get(ctx, uuid.New())
+	assert.ErrorIs(t, err, receipt.ErrNotFound)
+	assert.Equal(t, 1, inner.gets)
+	assert.Equal(t, Stats{Hits: 6, Misses: 1}, repo.Stats())
+}
+
+func Test_RepositoryConformance(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) receipt.Repository {
+		inner := memory.New(context.Background())
+		t.Cleanup(func() { inner.Close() })
+
+		return New(inner, NewLRU(10), time.Minute)
+	})
+}
+
+func Test_RepositoryMiss(t *testing.T) {
+	ctx := context.Background()
+	inner := &countingRepository{Repository: memory.New(ctx)}
+
+	id, err := inner.Save(ctx, newReceipt(), newPoints())
+	assert.NoError(t, err)
+
+	repo := New(inner, NewLRU(10), time.Minute)
+
+	for i := 0; i < 3; i++ {
+		points, err := repo.Get(ctx, id)
+		assert.NoError(t, err)
+		assert.Equal(t, newPoints(), *points)
+	}
+
+	assert.Equal(t, 1, inner.gets)
+	assert.Equal(t, Stats{Hits: 2, Misses: 1}, repo.Stats())
+}
+
+func Test_RepositoryBackendErrors(t *testing.T) {
+	ctx := context.Background()
+	inner := &countingRepository{Repository: memory.New(ctx)}
+	repo := New(inner, failingBackend{}, time.Minute)
+
+	id, err := repo.Save(ctx, newReceipt(), newPoints())
+	assert.NoError(t, err)
+
+	points, err := repo.Get(ctx, id)
+	assert.NoError(t, err)
+	assert.Equal(t, newPoints(), *points)
+	assert.Equal(t, 1, inner.gets)
+
+	// two failed sets on save, a failed get and a failed set on read.
+	assert.Equal(t, Stats{Misses: 1, Errors: 4}, repo.Stats())
+}
@@ -0,0 +1,101 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+const DefaultCapacity int = 10_000
+
+// LRU is an in-process Backend that keeps up to capacity entries, the least
+// recently used entry is evicted to make room for a new one.
+type LRU struct {
+	capacity int
+	now      func() time.Time
+
+	mu      sync.Mutex
+	order   *list.List
+	entries map[string]*list.Element
+}
+
+type lruEntry struct {
+	key       string
+	value     []byte
+	expiresAt time.Time
+}
+
+// NewLRU returns an empty LRU backend, DefaultCapacity is used when capacity is
+// not positive.
+func NewLRU(capacity int) *LRU {
+	if capacity <= 0 {
+		capacity = DefaultCapacity
+	}
+
+	return &LRU{
+		capacity: capacity,
+		now:      time.Now,
+		order:    list.New(),
+		entries:  make(map[string]*list.Element, capacity),
+	}
+}
+
+func (l *LRU) Get(_ context.Context, key string) ([]byte, bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	elem, ok := l.entries[key]
+	if !ok {
+		return nil, false, nil
+	}
+
+	entry, _ := elem.Value.(*lruEntry)
+	if !l.now().Before(entry.expiresAt) {
+		l.remove(elem)
+
+		return nil, false, nil
+	}
+
+	l.order.MoveToFront(elem)
+
+	return entry.value, true, nil
+}
+
+func (l *LRU) Set(_ context.Context, key string, value []byte, ttl time.Duration) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	expiresAt := l.now().Add(ttl)
+
+	if elem, ok := l.entries[key]; ok {
+		entry, _ := elem.Value.(*lruEntry)
+		entry.value = value
+		entry.expiresAt = expiresAt
+		l.order.MoveToFront(elem)
+
+		return nil
+	}
+
+	for l.order.Len() >= l.capacity {
+		l.remove(l.order.Back())
+	}
+
+	l.entries[key] = l.order.PushFront(&lruEntry{key: key, value: value, expiresAt: expiresAt})
+
+	return nil
+}
+
+// Len returns the number of entries, expired ones included until they are
+// read or evicted.
+func (l *LRU) Len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.order.Len()
+}
+
+func (l *LRU) remove(elem *list.Element) {
+	entry, _ := l.order.Remove(elem).(*lruEntry)
+	delete(l.entries, entry.key)
+}
@@ -0,0 +1,56 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_LRU(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	lru := NewLRU(2)
+	lru.now = func() time.Time { return now }
+
+	assert.NoError(t, lru.Set(ctx, "a", []byte("1"), time.Minute))
+	assert.NoError(t, lru.Set(ctx, "b", []byte("2"), time.Minute))
+
+	// reading a makes b the least recently used entry.
+	value, ok, err := lru.Get(ctx, "a")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, []byte("1"), value)
+
+	assert.NoError(t, lru.Set(ctx, "c", []byte("3"), time.Second))
+	assert.Equal(t, 2, lru.Len())
+
+	_, ok, _ = lru.Get(ctx, "b")
+	assert.False(t, ok)
+
+	_, ok, _ = lru.Get(ctx, "c")
+	assert.True(t, ok)
+
+	// c expires before a.
+	now = now.Add(2 * time.Second)
+
+	_, ok, _ = lru.Get(ctx, "c")
+	assert.False(t, ok)
+	assert.Equal(t, 1, lru.Len())
+
+	_, ok, _ = lru.Get(ctx, "a")
+	assert.True(t, ok)
+
+	// setting an existing key replaces its value and time to live.
+	assert.NoError(t, lru.Set(ctx, "a", []byte("4"), time.Second))
+
+	value, _, _ = lru.Get(ctx, "a")
+	assert.Equal(t, []byte("4"), value)
+
+	now = now.Add(time.Second)
+
+	_, ok, _ = lru.Get(ctx, "a")
+	assert.False(t, ok)
+}
@@ -0,0 +1,278 @@
+package cache
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultPoolSize int = 8
+
+	dialTimeOut time.Duration = time.Second
+)
+
+var (
+	ErrRedis       = errors.New("redis error")
+	ErrRedisClosed = errors.New("redis client closed")
+	errProtocol    = errors.New("redis protocol error")
+)
+
+// Redis is a Backend that talks the RESP protocol to a Redis compatible server.
+// It keeps a small pool of connections, a connection with a failed command is
+// discarded.
+type Redis struct {
+	addr string
+	pool chan *redisConn
+
+	mu     sync.Mutex
+	closed bool
+}
+
+type redisConn struct {
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+// NewRedis returns a client for the server in addr ("host:port"), connections
+// are opened on demand and up to poolSize (DefaultPoolSize when it is not
+// positive) are kept open.
+func NewRedis(addr string, poolSize int) *Redis {
+	if poolSize <= 0 {
+		poolSize = DefaultPoolSize
+	}
+
+	return &Redis{
+		addr: addr,
+		pool: make(chan *redisConn, poolSize),
+	}
+}
+
+func (r *Redis) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	reply, err := r.do(ctx, "GET", key)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if reply == nil {
+		return nil, false, nil
+	}
+
+	value, ok := reply.([]byte)
+	if !ok {
+		return nil, false, fmt.Errorf("GET reply %T:%w", reply, errProtocol)
+	}
+
+	return value, true, nil
+}
+
+func (r *Redis) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	_, err := r.do(ctx, "SET", key, string(value), "PX", strconv.FormatInt(ttl.Milliseconds(), 10))
+
+	return err
+}
+
+// Ping checks that the server answers.
+func (r *Redis) Ping(ctx context.Context) error {
+	_, err := r.do(ctx, "PING")
+
+	return err
+}
+
+// Close closes the pooled connections, the client can not be used afterwards.
+func (r *Redis) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.closed {
+		return nil
+	}
+
+	r.closed = true
+	close(r.pool)
+
+	var errs []error
+	for c := range r.pool {
+		errs = append(errs, c.conn.Close())
+	}
+
+	return errors.Join(errs...)
+}
+
+// do sends a command and returns its reply: nil, string, int64, []byte or
+// []any. Error replies are returned wrapping ErrRedis.
+func (r *Redis) do(ctx context.Context, args ...string) (any, error) {
+	c, err := r.conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Time{}
+	}
+
+	if err := c.conn.SetDeadline(deadline); err != nil {
+		c.conn.Close()
+
+		return nil, err
+	}
+
+	// a canceled context interrupts the command by expiring the deadline.
+	done := make(chan struct{})
+	interrupted := make(chan bool, 1)
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			_ = c.conn.SetDeadline(time.Unix(1, 0))
+			interrupted <- true
+		case <-done:
+			interrupted <- false
+		}
+	}()
+
+	reply, err := c.command(args)
+
+	close(done)
+
+	if <-interrupted || (err != nil && !errors.Is(err, ErrRedis)) {
+		c.conn.Close()
+
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
+		return nil, err
+	}
+
+	r.release(c)
+
+	return reply, err
+}
+
+func (r *Redis) conn(ctx context.Context) (*redisConn, error) {
+	r.mu.Lock()
+	closed := r.closed
+	r.mu.Unlock()
+
+	if closed {
+		return nil, ErrRedisClosed
+	}
+
+	select {
+	case c, ok := <-r.pool:
+		if ok {
+			return c, nil
+		}
+
+		return nil, ErrRedisClosed
+	default:
+	}
+
+	dialer := net.Dialer{Timeout: dialTimeOut}
+
+	conn, err := dialer.DialContext(ctx, "tcp", r.addr)
+	if err != nil {
+		return nil, err
+	}
+
+	return &redisConn{conn: conn, reader: bufio.NewReader(conn)}, nil
+}
+
+// release returns the connection to the pool, or closes it when the pool is
+// full or the client closed.
+func (r *Redis) release(c *redisConn) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.closed {
+		c.conn.Close()
+
+		return
+	}
+
+	select {
+	case r.pool <- c:
+	default:
+		c.conn.Close()
+	}
+}
+
+func (c *redisConn) command(args []string) (any, error) {
+	buf := make([]byte, 0, 64)
+	buf = append(buf, '*')
+	buf = strconv.AppendInt(buf, int64(len(args)), 10)
+	buf = append(buf, '\r', '\n')
+
+	for _, arg := range args {
+		buf = append(buf, '$')
+		buf = strconv.AppendInt(buf, int64(len(arg)), 10)
+		buf = append(buf, '\r', '\n')
+		buf = append(buf, arg...)
+		buf = append(buf, '\r', '\n')
+	}
+
+	if _, err := c.conn.Write(buf); err != nil {
+		return nil, err
+	}
+
+	return readReply(c.reader)
+}
+
+func readReply(r *bufio.Reader) (any, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+
+	if len(line) < 3 || line[len(line)-2] != '\r' {
+		return nil, fmt.Errorf("%q:%w", line, errProtocol)
+	}
+
+	kind, text := line[0], line[1:len(line)-2]
+
+	switch kind {
+	case '+':
+		return text, nil
+	case '-':
+		return nil, fmt.Errorf("%s:%w", text, ErrRedis)
+	case ':':
+		return strconv.ParseInt(text, 10, 64)
+	case '$':
+		size, err := strconv.Atoi(text)
+		if err != nil || size < 0 {
+			return nil, err
+		}
+
+		data := make([]byte, size+2)
+		if _, err := io.ReadFull(r, data); err != nil {
+			return nil, err
+		}
+
+		return data[:size], nil
+	case '*':
+		size, err := strconv.Atoi(text)
+		if err != nil || size < 0 {
+			return nil, err
+		}
+
+		values := make([]any, size)
+
+		for i := range values {
+			if values[i], err = readReply(r); err != nil {
+				return nil, err
+			}
+		}
+
+		return values, nil
+	}
+
+	return nil, fmt.Errorf("%q:%w", line, errProtocol)
+}
@@ -0,0 +1,99 @@
+package cache_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "receipt-processor-challenge/internal/interfaceadapters/storage/cache"
+	"receipt-processor-challenge/internal/interfaceadapters/storage/memory"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Redis(t *testing.T) {
+	ctx := context.Background()
+	server := miniredis.RunT(t)
+	client := NewRedis(server.Addr(), 2)
+
+	defer client.Close()
+
+	assert.NoError(t, client.Ping(ctx))
+
+	_, ok, err := client.Get(ctx, "missing")
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	value := []byte("binary\r\n\x00value")
+	assert.NoError(t, client.Set(ctx, "key", value, time.Minute))
+
+	stored, ok, err := client.Get(ctx, "key")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, value, stored)
+	assert.Equal(t, time.Minute, server.TTL("key"))
+
+	server.FastForward(time.Minute)
+
+	_, ok, err = client.Get(ctx, "key")
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	// error replies are returned and keep the connection usable.
+	server.SetError("LOADING")
+
+	_, _, err = client.Get(ctx, "key")
+	assert.ErrorIs(t, err, ErrRedis)
+
+	server.SetError("")
+	assert.NoError(t, client.Ping(ctx))
+
+	// broken connections are discarded and opened again once the server is back.
+	server.Close()
+	assert.Error(t, client.Ping(ctx))
+	assert.NoError(t, server.Restart())
+	assert.NoError(t, client.Ping(ctx))
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+
+	_, _, err = client.Get(canceled, "key")
+	assert.ErrorIs(t, err, context.Canceled)
+
+	assert.NoError(t, client.Close())
+
+	_, _, err = client.Get(ctx, "key")
+	assert.ErrorIs(t, err, ErrRedisClosed)
+}
+
+func Test_RepositoryWithRedis(t *testing.T) {
+	ctx := context.Background()
+	server := miniredis.RunT(t)
+	inner := &countingRepository{Repository: memory.New(ctx)}
+
+	id, err := inner.Save(ctx, newReceipt(), newPoints())
+	assert.NoError(t, err)
+
+	repo := New(inner, NewRedis(server.Addr(), 2), time.Minute)
+
+	defer repo.Close()
+
+	for i := 0; i < 3; i++ {
+		rcpt, err := repo.GetReceipt(ctx, id)
+		assert.NoError(t, err)
+		assert.Equal(t, newReceipt(), *rcpt)
+	}
+
+	assert.Equal(t, 1, inner.receiptGets)
+	assert.Equal(t, Stats{Hits: 2, Misses: 1}, repo.Stats())
+	assert.True(t, server.Exists("receipt:"+id.String()))
+
+	assert.NoError(t, repo.Ping(ctx))
+	assert.NoError(t, repo.PingCache(ctx))
+
+	server.Close()
+
+	assert.NoError(t, repo.Ping(ctx))
+	assert.Error(t, repo.PingCache(ctx))
+}
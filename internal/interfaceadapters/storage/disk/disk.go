@@ -0,0 +1,407 @@
+/*
+Package disk stores receipts in a local directory so they survive restarts.
+
+Every saved receipt is appended to a log file and synced to disk before Save
+returns. Once the log holds a number of entries the whole store is written to
+a snapshot file and the log starts again empty. On startup the snapshot is
+loaded and the log replayed on top of it, an entry cut by a crash at the end of
+the log is discarded.
+*/
+package disk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"receipt-processor-challenge/internal/domain/receipt"
+
+	"github.com/google/uuid"
+)
+
+const (
+	logFile      string = "receipts.log"
+	snapshotFile string = "receipts.snapshot"
+
+	DefaultSnapshotEvery int = 1000
+)
+
+var (
+	ErrCorrupted     = errors.New("corrupted storage")
+	ErrClosed        = errors.New("storage closed")
+	ErrEntryTooLarge = errors.New("entry too large")
+
+	errInvalidID = errors.New("invalid id")
+)
+
+type record struct {
+	keys    []string
+	receipt receipt.Receipt
+	points  receipt.Points
+}
+
+type Store struct {
+	dir           string
+	snapshotEvery int
+
+	// writer serializes the writes, it is a channel so waiting writers honor
+	// their context.
+	writer chan struct{}
+	log    logHandle
+	// logEntries is the number of entries in the log since the last snapshot.
+	logEntries int
+	// snapshotErr is the error of the last failed snapshot, it is retried with
+	// the next save and returned by Close.
+	snapshotErr error
+	// failed is set when a failed append could not be rolled back, the log
+	// may then hold a partial entry and every later save is refused.
+	failed error
+
+	mu      sync.RWMutex
+	order   []uuid.UUID
+	records map[uuid.UUID]record
+	keys    map[string]uuid.UUID
+	matches map[string][]uuid.UUID
+	flagged []uuid.UUID
+	closed  bool
+}
+
+// New opens the store kept in dir, creating it when it does not exist, and
+// recovers the receipts saved before. A snapshot is taken every snapshotEvery
+// saves, DefaultSnapshotEvery is used when it is not positive.
+func New(_ context.Context, dir string, snapshotEvery int) (*Store, error) {
+	if snapshotEvery <= 0 {
+		snapshotEvery = DefaultSnapshotEvery
+	}
+
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, err
+	}
+
+	s := &Store{
+		dir:           dir,
+		snapshotEvery: snapshotEvery,
+		writer:        make(chan struct{}, 1),
+		records:       make(map[uuid.UUID]record),
+		keys:          make(map[string]uuid.UUID),
+		matches:       make(map[string][]uuid.UUID),
+	}
+
+	if err := s.recover(); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// recover loads the snapshot, replays the log and truncates the entry left
+// incomplete by a crash, if any.
+func (s *Store) recover() error {
+	entries, err := readSnapshot(filepath.Join(s.dir, snapshotFile))
+	if err != nil {
+		return err
+	}
+
+	for _, e := range entries {
+		if err := s.apply(e); err != nil {
+			return err
+		}
+	}
+
+	f, err := os.OpenFile(filepath.Join(s.dir, logFile), os.O_RDWR|os.O_CREATE, 0o600)
+	if err != nil {
+		return err
+	}
+
+	entries, end, err := readLog(f)
+	if err != nil {
+		f.Close()
+
+		return err
+	}
+
+	// entries already in the snapshot are skipped, the log is only truncated
+	// after the snapshot is written.
+	for _, e := range entries {
+		if _, ok := s.records[e.ID]; ok {
+			continue
+		}
+
+		if err := s.apply(e); err != nil {
+			f.Close()
+
+			return err
+		}
+	}
+
+	if err := f.Truncate(end); err != nil {
+		f.Close()
+
+		return err
+	}
+
+	if _, err := f.Seek(end, io.SeekStart); err != nil {
+		f.Close()
+
+		return err
+	}
+
+	s.log = f
+	s.logEntries = len(entries)
+
+	return nil
+}
+
+// apply adds the entry to the in memory indexes.
+func (s *Store) apply(e entry) error {
+	rec, err := e.record()
+	if err != nil {
+		return err
+	}
+
+	s.order = append(s.order, e.ID)
+	s.records[e.ID] = rec
+
+	for _, key := range e.Keys {
+		s.keys[key] = e.ID
+	}
+
+	matchKey := rec.receipt.MatchKey()
+	s.matches[matchKey] = append(s.matches[matchKey], e.ID)
+
+	if rec.points.Duplicate != nil {
+		s.flagged = append(s.flagged, e.ID)
+	}
+
+	return nil
+}
+
+func (s *Store) Save(ctx context.Context, rcpt receipt.Receipt, points receipt.Points, keys ...string) (uuid.UUID, error) {
+	select {
+	case s.writer <- struct{}{}:
+	case <-ctx.Done():
+		return uuid.Nil, ctx.Err()
+	}
+
+	defer func() { <-s.writer }()
+
+	if err := ctx.Err(); err != nil {
+		return uuid.Nil, err
+	}
+
+	s.mu.RLock()
+	closed := s.closed
+	failed := s.failed
+	existing, duplicated := s.existing(keys)
+	s.mu.RUnlock()
+
+	if closed {
+		return uuid.Nil, ErrClosed
+	}
+
+	if failed != nil {
+		return uuid.Nil, failed
+	}
+
+	if duplicated {
+		return existing, receipt.ErrDuplicated
+	}
+
+	e := newEntry(uuid.New(), keys, rcpt, points)
+
+	if err := appendEntry(s.log, e); err != nil {
+		if errors.Is(err, ErrCorrupted) {
+			s.mu.Lock()
+			s.failed = err
+			s.mu.Unlock()
+		}
+
+		return uuid.Nil, err
+	}
+
+	s.mu.Lock()
+	err := s.apply(e)
+	s.mu.Unlock()
+
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	s.logEntries++
+	if s.logEntries >= s.snapshotEvery || s.snapshotErr != nil {
+		s.snapshotErr = s.snapshot()
+	}
+
+	return e.ID, nil
+}
+
+func (s *Store) existing(keys []string) (uuid.UUID, bool) {
+	for _, key := range keys {
+		if id, ok := s.keys[key]; ok {
+			return id, true
+		}
+	}
+
+	return uuid.Nil, false
+}
+
+// snapshot writes every stored receipt to the snapshot file and empties the
+// log, it must be called holding the writer.
+func (s *Store) snapshot() error {
+	s.mu.RLock()
+	entries := make([]entry, len(s.order))
+
+	for index, id := range s.order {
+		rec := s.records[id]
+		entries[index] = newEntry(id, rec.keys, rec.receipt, rec.points)
+	}
+	s.mu.RUnlock()
+
+	if err := writeSnapshot(filepath.Join(s.dir, snapshotFile), entries); err != nil {
+		return fmt.Errorf("snapshot:%w", err)
+	}
+
+	if err := s.log.Truncate(0); err != nil {
+		return fmt.Errorf("truncate log:%w", err)
+	}
+
+	if _, err := s.log.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("truncate log:%w", err)
+	}
+
+	s.logEntries = 0
+
+	return nil
+}
+
+func (s *Store) Get(ctx context.Context, id uuid.UUID) (*receipt.Points, error) {
+	rec, err := s.load(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return &rec.points, nil
+}
+
+func (s *Store) GetReceipt(ctx context.Context, id uuid.UUID) (*receipt.Receipt, error) {
+	rec, err := s.load(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return &rec.receipt, nil
+}
+
+func (s *Store) load(ctx context.Context, id uuid.UUID) (*record, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	if id == uuid.Nil {
+		return nil, errInvalidID
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	rec, ok := s.records[id]
+	if !ok {
+		return nil, receipt.ErrNotFound
+	}
+
+	return &rec, nil
+}
+
+func (s *Store) FindByKey(ctx context.Context, key string) (uuid.UUID, error) {
+	if err := ctx.Err(); err != nil {
+		return uuid.Nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	id, ok := s.keys[key]
+	if !ok {
+		return uuid.Nil, receipt.ErrNotFound
+	}
+
+	return id, nil
+}
+
+func (s *Store) FindMatching(ctx context.Context, rcpt receipt.Receipt) ([]uuid.UUID, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return append([]uuid.UUID(nil), s.matches[rcpt.MatchKey()]...), nil
+}
+
+func (s *Store) Flagged(ctx context.Context) ([]receipt.FlaggedReceipt, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := make([]receipt.FlaggedReceipt, 0, len(s.flagged))
+
+	for _, id := range s.flagged {
+		result = append(result, receipt.FlaggedReceipt{ID: id, Points: s.records[id].points})
+	}
+
+	return result, nil
+}
+
+// Ping fails once the store is closed, when the log file can not be reached,
+// when a failed save could not be rolled back or when the last snapshot failed.
+func (s *Store) Ping(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.closed {
+		return ErrClosed
+	}
+
+	if _, err := s.log.Stat(); err != nil {
+		return err
+	}
+
+	if s.failed != nil {
+		return s.failed
+	}
+
+	return s.snapshotErr
+}
+
+// Close takes a last snapshot and closes the log, the store can not be used
+// afterwards.
+func (s *Store) Close() error {
+	s.writer <- struct{}{}
+	defer func() { <-s.writer }()
+
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+
+		return nil
+	}
+
+	s.closed = true
+	s.mu.Unlock()
+
+	snapErr := s.snapshot()
+
+	return errors.Join(snapErr, s.log.Close())
+}
@@ -0,0 +1,211 @@
+package disk_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"receipt-processor-challenge/internal/domain/receipt"
+	. "receipt-processor-challenge/internal/interfaceadapters/storage/disk"
+	"receipt-processor-challenge/internal/interfaceadapters/storage/storagetest"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Repository(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) receipt.Repository {
+		store, err := New(context.Background(), t.TempDir(), 100)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		t.Cleanup(func() { store.Close() })
+
+		return store
+	})
+}
+
+func Test_SaveAndRecover(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	store, err := New(ctx, dir, 2)
+	assert.NoError(t, err)
+
+	ids := []uuid.UUID{}
+
+	for i := 0; i < 5; i++ {
+		points := storagetest.NewPoints(i)
+		if i == 3 {
+			points.Duplicate = &receipt.Duplicate{Of: ids[0], Similarity: 0.9, Policy: "flag"}
+		}
+
+		id, err := store.Save(ctx, storagetest.NewReceipt("Market"), points, "key-"+string(rune('a'+i)))
+		assert.NoError(t, err)
+
+		ids = append(ids, id)
+	}
+
+	// no Close: the store is reopened as after a crash.
+	recovered, err := New(ctx, dir, 2)
+	assert.NoError(t, err)
+
+	for i, id := range ids {
+		points, err := recovered.Get(ctx, id)
+		assert.NoError(t, err)
+		assert.Equal(t, storagetest.NewPoints(i).Breakdown, points.Breakdown)
+		assert.Equal(t, i, points.Points)
+
+		rcpt, err := recovered.GetReceipt(ctx, id)
+		assert.NoError(t, err)
+		assert.Equal(t, storagetest.NewReceipt("Market"), *rcpt)
+	}
+
+	matching, err := recovered.FindMatching(ctx, storagetest.NewReceipt("Market"))
+	assert.NoError(t, err)
+	assert.Equal(t, ids, matching)
+
+	flagged, err := recovered.Flagged(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, flagged, 1)
+	assert.Equal(t, ids[3], flagged[0].ID)
+	assert.Equal(t, ids[0], flagged[0].Points.Duplicate.Of)
+
+	id, err := recovered.Save(ctx, storagetest.NewReceipt("Market"), storagetest.NewPoints(9), "key-b")
+	assert.ErrorIs(t, err, receipt.ErrDuplicated)
+	assert.Equal(t, ids[1], id)
+}
+
+func Test_RecoverTornLog(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	store, err := New(ctx, dir, 100)
+	assert.NoError(t, err)
+
+	first, err := store.Save(ctx, storagetest.NewReceipt("Market"), storagetest.NewPoints(1))
+	assert.NoError(t, err)
+
+	second, err := store.Save(ctx, storagetest.NewReceipt("Market"), storagetest.NewPoints(2))
+	assert.NoError(t, err)
+
+	// cut the last entry in half as if the process crashed while writing it.
+	path := filepath.Join(dir, "receipts.log")
+	info, err := os.Stat(path)
+	assert.NoError(t, err)
+	assert.NoError(t, os.Truncate(path, info.Size()-10))
+
+	recovered, err := New(ctx, dir, 100)
+	assert.NoError(t, err)
+
+	_, err = recovered.Get(ctx, first)
+	assert.NoError(t, err)
+
+	_, err = recovered.Get(ctx, second)
+	assert.ErrorIs(t, err, receipt.ErrNotFound)
+
+	// the torn entry is gone, new entries are readable after a restart.
+	third, err := recovered.Save(ctx, storagetest.NewReceipt("Market"), storagetest.NewPoints(3))
+	assert.NoError(t, err)
+
+	recovered, err = New(ctx, dir, 100)
+	assert.NoError(t, err)
+
+	points, err := recovered.Get(ctx, third)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, points.Points)
+}
+
+func Test_RecoverOversizedFrame(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	store, err := New(ctx, dir, 100)
+	assert.NoError(t, err)
+
+	first, err := store.Save(ctx, storagetest.NewReceipt("Market"), storagetest.NewPoints(1))
+	assert.NoError(t, err)
+
+	// a corrupted header claiming a 4 GiB entry, recovery must not allocate it.
+	f, err := os.OpenFile(filepath.Join(dir, "receipts.log"), os.O_WRONLY|os.O_APPEND, 0o600)
+	assert.NoError(t, err)
+	_, err = f.Write([]byte{0xff, 0xff, 0xff, 0xff, 0, 0, 0, 0, '{'})
+	assert.NoError(t, err)
+	assert.NoError(t, f.Close())
+
+	recovered, err := New(ctx, dir, 100)
+	assert.NoError(t, err)
+
+	_, err = recovered.Get(ctx, first)
+	assert.NoError(t, err)
+
+	// the corrupted tail is gone, new entries are readable after a restart.
+	second, err := recovered.Save(ctx, storagetest.NewReceipt("Market"), storagetest.NewPoints(2))
+	assert.NoError(t, err)
+
+	recovered, err = New(ctx, dir, 100)
+	assert.NoError(t, err)
+
+	points, err := recovered.Get(ctx, second)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, points.Points)
+}
+
+func Test_SaveTooLarge(t *testing.T) {
+	ctx := context.Background()
+
+	store, err := New(ctx, t.TempDir(), 100)
+	assert.NoError(t, err)
+
+	rcpt := storagetest.NewReceipt(strings.Repeat("M", 17<<20))
+
+	_, err = store.Save(ctx, rcpt, storagetest.NewPoints(1))
+	assert.ErrorIs(t, err, ErrEntryTooLarge)
+	assert.NoError(t, store.Ping(ctx))
+}
+
+func Test_Close(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	store, err := New(ctx, dir, 100)
+	assert.NoError(t, err)
+
+	id, err := store.Save(ctx, storagetest.NewReceipt("Market"), storagetest.NewPoints(1))
+	assert.NoError(t, err)
+	assert.NoError(t, store.Ping(ctx))
+	assert.NoError(t, store.Close())
+
+	_, err = store.Save(ctx, storagetest.NewReceipt("Market"), storagetest.NewPoints(1))
+	assert.ErrorIs(t, err, ErrClosed)
+	assert.ErrorIs(t, store.Ping(ctx), ErrClosed)
+
+	// closing takes a snapshot and empties the log.
+	info, err := os.Stat(filepath.Join(dir, "receipts.log"))
+	assert.NoError(t, err)
+	assert.Zero(t, info.Size())
+
+	reopened, err := New(ctx, dir, 100)
+	assert.NoError(t, err)
+
+	points, err := reopened.Get(ctx, id)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, points.Points)
+}
+
+func Test_ContextCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	store, err := New(context.Background(), t.TempDir(), 100)
+	assert.NoError(t, err)
+
+	_, err = store.Save(ctx, storagetest.NewReceipt("Market"), storagetest.NewPoints(1))
+	assert.ErrorIs(t, err, context.Canceled)
+
+	_, err = store.Get(ctx, uuid.New())
+	assert.ErrorIs(t, err, context.Canceled)
+}
@@ -0,0 +1,130 @@
+package disk
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+)
+
+// frameHeaderSize is the size of the header written before every log entry:
+// the length of the entry and its CRC-32, both big endian uint32.
+const frameHeaderSize int = 8
+
+// maxEntrySize is the largest entry of the log, a frame header with a bigger
+// length is torn or corrupted and ends the log before its entry is allocated.
+const maxEntrySize int = 16 << 20
+
+// logHandle is the open log file, an *os.File outside of the tests.
+type logHandle interface {
+	io.WriteSeeker
+	Sync() error
+	Truncate(size int64) error
+	Stat() (os.FileInfo, error)
+	Close() error
+}
+
+/*
+appendEntry writes the entry at the end of the log and waits until it is on
+disk. When the write or the sync fails the log is cut back to where the entry
+started, so a later entry is not written after a partial one and lost on
+recovery. An error wrapping ErrCorrupted is returned when the log can not be
+cut back.
+*/
+func appendEntry(f logHandle, e entry) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	// it would be discarded as corrupted on recovery.
+	if len(data) > maxEntrySize {
+		return fmt.Errorf("entry of %d bytes:%w", len(data), ErrEntryTooLarge)
+	}
+
+	frame := make([]byte, frameHeaderSize+len(data))
+	binary.BigEndian.PutUint32(frame[0:4], uint32(len(data)))
+	binary.BigEndian.PutUint32(frame[4:8], crc32.ChecksumIEEE(data))
+	copy(frame[frameHeaderSize:], data)
+
+	offset, err := f.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+
+	_, err = f.Write(frame)
+	if err == nil {
+		err = f.Sync()
+	}
+
+	if err == nil {
+		return nil
+	}
+
+	if rollbackErr := rollback(f, offset); rollbackErr != nil {
+		return errors.Join(err, fmt.Errorf("roll back log:%s:%w", rollbackErr.Error(), ErrCorrupted))
+	}
+
+	return err
+}
+
+// rollback cuts the log at offset and moves the next write there, the new size
+// reaches the disk with the sync of the next entry.
+func rollback(f logHandle, offset int64) error {
+	if err := f.Truncate(offset); err != nil {
+		return err
+	}
+
+	_, err := f.Seek(offset, io.SeekStart)
+
+	return err
+}
+
+// readLog returns the entries of the log and the offset where the last
+// complete entry ends. An entry cut by a crash, with a wrong checksum or a
+// length over maxEntrySize, ends the log: it and everything after it are
+// discarded.
+func readLog(r io.Reader) ([]entry, int64, error) {
+	entries := []entry{}
+	offset := int64(0)
+	header := make([]byte, frameHeaderSize)
+
+	for {
+		if _, err := io.ReadFull(r, header); err != nil {
+			if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+				return entries, offset, nil
+			}
+
+			return nil, 0, err
+		}
+
+		size := binary.BigEndian.Uint32(header[0:4])
+		if size > uint32(maxEntrySize) {
+			return entries, offset, nil
+		}
+
+		data := make([]byte, size)
+		if _, err := io.ReadFull(r, data); err != nil {
+			if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+				return entries, offset, nil
+			}
+
+			return nil, 0, err
+		}
+
+		if crc32.ChecksumIEEE(data) != binary.BigEndian.Uint32(header[4:8]) {
+			return entries, offset, nil
+		}
+
+		e := entry{}
+		if err := json.Unmarshal(data, &e); err != nil {
+			return entries, offset, nil //nolint:nilerr // a torn entry is the end of the log.
+		}
+
+		entries = append(entries, e)
+		offset += int64(frameHeaderSize + len(data))
+	}
+}
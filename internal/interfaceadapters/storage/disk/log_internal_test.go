@@ -0,0 +1,135 @@
+package disk
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"receipt-processor-challenge/internal/domain/receipt"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var errInjected = errors.New("injected failure")
+
+// failingLog fails the calls of the log it wraps as configured, a failed write
+// still writes half of the frame as a full disk would.
+type failingLog struct {
+	logHandle
+	write    bool
+	sync     bool
+	truncate bool
+}
+
+func (f *failingLog) Write(b []byte) (int, error) {
+	if f.write {
+		n, _ := f.logHandle.Write(b[:len(b)/2])
+
+		return n, errInjected
+	}
+
+	return f.logHandle.Write(b)
+}
+
+func (f *failingLog) Sync() error {
+	if f.sync {
+		return errInjected
+	}
+
+	return f.logHandle.Sync()
+}
+
+func (f *failingLog) Truncate(size int64) error {
+	if f.truncate {
+		return errInjected
+	}
+
+	return f.logHandle.Truncate(size)
+}
+
+func logReceipt(retailer string) receipt.Receipt {
+	return receipt.Receipt{
+		Retailer:     retailer,
+		PurchaseDate: time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC),
+		PurchaseTime: time.Date(0, 1, 1, 13, 1, 0, 0, time.UTC),
+		Items:        []receipt.Item{{ShortDescription: "Mountain Dew 12PK", Price: 649}},
+		Total:        649,
+	}
+}
+
+func Test_AppendRollback(t *testing.T) {
+	cases := []struct {
+		name    string
+		failure failingLog
+	}{
+		{name: "write-case", failure: failingLog{write: true}},
+		{name: "sync-case", failure: failingLog{sync: true}},
+	}
+
+	for _, c := range cases {
+		c := c
+
+		t.Run(c.name, func(t *testing.T) {
+			ctx := context.Background()
+			dir := t.TempDir()
+
+			store, err := New(ctx, dir, 100)
+			assert.NoError(t, err)
+
+			first, err := store.Save(ctx, logReceipt("first"), receipt.Points{Points: 1})
+			assert.NoError(t, err)
+
+			file := store.log
+			failure := c.failure
+			failure.logHandle = file
+			store.log = &failure
+
+			_, err = store.Save(ctx, logReceipt("failed"), receipt.Points{Points: 2})
+			assert.ErrorIs(t, err, errInjected)
+			assert.NotErrorIs(t, err, ErrCorrupted)
+			assert.NoError(t, store.Ping(ctx))
+
+			store.log = file
+
+			third, err := store.Save(ctx, logReceipt("third"), receipt.Points{Points: 3})
+			assert.NoError(t, err)
+
+			// no Close: the saves after the failed one are read back from the log.
+			recovered, err := New(ctx, dir, 100)
+			assert.NoError(t, err)
+
+			points, err := recovered.Get(ctx, first)
+			assert.NoError(t, err)
+			assert.Equal(t, 1, points.Points)
+
+			points, err = recovered.Get(ctx, third)
+			assert.NoError(t, err)
+			assert.Equal(t, 3, points.Points)
+
+			matching, err := recovered.FindMatching(ctx, logReceipt("failed"))
+			assert.NoError(t, err)
+			assert.Empty(t, matching)
+		})
+	}
+}
+
+func Test_AppendRollbackFailed(t *testing.T) {
+	ctx := context.Background()
+
+	store, err := New(ctx, t.TempDir(), 100)
+	assert.NoError(t, err)
+
+	store.log = &failingLog{logHandle: store.log, write: true, truncate: true}
+
+	_, err = store.Save(ctx, logReceipt("failed"), receipt.Points{Points: 1})
+	assert.ErrorIs(t, err, errInjected)
+	assert.ErrorIs(t, err, ErrCorrupted)
+	assert.ErrorIs(t, store.Ping(ctx), ErrCorrupted)
+
+	// the log may end with a partial entry, no save is written after it.
+	store.log = store.log.(*failingLog).logHandle
+
+	_, err = store.Save(ctx, logReceipt("next"), receipt.Points{Points: 2})
+	assert.ErrorIs(t, err, ErrCorrupted)
+}
@@ -0,0 +1,169 @@
+package disk
+
+import (
+	"fmt"
+	"time"
+
+	"receipt-processor-challenge/internal/domain/receipt"
+
+	"github.com/google/uuid"
+)
+
+// entry is the on disk format of a stored receipt, it is used by both the log
+// and the snapshots.
+type entry struct {
+	ID      uuid.UUID   `json:"id"`
+	Keys    []string    `json:"keys,omitempty"`
+	Receipt receiptData `json:"receipt"`
+	Points  pointsData  `json:"points"`
+}
+
+type receiptData struct {
+	Retailer     string     `json:"retailer"`
+	PurchaseDate string     `json:"purchaseDate"`
+	PurchaseTime string     `json:"purchaseTime"`
+	Items        []itemData `json:"items"`
+	Total        int64      `json:"total"`
+}
+
+type itemData struct {
+	ShortDescription string `json:"shortDescription"`
+	Price            int64  `json:"price"`
+}
+
+type pointsData struct {
+	Points         int            `json:"points"`
+	RuleSetVersion string         `json:"ruleSetVersion"`
+	Breakdown      []awardData    `json:"breakdown"`
+	Duplicate      *duplicateData `json:"duplicate,omitempty"`
+}
+
+type awardData struct {
+	Rule        string           `json:"rule"`
+	Description string           `json:"description"`
+	Points      int              `json:"points"`
+	Item        *awardedItemData `json:"item,omitempty"`
+}
+
+type awardedItemData struct {
+	Index int      `json:"index"`
+	Item  itemData `json:"item"`
+}
+
+type duplicateData struct {
+	Of         uuid.UUID `json:"of"`
+	Similarity float64   `json:"similarity"`
+	Policy     string    `json:"policy"`
+}
+
+func newEntry(id uuid.UUID, keys []string, r receipt.Receipt, p receipt.Points) entry {
+	items := make([]itemData, len(r.Items))
+
+	for index, item := range r.Items {
+		items[index] = fromItem(item)
+	}
+
+	awards := make([]awardData, len(p.Breakdown))
+
+	for index, a := range p.Breakdown {
+		awards[index] = awardData{
+			Rule:        a.Rule,
+			Description: a.Description,
+			Points:      a.Points,
+		}
+
+		if a.Item != nil {
+			awards[index].Item = &awardedItemData{Index: a.Item.Index, Item: fromItem(a.Item.Item)}
+		}
+	}
+
+	e := entry{
+		ID:   id,
+		Keys: keys,
+		Receipt: receiptData{
+			Retailer:     r.Retailer,
+			PurchaseDate: r.PurchaseDate.Format(receipt.DatePurchaseFormat),
+			PurchaseTime: r.PurchaseTime.Format(receipt.TimePurchaseFormat),
+			Items:        items,
+			Total:        r.Total.Cents(),
+		},
+		Points: pointsData{
+			Points:         p.Points,
+			RuleSetVersion: p.RuleSetVersion,
+			Breakdown:      awards,
+		},
+	}
+
+	if p.Duplicate != nil {
+		e.Points.Duplicate = &duplicateData{
+			Of:         p.Duplicate.Of,
+			Similarity: p.Duplicate.Similarity,
+			Policy:     p.Duplicate.Policy,
+		}
+	}
+
+	return e
+}
+
+func fromItem(i receipt.Item) itemData {
+	return itemData{ShortDescription: i.ShortDescription, Price: i.Price.Cents()}
+}
+
+func (i itemData) toItem() receipt.Item {
+	return receipt.Item{ShortDescription: i.ShortDescription, Price: receipt.Money(i.Price)}
+}
+
+// record converts the entry back to the domain types.
+func (e entry) record() (record, error) {
+	purchaseDate, err := time.Parse(receipt.DatePurchaseFormat, e.Receipt.PurchaseDate)
+	if err != nil {
+		return record{}, fmt.Errorf("%s purchaseDate:%w", e.ID, ErrCorrupted)
+	}
+
+	purchaseTime, err := time.Parse(receipt.TimePurchaseFormat, e.Receipt.PurchaseTime)
+	if err != nil {
+		return record{}, fmt.Errorf("%s purchaseTime:%w", e.ID, ErrCorrupted)
+	}
+
+	items := make([]receipt.Item, len(e.Receipt.Items))
+
+	for index, item := range e.Receipt.Items {
+		items[index] = item.toItem()
+	}
+
+	awards := make([]receipt.Award, len(e.Points.Breakdown))
+
+	for index, a := range e.Points.Breakdown {
+		awards[index] = receipt.Award{
+			Rule:        a.Rule,
+			Description: a.Description,
+			Points:      a.Points,
+		}
+
+		if a.Item != nil {
+			awards[index].Item = &receipt.AwardedItem{Index: a.Item.Index, Item: a.Item.Item.toItem()}
+		}
+	}
+
+	rec := record{
+		keys: e.Keys,
+		receipt: receipt.Receipt{
+			Retailer:     e.Receipt.Retailer,
+			PurchaseDate: purchaseDate,
+			PurchaseTime: purchaseTime,
+			Items:        items,
+			Total:        receipt.Money(e.Receipt.Total),
+		},
+		points: receipt.Points{
+			Points:         e.Points.Points,
+			Breakdown:      awards,
+			RuleSetVersion: e.Points.RuleSetVersion,
+		},
+	}
+
+	if d := e.Points.Duplicate; d != nil {
+		rec.points.Duplicate = &receipt.Duplicate{Of: d.Of, Similarity: d.Similarity, Policy: d.Policy}
+	}
+
+	return rec, nil
+}
@@ -0,0 +1,90 @@
+package disk
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// snapshotVersion is the version of the snapshot file format.
+const snapshotVersion int = 1
+
+type snapshot struct {
+	Version int     `json:"version"`
+	Entries []entry `json:"entries"`
+}
+
+// readSnapshot returns the entries of the snapshot, a missing snapshot has no
+// entries.
+func readSnapshot(path string) ([]entry, error) {
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return []entry{}, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	snap := snapshot{}
+	if err := json.Unmarshal(content, &snap); err != nil {
+		return nil, fmt.Errorf("%s:%w", err.Error(), ErrCorrupted)
+	}
+
+	if snap.Version != snapshotVersion {
+		return nil, fmt.Errorf("snapshot version %d:%w", snap.Version, ErrCorrupted)
+	}
+
+	return snap.Entries, nil
+}
+
+// writeSnapshot replaces the snapshot atomically: the entries are written to a
+// temporary file that is renamed over the previous snapshot once it is on disk.
+func writeSnapshot(path string, entries []entry) error {
+	content, err := json.Marshal(snapshot{Version: snapshotVersion, Entries: entries})
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+
+		return err
+	}
+
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+
+	return syncDir(filepath.Dir(path))
+}
+
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+
+	defer d.Close()
+
+	return d.Sync()
+}
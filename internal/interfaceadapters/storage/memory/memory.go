@@ -3,7 +3,10 @@ package memory
 import (
 	"context"
 	"errors"
+	"hash/maphash"
+	"sort"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"receipt-processor-challenge/internal/domain/receipt"
@@ -12,217 +15,494 @@ import (
 )
 
 const (
-	get operation = iota
-	set
+	// shardCount is the number of independent locks, a power of two.
+	shardCount int = 64
 
 	defaultTimeOut = time.Second
 )
 
 var (
-	once    sync.Once                    //nolint:gochecknoglobals
-	storage map[uuid.UUID]receipt.Points //nolint:gochecknoglobals
-	engine  Engine                       //nolint:gochecknoglobals
+	ErrNotFound = receipt.ErrNotFound
+	ErrClosed   = errors.New("storage closed")
+	// ErrFull is returned by Save when the receipt is bigger than the byte
+	// budget of the engine.
+	ErrFull = errors.New("storage full")
+
+	// errTimeOut reports Timeout() like the net errors so it is told apart
+	// from the other failures without depending on this package.
+	errTimeOut   error = timeOutError{}
+	errInvalidID       = errors.New("invalid id")
+)
 
-	ErrNotFound = errors.New("points not found")
+type timeOutError struct{}
 
-	errTimeOut   = errors.New("time out")
-	errInvalidID = errors.New("invalid id")
-)
+func (timeOutError) Error() string { return "time out" }
+
+func (timeOutError) Timeout() bool { return true }
+
+type record struct {
+	receipt  receipt.Receipt
+	points   receipt.Points
+	keys     []string
+	matchKey string
+	// expires is zero when the record has no TTL.
+	expires time.Time
+	size    int64
+	// seq is the order in which the records were stored.
+	seq uint64
+}
 
-type operation int
+// recordShard keeps the records whose id falls in the shard.
+type recordShard struct {
+	mu      sync.RWMutex
+	records map[uuid.UUID]record
+}
 
-type request struct {
-	ctx context.Context
-	op  operation
-	in  chan payload
-	out chan payload
+// keyShard keeps the submission keys whose hash falls in the shard.
+type keyShard struct {
+	mu   sync.Mutex
+	keys map[string]uuid.UUID
 }
 
-type payload struct {
-	id   uuid.UUID
-	data *receipt.Points
-	err  error
+// matchShard keeps the ids of the receipts whose match key hash falls in the
+// shard, in the order they were saved.
+type matchShard struct {
+	mu      sync.RWMutex
+	matches map[string][]uuid.UUID
 }
 
+/*
+Engine keeps the receipts in memory. The data is split in shards, each one with
+its own lock, so saves and reads of different receipts do not wait for each
+other: records are sharded by id, submission keys and match keys by their hash.
+
+Every operation fails when its context is done before it starts. Saves may wait
+for the locks of the submission keys, the time out applies to them when the
+context has no deadline and they fail if it expires while waiting. Reads only
+hold a lock for a map lookup and do not need one.
+
+Every engine is independent, it is closed with Close or Shutdown, or when the
+context given to New is done.
+
+Receipts are kept forever unless the engine has retention limits: a TTL, after
+which a background sweeper removes them, and a capacity or byte budget, over
+which the least recently saved or read receipts are evicted. Reading a removed
+receipt returns ErrEvicted while its id is among the last remembered ones and
+ErrNotFound afterwards.
+*/
 type Engine struct {
-	req       chan request
-	opTimeOut time.Duration
+	opTimeOut     time.Duration
+	capacity      int
+	maxBytes      int64
+	ttl           time.Duration
+	sweepInterval time.Duration
+	seed          maphash.Seed
+	now           func() time.Time
+
+	// lifecycle is held for reading by every operation in flight and for
+	// writing by Shutdown, so closing waits for them to finish.
+	lifecycle sync.RWMutex
+	closed    bool
+	done      chan struct{}
+
+	seq        atomic.Uint64
+	size       atomic.Int64
+	bytes      atomic.Int64
+	evicted    atomic.Uint64
+	expired    atomic.Uint64
+	lru        lru
+	tombstones tombstones
+
+	records [shardCount]recordShard
+	keys    [shardCount]keyShard
+	matches [shardCount]matchShard
+
+	flaggedMu sync.RWMutex
+	flagged   []uuid.UUID
 }
 
-func New(ctx context.Context) *Engine {
-	once.Do(func() {
-		engine.req = make(chan request)
-		engine.opTimeOut = defaultTimeOut
-		go engine.start(ctx)
-	})
+// Option configures an Engine.
+type Option func(e *Engine)
 
-	return &engine
+// WithTimeOut sets the time out of the operations whose context has no
+// deadline, one second by default.
+func WithTimeOut(timeOut time.Duration) Option {
+	return func(e *Engine) {
+		e.opTimeOut = timeOut
+	}
 }
 
-func (e *Engine) start(ctx context.Context) {
-	storage = make(map[uuid.UUID]receipt.Points)
+// WithCapacity limits the number of stored receipts, there is no limit by
+// default. The least recently used receipts are evicted to make room.
+func WithCapacity(capacity int) Option {
+	return func(e *Engine) {
+		e.capacity = capacity
+	}
+}
+
+// New returns an empty engine, it is closed when ctx is done.
+func New(ctx context.Context, opts ...Option) *Engine {
+	e := &Engine{
+		opTimeOut: defaultTimeOut,
+		seed:      maphash.MakeSeed(),
+		now:       time.Now,
+		done:      make(chan struct{}),
+	}
+
+	e.tombstones.size = defaultTombstones
+
+	for _, opt := range opts {
+		opt(e)
+	}
+
+	for i := 0; i < shardCount; i++ {
+		e.records[i].records = make(map[uuid.UUID]record)
+		e.keys[i].keys = make(map[string]uuid.UUID)
+		e.matches[i].matches = make(map[string][]uuid.UUID)
+	}
 
-	for {
+	go func() {
 		select {
 		case <-ctx.Done():
-			return
-		case req := <-e.req:
-			switch req.op {
-			case get:
-				e.get(req)
-			case set:
-				e.save(req)
+			_ = e.Close()
+		case <-e.done:
+		}
+	}()
+
+	if e.ttl > 0 {
+		interval := e.sweepInterval
+		if interval <= 0 {
+			interval = defaultSweepInterval
+
+			if half := e.ttl / 2; half < interval { //nolint:gomnd
+				interval = half
 			}
 		}
+
+		go e.sweeper(interval)
 	}
+
+	return e
 }
 
-func (e *Engine) save(req request) {
-	var data payload
+// Close stops the engine once the operations in flight finish.
+func (e *Engine) Close() error {
+	return e.Shutdown(context.Background())
+}
+
+// Shutdown stops accepting operations and waits for the ones in flight until
+// ctx is done. The engine is closed even when ctx expires first.
+func (e *Engine) Shutdown(ctx context.Context) error {
+	drained := make(chan struct{})
+
+	go func() {
+		e.lifecycle.Lock()
+		defer e.lifecycle.Unlock()
+
+		if !e.closed {
+			e.closed = true
+			close(e.done)
+		}
+
+		close(drained)
+	}()
 
 	select {
-	case data = <-req.in:
-	case <-req.ctx.Done():
-		return
+	case <-drained:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
 	}
+}
 
-	storage[data.id] = *data.data
+// begin registers an operation in flight, end must be called when it finishes.
+func (e *Engine) begin() error {
+	e.lifecycle.RLock()
 
-	defer close(req.out)
+	if e.closed {
+		e.lifecycle.RUnlock()
 
-	pload := payload{
-		id:   uuid.Nil,
-		data: nil,
-		err:  nil,
+		return ErrClosed
 	}
 
-	select {
-	case req.out <- pload:
-	case <-req.ctx.Done():
+	return nil
+}
+
+func (e *Engine) end() {
+	e.lifecycle.RUnlock()
+}
+
+// withTimeOut applies the default time out to contexts without deadline.
+func (e *Engine) withTimeOut(ctx context.Context) (context.Context, context.CancelFunc) {
+	if _, deadLineSet := ctx.Deadline(); deadLineSet {
+		return ctx, func() {}
 	}
+
+	return context.WithTimeout(ctx, e.opTimeOut)
 }
 
-func (e *Engine) get(req request) {
-	var data payload
+func (e *Engine) recordShard(id uuid.UUID) *recordShard {
+	// ids are random, their last byte is evenly distributed.
+	return &e.records[int(id[len(id)-1])&(shardCount-1)]
+}
 
-	select {
-	case data = <-req.in:
-	case <-req.ctx.Done():
-		return
+func (e *Engine) shardIndex(key string) int {
+	return int(maphash.String(e.seed, key) & uint64(shardCount-1))
+}
+
+func (e *Engine) Save(ctx context.Context, rcpt receipt.Receipt, points receipt.Points, keys ...string) (uuid.UUID, error) {
+	ctx, cancel := e.withTimeOut(ctx)
+	defer cancel()
+
+	if err := ctx.Err(); err != nil {
+		return uuid.Nil, err
 	}
 
-	var pload *payload
+	if err := e.begin(); err != nil {
+		return uuid.Nil, err
+	}
+	defer e.end()
+
+	rcpt.Items = append([]receipt.Item(nil), rcpt.Items...)
+	rec := record{
+		receipt:  rcpt,
+		points:   points,
+		keys:     append([]string(nil), keys...),
+		matchKey: rcpt.MatchKey(),
+	}
 
-	defer close(req.out)
-	defer func() {
-		select {
-		case <-req.ctx.Done():
-		case req.out <- *pload:
+	if e.retains() {
+		rec.expires = e.expiresAt()
+		rec.size = sizeOf(rcpt, points, keys)
+
+		if e.maxBytes > 0 && rec.size > e.maxBytes {
+			return uuid.Nil, ErrFull
 		}
-	}()
+	}
+
+	id, err := e.store(ctx, uuid.New(), rec)
+	if err == nil && e.limited() {
+		e.evict()
+	}
+
+	return id, err
+}
+
+func (e *Engine) store(ctx context.Context, newID uuid.UUID, rec record) (uuid.UUID, error) {
+	keys := rec.keys
 
-	if data.id == uuid.Nil {
-		pload = &payload{
-			id:   data.id,
-			data: nil,
-			err:  errInvalidID,
+	// the key shards stay locked until the record is stored, so a concurrent
+	// save with the same key finds it.
+	unlock := e.lockKeys(keys)
+	defer unlock()
+
+	for _, key := range keys {
+		if id, ok := e.keys[e.shardIndex(key)].keys[key]; ok {
+			return id, receipt.ErrDuplicated
 		}
+	}
 
-		return
+	if err := ctx.Err(); err != nil {
+		return uuid.Nil, err
 	}
 
-	val, ok := storage[data.id]
-	if !ok {
-		pload = &payload{
-			id:   data.id,
-			data: nil,
-			err:  ErrNotFound,
+	rec.seq = e.seq.Add(1)
+
+	shard := e.recordShard(newID)
+	shard.mu.Lock()
+	shard.records[newID] = rec
+	shard.mu.Unlock()
+
+	for _, key := range keys {
+		e.keys[e.shardIndex(key)].keys[key] = newID
+	}
+
+	match := &e.matches[e.shardIndex(rec.matchKey)]
+	match.mu.Lock()
+	match.matches[rec.matchKey] = append(match.matches[rec.matchKey], newID)
+	match.mu.Unlock()
+
+	if rec.points.Duplicate != nil {
+		e.flaggedMu.Lock()
+		e.flagged = append(e.flagged, newID)
+		e.flaggedMu.Unlock()
+	}
+
+	e.size.Add(1)
+	e.bytes.Add(rec.size)
+
+	if e.limited() {
+		e.lru.push(newID)
+	}
+
+	return newID, nil
+}
+
+// lockKeys locks the shards of the keys in index order, to avoid deadlocks
+// between saves sharing shards, and returns the function that unlocks them.
+func (e *Engine) lockKeys(keys []string) func() {
+	indexes := make([]int, 0, len(keys))
+	seen := make(map[int]struct{}, len(keys))
+
+	for _, key := range keys {
+		index := e.shardIndex(key)
+		if _, ok := seen[index]; ok {
+			continue
 		}
 
-		return
+		seen[index] = struct{}{}
+		indexes = append(indexes, index)
 	}
 
-	pload = &payload{
-		id:   data.id,
-		data: &val,
-		err:  nil,
+	sort.Ints(indexes)
+
+	for _, index := range indexes {
+		e.keys[index].mu.Lock()
+	}
+
+	return func() {
+		for _, index := range indexes {
+			e.keys[index].mu.Unlock()
+		}
 	}
 }
 
-func (e *Engine) Save(ctx context.Context, receipt receipt.Points) (uuid.UUID, error) {
-	if _, deadLineSet := ctx.Deadline(); !deadLineSet {
-		var cancel context.CancelFunc
+func (e *Engine) Get(ctx context.Context, uid uuid.UUID) (*receipt.Points, error) {
+	data, err := e.load(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+
+	return &data.points, nil
+}
 
-		ctx, cancel = context.WithTimeout(ctx, e.opTimeOut)
-		defer cancel()
+func (e *Engine) GetReceipt(ctx context.Context, uid uuid.UUID) (*receipt.Receipt, error) {
+	data, err := e.load(ctx, uid)
+	if err != nil {
+		return nil, err
 	}
 
-	newID := uuid.New()
+	return &data.receipt, nil
+}
 
-	saveRequest := request{
-		ctx: ctx,
-		op:  set,
-		in:  make(chan payload),
-		out: make(chan payload),
+func (e *Engine) load(ctx context.Context, uid uuid.UUID) (*record, error) {
+	if ctx.Err() != nil {
+		return nil, errTimeOut
 	}
 
-	pload := payload{
-		id:   newID,
-		data: &receipt,
-		err:  nil,
+	if err := e.begin(); err != nil {
+		return nil, err
 	}
+	defer e.end()
 
-	select {
-	case <-ctx.Done():
-		return uuid.Nil, ctx.Err()
-	case e.req <- saveRequest:
-		select {
-		case saveRequest.in <- pload:
-			close(saveRequest.in)
-		case <-ctx.Done():
-			return uuid.Nil, ctx.Err()
-		}
+	if uid == uuid.Nil {
+		return nil, errInvalidID
 	}
 
-	<-saveRequest.out
+	shard := e.recordShard(uid)
+	shard.mu.RLock()
+	val, ok := shard.records[uid]
+	shard.mu.RUnlock()
+
+	switch {
+	case !ok && e.tombstones.contains(uid):
+		return nil, ErrEvicted
+	case !ok:
+		return nil, ErrNotFound
+	case val.expired(e.now()):
+		// the sweeper has not removed it yet.
+		return nil, ErrEvicted
+	}
 
-	return newID, nil
+	if e.limited() {
+		e.lru.touch(uid)
+	}
+
+	return &val, nil
 }
 
-func (e *Engine) Get(ctx context.Context, uid uuid.UUID) (*receipt.Points, error) {
-	if _, deadLineSet := ctx.Deadline(); !deadLineSet {
-		var cancel context.CancelFunc
+func (e *Engine) FindByKey(ctx context.Context, key string) (uuid.UUID, error) {
+	if ctx.Err() != nil {
+		return uuid.Nil, errTimeOut
+	}
 
-		ctx, cancel = context.WithTimeout(ctx, e.opTimeOut)
-		defer cancel()
+	if err := e.begin(); err != nil {
+		return uuid.Nil, err
 	}
+	defer e.end()
+
+	shard := &e.keys[e.shardIndex(key)]
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
 
-	getRequest := request{
-		ctx: ctx,
-		op:  get,
-		in:  make(chan payload),
-		out: make(chan payload),
+	id, ok := shard.keys[key]
+	if !ok {
+		return uuid.Nil, ErrNotFound
 	}
 
-	pload := payload{
-		id:   uid,
-		data: nil,
-		err:  nil,
+	return id, nil
+}
+
+func (e *Engine) FindMatching(ctx context.Context, rcpt receipt.Receipt) ([]uuid.UUID, error) {
+	if ctx.Err() != nil {
+		return nil, errTimeOut
 	}
 
-	select {
-	case <-ctx.Done():
+	if err := e.begin(); err != nil {
+		return nil, err
+	}
+	defer e.end()
+
+	matchKey := rcpt.MatchKey()
+	match := &e.matches[e.shardIndex(matchKey)]
+	match.mu.RLock()
+	defer match.mu.RUnlock()
+
+	return append([]uuid.UUID(nil), match.matches[matchKey]...), nil
+}
+
+// Ping fails once the engine is closed.
+func (e *Engine) Ping(ctx context.Context) error {
+	if ctx.Err() != nil {
+		return errTimeOut
+	}
+
+	if err := e.begin(); err != nil {
+		return err
+	}
+
+	e.end()
+
+	return nil
+}
+
+func (e *Engine) Flagged(ctx context.Context) ([]receipt.FlaggedReceipt, error) {
+	if ctx.Err() != nil {
 		return nil, errTimeOut
-	case e.req <- getRequest:
-		select {
-		case getRequest.in <- pload:
-			close(getRequest.in)
-		case <-ctx.Done():
-			return nil, ctx.Err()
-		}
 	}
 
-	data := <-getRequest.out
+	if err := e.begin(); err != nil {
+		return nil, err
+	}
+	defer e.end()
+
+	e.flaggedMu.RLock()
+	ids := append([]uuid.UUID(nil), e.flagged...)
+	e.flaggedMu.RUnlock()
+
+	result := make([]receipt.FlaggedReceipt, 0, len(ids))
+	now := e.now()
+
+	for _, id := range ids {
+		shard := e.recordShard(id)
+		shard.mu.RLock()
+		rec, ok := shard.records[id]
+		shard.mu.RUnlock()
+
+		if ok && !rec.expired(now) {
+			result = append(result, receipt.FlaggedReceipt{ID: id, Points: rec.points})
+		}
+	}
 
-	return data.data, data.err
+	return result, nil
 }
@@ -2,13 +2,36 @@ package memory
 
 import (
 	"context"
+	"fmt"
 	"receipt-processor-challenge/internal/domain/receipt"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
 )
 
-var id uuid.UUID
+// the sinks are written by every goroutine of the parallel benchmarks.
+var (
+	sinkMu sync.Mutex
+	id     uuid.UUID
+	points *receipt.Points
+)
+
+// benchRetailers is the number of retailers of the parallel benchmarks, the
+// receipts spread over as many match keys.
+const benchRetailers = 4096
+
+func benchReceipt(retailer string) receipt.Receipt {
+	return receipt.Receipt{
+		Retailer:     retailer,
+		PurchaseDate: time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC),
+		PurchaseTime: time.Date(0, 1, 1, 13, 1, 0, 0, time.UTC),
+		Items:        []receipt.Item{{ShortDescription: "Mountain Dew 12PK", Price: 649}},
+		Total:        649,
+	}
+}
 
 func BenchmarkSave(b *testing.B) {
 	ctx, cancel := context.WithCancel(context.Background())
@@ -16,9 +39,83 @@ func BenchmarkSave(b *testing.B) {
 
 	var newID uuid.UUID
 	for i := 0; i < b.N; i++ {
-		newID, _ = store.Save(ctx, receipt.Points{Points: 10})
+		newID, _ = store.Save(ctx, receipt.Receipt{}, receipt.Points{Points: 10})
 	}
 
 	id = newID
 	cancel()
 }
+
+// benchReceipts returns receipts of benchRetailers different retailers.
+func benchReceipts() []receipt.Receipt {
+	receipts := make([]receipt.Receipt, benchRetailers)
+	for i := range receipts {
+		receipts[i] = benchReceipt(fmt.Sprintf("Retailer %d", i))
+	}
+
+	return receipts
+}
+
+// BenchmarkSaveParallel saves from GOMAXPROCS goroutines, run it with
+// -cpu 1,2,4,8 to see how the throughput scales with the cores. The receipts
+// have different retailers so the saves spread over the match key shards.
+func BenchmarkSaveParallel(b *testing.B) {
+	ctx, cancel := context.WithCancel(context.Background())
+	store := New(ctx)
+	receipts := benchReceipts()
+
+	// every goroutine starts at its own retailer.
+	var goroutines atomic.Int64
+
+	b.ResetTimer()
+
+	b.RunParallel(func(pb *testing.PB) {
+		var newID uuid.UUID
+
+		i := int(goroutines.Add(1)) * (benchRetailers / 64)
+		for pb.Next() {
+			newID, _ = store.Save(ctx, receipts[i%benchRetailers], receipt.Points{Points: 10})
+			i++
+		}
+
+		sinkMu.Lock()
+		id = newID
+		sinkMu.Unlock()
+	})
+
+	cancel()
+}
+
+// BenchmarkGetParallel reads from GOMAXPROCS goroutines, run it with
+// -cpu 1,2,4,8 to see how the throughput scales with the cores.
+func BenchmarkGetParallel(b *testing.B) {
+	ctx, cancel := context.WithCancel(context.Background())
+	store := New(ctx)
+
+	const stored = 1024
+
+	ids := make([]uuid.UUID, stored)
+	for i := range ids {
+		ids[i], _ = store.Save(ctx, benchReceipt(fmt.Sprintf("Retailer %d", i)), receipt.Points{Points: i})
+	}
+
+	b.ResetTimer()
+
+	b.RunParallel(func(pb *testing.PB) {
+		var (
+			pts *receipt.Points
+			i   int
+		)
+
+		for pb.Next() {
+			pts, _ = store.Get(ctx, ids[i%stored])
+			i++
+		}
+
+		sinkMu.Lock()
+		points = pts
+		sinkMu.Unlock()
+	})
+
+	cancel()
+}
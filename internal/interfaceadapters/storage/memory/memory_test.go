@@ -3,27 +3,27 @@ package memory_test
 import (
 	"context"
 	"errors"
-	"os"
+	"sync"
 	"testing"
 	"time"
 
 	"receipt-processor-challenge/internal/domain/receipt"
 	. "receipt-processor-challenge/internal/interfaceadapters/storage/memory"
+	"receipt-processor-challenge/internal/interfaceadapters/storage/storagetest"
 
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 )
 
-var mStorage *Engine
+func newEngine(t *testing.T, opts ...Option) *Engine {
+	t.Helper()
 
-func TestMain(m *testing.M) {
-	ctx, cancel := context.WithCancel(context.Background())
-
-	mStorage = New(ctx)
-	code := m.Run()
+	engine := New(context.Background(), opts...)
+	t.Cleanup(func() {
+		assert.NoError(t, engine.Close())
+	})
 
-	cancel()
-	os.Exit(code)
+	return engine
 }
 
 func Test_Save(t *testing.T) {
@@ -74,8 +74,10 @@ func Test_Save(t *testing.T) {
 		t.Run(c.name, func(t *testing.T) {
 			defer cancel()
 
+			mStorage := newEngine(t)
+
 			for i := 0; i < len(input); i++ {
-				uuid, err := mStorage.Save(ctx, input[i])
+				uuid, err := mStorage.Save(ctx, receipt.Receipt{}, input[i])
 
 				assert.NotNil(t, uuid)
 				assert.Equal(t, expectedResult[i], err)
@@ -86,13 +88,14 @@ func Test_Save(t *testing.T) {
 
 func Test_Get(t *testing.T) {
 	ctx := context.Background()
+	mStorage := newEngine(t)
 
 	point, err := mStorage.Get(ctx, uuid.New())
 	assert.Equal(t, errors.New("points not found"), err)
 	assert.Nil(t, point)
 
 	newPoint := receipt.Points{Points: 199}
-	newID, err := mStorage.Save(ctx, newPoint)
+	newID, err := mStorage.Save(ctx, receipt.Receipt{}, newPoint)
 	assert.NoError(t, err)
 	assert.NotEqual(t, uuid.Nil, newID)
 
@@ -100,3 +103,240 @@ func Test_Get(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, newPoint.Points, lastPoint.Points)
 }
+
+func Test_GetReceipt(t *testing.T) {
+	ctx := context.Background()
+	mStorage := newEngine(t)
+
+	rcpt, err := mStorage.GetReceipt(ctx, uuid.New())
+	assert.Equal(t, ErrNotFound, err)
+	assert.Nil(t, rcpt)
+
+	newReceipt := receipt.Receipt{
+		Retailer:     "Target",
+		PurchaseDate: time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC),
+		PurchaseTime: time.Date(0, 1, 1, 13, 1, 0, 0, time.UTC),
+		Items: []receipt.Item{
+			{ShortDescription: "Mountain Dew 12PK", Price: money(t, "6.49")},
+		},
+		Total: money(t, "6.49"),
+	}
+	newID, err := mStorage.Save(ctx, newReceipt, receipt.Points{Points: 10})
+	assert.NoError(t, err)
+
+	lastReceipt, err := mStorage.GetReceipt(ctx, newID)
+	assert.NoError(t, err)
+	assert.Equal(t, newReceipt, *lastReceipt)
+}
+
+func money(t *testing.T, amount string) receipt.Money {
+	t.Helper()
+
+	m, err := receipt.ParseMoney(amount)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return m
+}
+
+func Test_Repository(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) receipt.Repository {
+		return newEngine(t)
+	})
+}
+
+func Test_Independent(t *testing.T) {
+	ctx := context.Background()
+	first := newEngine(t)
+	second := newEngine(t)
+
+	id, err := first.Save(ctx, receipt.Receipt{}, receipt.Points{Points: 5}, "key-a")
+	assert.NoError(t, err)
+
+	_, err = second.Get(ctx, id)
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	_, err = second.Save(ctx, receipt.Receipt{}, receipt.Points{Points: 5}, "key-a")
+	assert.NoError(t, err)
+}
+
+func Test_Close(t *testing.T) {
+	ctx := context.Background()
+	engine := New(ctx)
+
+	id, err := engine.Save(ctx, receipt.Receipt{}, receipt.Points{Points: 5})
+	assert.NoError(t, err)
+
+	assert.NoError(t, engine.Ping(ctx))
+	assert.NoError(t, engine.Close())
+	assert.NoError(t, engine.Close())
+	assert.ErrorIs(t, engine.Ping(ctx), ErrClosed)
+
+	_, err = engine.Save(ctx, receipt.Receipt{}, receipt.Points{Points: 5})
+	assert.ErrorIs(t, err, ErrClosed)
+
+	_, err = engine.Get(ctx, id)
+	assert.ErrorIs(t, err, ErrClosed)
+}
+
+func Test_CloseWithContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	engine := New(ctx)
+
+	cancel()
+
+	assert.Eventually(t, func() bool {
+		_, err := engine.Get(context.Background(), uuid.New())
+
+		return errors.Is(err, ErrClosed)
+	}, time.Second, time.Millisecond)
+}
+
+func Test_ShutdownDrains(t *testing.T) {
+	ctx := context.Background()
+	engine := newEngine(t, WithTimeOut(time.Second))
+
+	_, err := engine.Save(ctx, receipt.Receipt{}, receipt.Points{Points: 5})
+	assert.NoError(t, err)
+
+	const saves = 20
+
+	wg := sync.WaitGroup{}
+	errs := make([]error, saves)
+
+	for i := 0; i < saves; i++ {
+		wg.Add(1)
+
+		go func(i int) {
+			defer wg.Done()
+
+			_, errs[i] = engine.Save(ctx, receipt.Receipt{}, receipt.Points{Points: i})
+		}(i)
+	}
+
+	assert.NoError(t, engine.Shutdown(ctx))
+	wg.Wait()
+
+	// every save either finished before the shutdown or was rejected.
+	for _, err := range errs {
+		if err != nil {
+			assert.ErrorIs(t, err, ErrClosed)
+		}
+	}
+}
+
+func Test_Capacity(t *testing.T) {
+	ctx := context.Background()
+	engine := newEngine(t, WithCapacity(2))
+
+	firstID, err := engine.Save(ctx, receipt.Receipt{}, receipt.Points{Points: 1})
+	assert.NoError(t, err)
+
+	secondID, err := engine.Save(ctx, receipt.Receipt{}, receipt.Points{Points: 2}, "key-b")
+	assert.NoError(t, err)
+
+	// reading the first receipt makes the second one the least recently used.
+	_, err = engine.Get(ctx, firstID)
+	assert.NoError(t, err)
+
+	_, err = engine.Save(ctx, receipt.Receipt{}, receipt.Points{Points: 3})
+	assert.NoError(t, err)
+
+	_, err = engine.Get(ctx, secondID)
+	assert.ErrorIs(t, err, ErrEvicted)
+
+	_, err = engine.Get(ctx, firstID)
+	assert.NoError(t, err)
+
+	// the keys of an evicted receipt can be used again.
+	_, err = engine.Save(ctx, receipt.Receipt{}, receipt.Points{Points: 4}, "key-b")
+	assert.NoError(t, err)
+
+	stats := engine.Stats()
+	assert.Equal(t, int64(2), stats.Entries)
+	assert.Equal(t, uint64(2), stats.Evicted)
+}
+
+func Test_MaxBytes(t *testing.T) {
+	ctx := context.Background()
+	engine := newEngine(t, WithMaxBytes(1200))
+
+	firstID, err := engine.Save(ctx, receipt.Receipt{Retailer: "Target"}, receipt.Points{Points: 1})
+	assert.NoError(t, err)
+
+	_, err = engine.Save(ctx, receipt.Receipt{Retailer: "Target"}, receipt.Points{Points: 2})
+	assert.NoError(t, err)
+
+	_, err = engine.Save(ctx, receipt.Receipt{Retailer: "Target"}, receipt.Points{Points: 3})
+	assert.NoError(t, err)
+
+	_, err = engine.Get(ctx, firstID)
+	assert.ErrorIs(t, err, ErrEvicted)
+	assert.LessOrEqual(t, engine.Stats().Bytes, int64(1200))
+
+	_, err = engine.Save(ctx, receipt.Receipt{Retailer: string(make([]byte, 2000))}, receipt.Points{})
+	assert.ErrorIs(t, err, ErrFull)
+}
+
+func Test_TTL(t *testing.T) {
+	ctx := context.Background()
+	engine := newEngine(t, WithTTL(20*time.Millisecond), WithSweepInterval(5*time.Millisecond))
+
+	rcpt := receipt.Receipt{Retailer: "Target"}
+	id, err := engine.Save(ctx, rcpt, receipt.Points{Points: 1, Duplicate: &receipt.Duplicate{}})
+	assert.NoError(t, err)
+
+	_, err = engine.Get(ctx, id)
+	assert.NoError(t, err)
+
+	assert.Eventually(t, func() bool {
+		return engine.Stats().Expired == 1
+	}, time.Second, time.Millisecond)
+
+	_, err = engine.Get(ctx, id)
+	assert.ErrorIs(t, err, ErrEvicted)
+
+	ids, err := engine.FindMatching(ctx, rcpt)
+	assert.NoError(t, err)
+	assert.Empty(t, ids)
+
+	flagged, err := engine.Flagged(ctx)
+	assert.NoError(t, err)
+	assert.Empty(t, flagged)
+	assert.Equal(t, int64(0), engine.Stats().Entries)
+}
+
+func Test_ExpiredBeforeSweep(t *testing.T) {
+	ctx := context.Background()
+	engine := newEngine(t, WithTTL(time.Millisecond), WithSweepInterval(time.Hour))
+
+	id, err := engine.Save(ctx, receipt.Receipt{}, receipt.Points{Points: 1})
+	assert.NoError(t, err)
+
+	time.Sleep(2 * time.Millisecond)
+
+	_, err = engine.Get(ctx, id)
+	assert.ErrorIs(t, err, ErrEvicted)
+}
+
+func Test_Tombstones(t *testing.T) {
+	ctx := context.Background()
+	engine := newEngine(t, WithCapacity(1), WithTombstones(1))
+
+	ids := make([]uuid.UUID, 3)
+
+	for i := range ids {
+		var err error
+
+		ids[i], err = engine.Save(ctx, receipt.Receipt{}, receipt.Points{Points: i})
+		assert.NoError(t, err)
+	}
+
+	// only the last removed id is remembered.
+	_, err := engine.Get(ctx, ids[0])
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	_, err = engine.Get(ctx, ids[1])
+	assert.ErrorIs(t, err, ErrEvicted)
+}
@@ -0,0 +1,337 @@
+package memory
+
+import (
+	"container/list"
+	"sync"
+	"time"
+
+	"receipt-processor-challenge/internal/domain/receipt"
+
+	"github.com/google/uuid"
+)
+
+const (
+	defaultSweepInterval = time.Minute
+	defaultTombstones    = 10000
+
+	// the estimated size of a record is the length of its strings plus a fixed
+	// overhead for the record, every item, award and key.
+	recordOverhead = 512
+	itemOverhead   = 32
+	awardOverhead  = 64
+	keyOverhead    = 64
+)
+
+// ErrEvicted is returned when the receipt was stored but has been removed by
+// the retention limits.
+var ErrEvicted = receipt.ErrEvicted
+
+// Stats are the counters of the retention limits.
+type Stats struct {
+	// Entries is the number of stored receipts.
+	Entries int64
+	// Bytes is the estimated size of the stored receipts.
+	Bytes int64
+	// Evicted is the number of receipts removed to respect the capacity or the
+	// byte budget.
+	Evicted uint64
+	// Expired is the number of receipts removed because their TTL passed.
+	Expired uint64
+}
+
+// WithTTL removes every receipt once ttl has passed since it was saved, they
+// are kept forever by default.
+func WithTTL(ttl time.Duration) Option {
+	return func(e *Engine) {
+		e.ttl = ttl
+	}
+}
+
+// WithMaxBytes limits the estimated size of the stored receipts, there is no
+// limit by default. The least recently used receipts are evicted to make room
+// and a receipt bigger than the budget is rejected with ErrFull.
+func WithMaxBytes(maxBytes int64) Option {
+	return func(e *Engine) {
+		e.maxBytes = maxBytes
+	}
+}
+
+// WithSweepInterval sets how often expired receipts are removed, every minute
+// or half the TTL when it is shorter.
+func WithSweepInterval(interval time.Duration) Option {
+	return func(e *Engine) {
+		e.sweepInterval = interval
+	}
+}
+
+// WithTombstones sets how many removed ids are remembered to answer ErrEvicted
+// instead of ErrNotFound, 10000 by default.
+func WithTombstones(tombstones int) Option {
+	return func(e *Engine) {
+		e.tombstones.size = tombstones
+	}
+}
+
+// Stats returns the current retention counters.
+func (e *Engine) Stats() Stats {
+	return Stats{
+		Entries: e.size.Load(),
+		Bytes:   e.bytes.Load(),
+		Evicted: e.evicted.Load(),
+		Expired: e.expired.Load(),
+	}
+}
+
+func (e *Engine) limited() bool {
+	return e.capacity > 0 || e.maxBytes > 0
+}
+
+func (e *Engine) retains() bool {
+	return e.limited() || e.ttl > 0
+}
+
+func (e *Engine) overLimit() bool {
+	return (e.capacity > 0 && e.size.Load() > int64(e.capacity)) ||
+		(e.maxBytes > 0 && e.bytes.Load() > e.maxBytes)
+}
+
+func (e *Engine) expiresAt() time.Time {
+	if e.ttl <= 0 {
+		return time.Time{}
+	}
+
+	return e.now().Add(e.ttl)
+}
+
+func (rec record) expired(now time.Time) bool {
+	return !rec.expires.IsZero() && !now.Before(rec.expires)
+}
+
+// evict removes the least recently used receipts until the engine is within
+// its limits.
+func (e *Engine) evict() {
+	for {
+		id, ok := e.lru.popIf(e.overLimit)
+		if !ok {
+			return
+		}
+
+		if e.remove(id) {
+			e.evicted.Add(1)
+		}
+	}
+}
+
+// sweep removes the expired receipts.
+func (e *Engine) sweep() {
+	now := e.now()
+	expired := []uuid.UUID{}
+
+	for i := range e.records {
+		shard := &e.records[i]
+		shard.mu.RLock()
+
+		for id, rec := range shard.records {
+			if rec.expired(now) {
+				expired = append(expired, id)
+			}
+		}
+
+		shard.mu.RUnlock()
+	}
+
+	for _, id := range expired {
+		if e.remove(id) {
+			e.expired.Add(1)
+		}
+	}
+}
+
+func (e *Engine) sweeper(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-e.done:
+			return
+		case <-ticker.C:
+			if e.begin() != nil {
+				return
+			}
+
+			e.sweep()
+			e.end()
+		}
+	}
+}
+
+// remove deletes the receipt and every index entry pointing to it, it reports
+// false when the receipt was already removed.
+func (e *Engine) remove(id uuid.UUID) bool {
+	shard := e.recordShard(id)
+	shard.mu.Lock()
+	rec, ok := shard.records[id]
+	delete(shard.records, id)
+	shard.mu.Unlock()
+
+	if !ok {
+		return false
+	}
+
+	unlock := e.lockKeys(rec.keys)
+
+	for _, key := range rec.keys {
+		keys := &e.keys[e.shardIndex(key)]
+		if keys.keys[key] == id {
+			delete(keys.keys, key)
+		}
+	}
+
+	unlock()
+
+	match := &e.matches[e.shardIndex(rec.matchKey)]
+	match.mu.Lock()
+	match.matches[rec.matchKey] = without(match.matches[rec.matchKey], id)
+
+	if len(match.matches[rec.matchKey]) == 0 {
+		delete(match.matches, rec.matchKey)
+	}
+
+	match.mu.Unlock()
+
+	if rec.points.Duplicate != nil {
+		e.flaggedMu.Lock()
+		e.flagged = without(e.flagged, id)
+		e.flaggedMu.Unlock()
+	}
+
+	e.lru.remove(id)
+	e.tombstones.add(id)
+	e.size.Add(-1)
+	e.bytes.Add(-rec.size)
+
+	return true
+}
+
+func without(ids []uuid.UUID, id uuid.UUID) []uuid.UUID {
+	for i := range ids {
+		if ids[i] == id {
+			return append(ids[:i], ids[i+1:]...)
+		}
+	}
+
+	return ids
+}
+
+func sizeOf(rcpt receipt.Receipt, points receipt.Points, keys []string) int64 {
+	size := recordOverhead + len(rcpt.Retailer) + len(points.RuleSetVersion)
+
+	for _, item := range rcpt.Items {
+		size += itemOverhead + len(item.ShortDescription)
+	}
+
+	for _, award := range points.Breakdown {
+		size += awardOverhead + len(award.Rule) + len(award.Description)
+
+		if award.Item != nil {
+			size += itemOverhead + len(award.Item.Item.ShortDescription)
+		}
+	}
+
+	for _, key := range keys {
+		size += keyOverhead + len(key)
+	}
+
+	return int64(size)
+}
+
+// lru keeps the ids in the order they were used, the most recent first.
+type lru struct {
+	mu    sync.Mutex
+	order list.List
+	elems map[uuid.UUID]*list.Element
+}
+
+func (l *lru) push(id uuid.UUID) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.elems == nil {
+		l.elems = make(map[uuid.UUID]*list.Element)
+	}
+
+	l.elems[id] = l.order.PushFront(id)
+}
+
+func (l *lru) touch(id uuid.UUID) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if elem, ok := l.elems[id]; ok {
+		l.order.MoveToFront(elem)
+	}
+}
+
+func (l *lru) remove(id uuid.UUID) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if elem, ok := l.elems[id]; ok {
+		l.order.Remove(elem)
+		delete(l.elems, id)
+	}
+}
+
+// popIf removes and returns the least recently used id while cond holds.
+func (l *lru) popIf(cond func() bool) (uuid.UUID, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	oldest := l.order.Back()
+	if oldest == nil || !cond() {
+		return uuid.Nil, false
+	}
+
+	id, _ := l.order.Remove(oldest).(uuid.UUID)
+	delete(l.elems, id)
+
+	return id, true
+}
+
+// tombstones remembers the last removed ids.
+type tombstones struct {
+	mu   sync.RWMutex
+	size int
+	ring []uuid.UUID
+	next int
+	ids  map[uuid.UUID]struct{}
+}
+
+func (t *tombstones) add(id uuid.UUID) {
+	if t.size <= 0 {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.ids == nil {
+		t.ring = make([]uuid.UUID, t.size)
+		t.ids = make(map[uuid.UUID]struct{}, t.size)
+	}
+
+	delete(t.ids, t.ring[t.next])
+	t.ring[t.next] = id
+	t.ids[id] = struct{}{}
+	t.next = (t.next + 1) % t.size
+}
+
+func (t *tombstones) contains(id uuid.UUID) bool {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	_, ok := t.ids[id]
+
+	return ok
+}
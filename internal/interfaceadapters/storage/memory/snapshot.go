@@ -0,0 +1,218 @@
+package memory
+
+import (
+	"context"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"receipt-processor-challenge/internal/domain/receipt"
+
+	"github.com/google/uuid"
+)
+
+// snapshotVersion is the version of the snapshot format.
+const snapshotVersion int = 1
+
+var ErrSnapshot = errors.New("invalid snapshot")
+
+// snapshotHeader is the first value of a snapshot, the entries follow it.
+type snapshotHeader struct {
+	Version int
+	Entries int
+}
+
+// snapshotEntry is a stored receipt with everything needed to index it again.
+type snapshotEntry struct {
+	ID      uuid.UUID
+	Keys    []string
+	Receipt receipt.Receipt
+	Points  receipt.Points
+	Expires time.Time
+}
+
+/*
+Export writes every stored receipt to w as a gob stream: a header with the
+format version followed by one entry per receipt in the order they were saved.
+Receipts saved while exporting may be left out.
+*/
+func (e *Engine) Export(ctx context.Context, w io.Writer) error {
+	if err := e.begin(); err != nil {
+		return err
+	}
+	defer e.end()
+
+	type ordered struct {
+		seq   uint64
+		entry snapshotEntry
+	}
+
+	now := e.now()
+	entries := []ordered{}
+
+	for i := range e.records {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		shard := &e.records[i]
+		shard.mu.RLock()
+
+		for id, rec := range shard.records {
+			if rec.expired(now) {
+				continue
+			}
+
+			entries = append(entries, ordered{seq: rec.seq, entry: snapshotEntry{
+				ID:      id,
+				Keys:    rec.keys,
+				Receipt: rec.receipt,
+				Points:  rec.points,
+				Expires: rec.expires,
+			}})
+		}
+
+		shard.mu.RUnlock()
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].seq < entries[j].seq
+	})
+
+	encoder := gob.NewEncoder(w)
+	if err := encoder.Encode(snapshotHeader{Version: snapshotVersion, Entries: len(entries)}); err != nil {
+		return err
+	}
+
+	for i := range entries {
+		if err := encoder.Encode(entries[i].entry); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+/*
+Import adds the receipts of a snapshot written by Export keeping their ids,
+submission keys and order. Expired receipts and receipts whose id or keys are
+already stored are skipped, the retention limits apply once every receipt is
+added.
+*/
+func (e *Engine) Import(ctx context.Context, r io.Reader) error {
+	if err := e.begin(); err != nil {
+		return err
+	}
+	defer e.end()
+
+	decoder := gob.NewDecoder(r)
+	header := snapshotHeader{}
+
+	if err := decoder.Decode(&header); err != nil {
+		return fmt.Errorf("%s:%w", err.Error(), ErrSnapshot)
+	}
+
+	if header.Version != snapshotVersion {
+		return fmt.Errorf("version %d:%w", header.Version, ErrSnapshot)
+	}
+
+	now := e.now()
+
+	for i := 0; i < header.Entries; i++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		entry := snapshotEntry{}
+		if err := decoder.Decode(&entry); err != nil {
+			return fmt.Errorf("entry %d %s:%w", i, err.Error(), ErrSnapshot)
+		}
+
+		rec := record{
+			receipt:  entry.Receipt,
+			points:   entry.Points,
+			keys:     entry.Keys,
+			matchKey: entry.Receipt.MatchKey(),
+			expires:  entry.Expires,
+		}
+
+		if rec.expired(now) || e.exists(entry.ID) {
+			continue
+		}
+
+		if e.retains() {
+			rec.size = sizeOf(rec.receipt, rec.points, rec.keys)
+		}
+
+		if _, err := e.store(ctx, entry.ID, rec); err != nil && !errors.Is(err, receipt.ErrDuplicated) {
+			return err
+		}
+	}
+
+	if e.limited() {
+		e.evict()
+	}
+
+	return nil
+}
+
+// SaveSnapshot exports the receipts to the file at path, the previous file is
+// replaced only once the new one is complete.
+func (e *Engine) SaveSnapshot(ctx context.Context, path string) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+
+	defer os.Remove(tmp.Name())
+
+	if err := e.Export(ctx, tmp); err != nil {
+		tmp.Close()
+
+		return err
+	}
+
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+// LoadSnapshot imports the receipts of the file at path, a missing file is
+// not an error.
+func (e *Engine) LoadSnapshot(ctx context.Context, path string) error {
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	defer file.Close()
+
+	return e.Import(ctx, file)
+}
+
+func (e *Engine) exists(id uuid.UUID) bool {
+	shard := e.recordShard(id)
+	shard.mu.RLock()
+	defer shard.mu.RUnlock()
+
+	_, ok := shard.records[id]
+
+	return ok
+}
@@ -0,0 +1,146 @@
+package memory_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"receipt-processor-challenge/internal/domain/receipt"
+	. "receipt-processor-challenge/internal/interfaceadapters/storage/memory"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func snapshotReceipt(t *testing.T) receipt.Receipt {
+	t.Helper()
+
+	return receipt.Receipt{
+		Retailer:     "Target",
+		PurchaseDate: time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC),
+		PurchaseTime: time.Date(0, 1, 1, 13, 1, 0, 0, time.UTC),
+		Items: []receipt.Item{
+			{ShortDescription: "Mountain Dew 12PK", Price: money(t, "6.49")},
+		},
+		Total: money(t, "6.49"),
+	}
+}
+
+func Test_ExportImport(t *testing.T) {
+	ctx := context.Background()
+	source := newEngine(t)
+	rcpt := snapshotReceipt(t)
+
+	ids := make([]uuid.UUID, 3)
+	for i := range ids {
+		var err error
+
+		points := receipt.Points{
+			Points:    i,
+			Breakdown: []receipt.Award{{Rule: "rule", Points: i, Item: &receipt.AwardedItem{Item: rcpt.Items[0]}}},
+			Duplicate: &receipt.Duplicate{Of: uuid.New(), Similarity: 1, Policy: "flag"},
+		}
+
+		ids[i], err = source.Save(ctx, rcpt, points, fmt.Sprintf("key-%d", i))
+		assert.NoError(t, err)
+	}
+
+	buf := bytes.Buffer{}
+	assert.NoError(t, source.Export(ctx, &buf))
+
+	target := newEngine(t)
+	assert.NoError(t, target.Import(ctx, &buf))
+
+	for i, id := range ids {
+		points, err := target.Get(ctx, id)
+		assert.NoError(t, err)
+		assert.Equal(t, i, points.Points)
+
+		stored, err := target.GetReceipt(ctx, id)
+		assert.NoError(t, err)
+		assert.Equal(t, rcpt, *stored)
+	}
+
+	matching, err := target.FindMatching(ctx, rcpt)
+	assert.NoError(t, err)
+	assert.Equal(t, ids, matching)
+
+	flagged, err := target.Flagged(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, flagged, len(ids))
+
+	for i := range flagged {
+		assert.Equal(t, ids[i], flagged[i].ID)
+	}
+
+	// the submission keys are restored.
+	id, err := target.Save(ctx, rcpt, receipt.Points{}, "key-0")
+	assert.ErrorIs(t, err, receipt.ErrDuplicated)
+	assert.Equal(t, ids[0], id)
+}
+
+func Test_ImportSkipsExpired(t *testing.T) {
+	ctx := context.Background()
+	source := newEngine(t, WithTTL(time.Hour))
+
+	id, err := source.Save(ctx, receipt.Receipt{}, receipt.Points{Points: 1})
+	assert.NoError(t, err)
+
+	buf := bytes.Buffer{}
+	assert.NoError(t, source.Export(ctx, &buf))
+
+	// the entries keep their expiration, an hour from now.
+	target := newEngine(t)
+	assert.NoError(t, target.Import(ctx, &buf))
+
+	_, err = target.Get(ctx, id)
+	assert.NoError(t, err)
+
+	shortTTL := newEngine(t, WithTTL(time.Millisecond))
+	_, err = shortTTL.Save(ctx, receipt.Receipt{}, receipt.Points{Points: 1})
+	assert.NoError(t, err)
+
+	time.Sleep(2 * time.Millisecond)
+
+	buf.Reset()
+	assert.NoError(t, shortTTL.Export(ctx, &buf))
+	assert.NoError(t, target.Import(ctx, &buf))
+	assert.Equal(t, int64(1), target.Stats().Entries)
+}
+
+func Test_ImportInvalid(t *testing.T) {
+	ctx := context.Background()
+	engine := newEngine(t)
+
+	err := engine.Import(ctx, bytes.NewBufferString("not a snapshot"))
+	assert.ErrorIs(t, err, ErrSnapshot)
+
+	buf := bytes.Buffer{}
+	assert.NoError(t, gob.NewEncoder(&buf).Encode(struct{ Version int }{Version: 99}))
+
+	err = engine.Import(ctx, &buf)
+	assert.ErrorIs(t, err, ErrSnapshot)
+}
+
+func Test_SaveLoadSnapshot(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "memory.snapshot")
+
+	engine := newEngine(t)
+	assert.NoError(t, engine.LoadSnapshot(ctx, path))
+
+	id, err := engine.Save(ctx, receipt.Receipt{}, receipt.Points{Points: 7})
+	assert.NoError(t, err)
+	assert.NoError(t, engine.SaveSnapshot(ctx, path))
+
+	loaded := newEngine(t)
+	assert.NoError(t, loaded.LoadSnapshot(ctx, path))
+
+	points, err := loaded.Get(ctx, id)
+	assert.NoError(t, err)
+	assert.Equal(t, 7, points.Points)
+}
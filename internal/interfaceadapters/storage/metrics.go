@@ -0,0 +1,38 @@
+package storage
+
+import (
+	"receipt-processor-challenge/internal/interfaceadapters/metrics"
+)
+
+// RegisterMetrics adds the counters of the cache and of the memory retention
+// limits to registry, when they are in use.
+func (s Service) RegisterMetrics(registry *metrics.Registry) {
+	if s.Cache != nil {
+		registry.NewCounterFunc("receipt_cache_hits_total", "Reads answered by the cache.", func() float64 {
+			return float64(s.Cache.Stats().Hits)
+		})
+		registry.NewCounterFunc("receipt_cache_misses_total", "Reads that missed the cache.", func() float64 {
+			return float64(s.Cache.Stats().Misses)
+		})
+		registry.NewCounterFunc("receipt_cache_errors_total", "Cache backend failures.", func() float64 {
+			return float64(s.Cache.Stats().Errors)
+		})
+	}
+
+	if s.Memory != nil {
+		registry.NewGaugeFunc("receipt_memory_entries", "Receipts stored in memory.", func() float64 {
+			return float64(s.Memory.Stats().Entries)
+		})
+		registry.NewGaugeFunc("receipt_memory_bytes", "Estimated size of the receipts in memory.", func() float64 {
+			return float64(s.Memory.Stats().Bytes)
+		})
+		registry.NewCounterFunc("receipt_memory_evicted_total",
+			"Receipts evicted to respect the capacity or the byte budget.", func() float64 {
+				return float64(s.Memory.Stats().Evicted)
+			})
+		registry.NewCounterFunc("receipt_memory_expired_total", "Receipts removed once their TTL passed.",
+			func() float64 {
+				return float64(s.Memory.Stats().Expired)
+			})
+	}
+}
@@ -0,0 +1,56 @@
+/*
+Package migrate reads the schema migrations of the SQL storages. Every storage
+embeds its own migrations and applies them in its own dialect.
+*/
+package migrate
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var ErrMigration = errors.New("migration error")
+
+type Migration struct {
+	Version int
+	Name    string
+	Script  string
+}
+
+// Load returns the migrations of the dir directory of fsys sorted by version,
+// file names start with the version, e.g. "0001_receipts.sql".
+func Load(fsys fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]Migration, 0, len(entries))
+
+	for _, entry := range entries {
+		prefix, _, _ := strings.Cut(entry.Name(), "_")
+
+		version, err := strconv.Atoi(prefix)
+		if err != nil {
+			return nil, fmt.Errorf("%s version:%w", entry.Name(), ErrMigration)
+		}
+
+		script, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, Migration{Version: version, Name: entry.Name(), Script: string(script)})
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Version < result[j].Version
+	})
+
+	return result, nil
+}
@@ -0,0 +1,57 @@
+package migrate_test
+
+import (
+	"testing"
+	"testing/fstest"
+
+	. "receipt-processor-challenge/internal/interfaceadapters/storage/migrate"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Load(t *testing.T) {
+	fsys := fstest.MapFS{
+		"migrations/0010_indexes.sql":  {Data: []byte("CREATE INDEX i ON receipts (id);")},
+		"migrations/0002_points.sql":   {Data: []byte("CREATE TABLE points (id TEXT);")},
+		"migrations/0001_receipts.sql": {Data: []byte("CREATE TABLE receipts (id TEXT);")},
+	}
+
+	migrations, err := Load(fsys, "migrations")
+	assert.NoError(t, err)
+	assert.Equal(t, []Migration{
+		{Version: 1, Name: "0001_receipts.sql", Script: "CREATE TABLE receipts (id TEXT);"},
+		{Version: 2, Name: "0002_points.sql", Script: "CREATE TABLE points (id TEXT);"},
+		{Version: 10, Name: "0010_indexes.sql", Script: "CREATE INDEX i ON receipts (id);"},
+	}, migrations)
+}
+
+func Test_LoadErrors(t *testing.T) {
+	cases := []struct {
+		name     string
+		fsys     fstest.MapFS
+		expected error
+	}{
+		{
+			name:     "bad-version-case",
+			fsys:     fstest.MapFS{"migrations/receipts.sql": {Data: []byte("")}},
+			expected: ErrMigration,
+		},
+		{
+			name: "missing-dir-case",
+			fsys: fstest.MapFS{},
+		},
+	}
+
+	for _, c := range cases {
+		c := c
+
+		t.Run(c.name, func(t *testing.T) {
+			_, err := Load(c.fsys, "migrations")
+			assert.Error(t, err)
+
+			if c.expected != nil {
+				assert.ErrorIs(t, err, c.expected)
+			}
+		})
+	}
+}
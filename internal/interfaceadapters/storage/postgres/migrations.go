@@ -0,0 +1,74 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+
+	"receipt-processor-challenge/internal/interfaceadapters/storage/migrate"
+)
+
+// migrationLock is the advisory lock held while migrating, it keeps several
+// instances starting at once from applying the same migration.
+const migrationLock int64 = 7_364_201
+
+//go:embed migrations/*.sql
+var migrations embed.FS
+
+// ErrMigration is migrate.ErrMigration, returned when a migration can not be
+// read or applied.
+//
+//nolint:gochecknoglobals
+var ErrMigration = migrate.ErrMigration
+
+// migrateSchema applies the migrations newer than the schema version in a
+// single transaction.
+func migrateSchema(ctx context.Context, db *sql.DB) error {
+	all, err := migrate.Load(migrations, "migrations")
+	if err != nil {
+		return err
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback() //nolint:errcheck // no-op once committed.
+
+	if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock($1)`, migrationLock); err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    INTEGER     PRIMARY KEY,
+		name       TEXT        NOT NULL,
+		applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+	)`); err != nil {
+		return err
+	}
+
+	current := 0
+	if err := tx.QueryRowContext(ctx, `SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&current); err != nil {
+		return err
+	}
+
+	for _, m := range all {
+		if m.Version <= current {
+			continue
+		}
+
+		if _, err := tx.ExecContext(ctx, m.Script); err != nil {
+			return fmt.Errorf("%s: %s:%w", m.Name, err.Error(), ErrMigration)
+		}
+
+		if _, err := tx.ExecContext(ctx,
+			`INSERT INTO schema_migrations (version, name) VALUES ($1, $2)`, m.Version, m.Name,
+		); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,46 @@
+CREATE TABLE receipts (
+    seq           BIGSERIAL   UNIQUE,
+    id            UUID        PRIMARY KEY,
+    retailer      TEXT        NOT NULL,
+    purchase_date DATE        NOT NULL,
+    purchase_time TIME        NOT NULL,
+    total         BIGINT      NOT NULL,
+    match_key     TEXT        NOT NULL,
+    created_at    TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX receipts_match_key ON receipts (match_key);
+
+CREATE TABLE items (
+    receipt_id        UUID    NOT NULL REFERENCES receipts (id) ON DELETE CASCADE,
+    position          INTEGER NOT NULL,
+    short_description TEXT    NOT NULL,
+    price             BIGINT  NOT NULL,
+    PRIMARY KEY (receipt_id, position)
+);
+
+CREATE TABLE points (
+    receipt_id       UUID             PRIMARY KEY REFERENCES receipts (id) ON DELETE CASCADE,
+    points           INTEGER          NOT NULL,
+    rule_set_version TEXT             NOT NULL,
+    duplicate_of     UUID,
+    similarity       DOUBLE PRECISION,
+    duplicate_policy TEXT
+);
+
+CREATE INDEX points_flagged ON points (receipt_id) WHERE duplicate_of IS NOT NULL;
+
+CREATE TABLE awards (
+    receipt_id  UUID    NOT NULL REFERENCES receipts (id) ON DELETE CASCADE,
+    position    INTEGER NOT NULL,
+    rule        TEXT    NOT NULL,
+    description TEXT    NOT NULL,
+    points      INTEGER NOT NULL,
+    item_index  INTEGER,
+    PRIMARY KEY (receipt_id, position)
+);
+
+CREATE TABLE submission_keys (
+    key        TEXT PRIMARY KEY,
+    receipt_id UUID NOT NULL REFERENCES receipts (id) ON DELETE CASCADE
+);
@@ -0,0 +1,149 @@
+package postgres_test
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"receipt-processor-challenge/internal/domain/receipt"
+	. "receipt-processor-challenge/internal/interfaceadapters/storage/postgres"
+	"receipt-processor-challenge/internal/interfaceadapters/storage/storagetest"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+// envDSN points the integration tests to a Postgres server, they are skipped
+// when it is not set. scripts/integration-test starts a local container and
+// sets it.
+const envDSN string = "POSTGRES_TEST_DSN"
+
+// newRepository connects to the test server using a new schema that is
+// dropped when the test ends.
+func newRepository(t *testing.T) (*Repository, string) {
+	t.Helper()
+
+	dsn := os.Getenv(envDSN)
+	if dsn == "" {
+		t.Skipf("%s is not set", envDSN)
+	}
+
+	ctx := context.Background()
+	schema := "receipts_test_" + strings.ReplaceAll(uuid.NewString(), "-", "")
+
+	admin, err := sql.Open("pgx", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := admin.ExecContext(ctx, "CREATE SCHEMA "+schema); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		_, _ = admin.ExecContext(ctx, "DROP SCHEMA "+schema+" CASCADE")
+		admin.Close()
+	})
+
+	separator := "?"
+	if strings.Contains(dsn, "?") {
+		separator = "&"
+	}
+
+	schemaDSN := fmt.Sprintf("%s%ssearch_path=%s", dsn, separator, schema)
+
+	repo, err := New(ctx, schemaDSN)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { repo.Close() })
+
+	return repo, schemaDSN
+}
+
+func Test_Repository(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) receipt.Repository {
+		repo, _ := newRepository(t)
+
+		return repo
+	})
+}
+
+func Test_Reopen(t *testing.T) {
+	ctx := context.Background()
+	repo, dsn := newRepository(t)
+
+	first, err := repo.Save(ctx, storagetest.NewReceipt("Market"), storagetest.NewPoints(10), "key-a")
+	assert.NoError(t, err)
+
+	flaggedPoints := storagetest.NewPoints(12)
+	flaggedPoints.Duplicate = &receipt.Duplicate{Of: first, Similarity: 0.9, Policy: "flag"}
+
+	second, err := repo.Save(ctx, storagetest.NewReceipt("Market"), flaggedPoints)
+	assert.NoError(t, err)
+
+	// reopening applies no migration twice and keeps the data.
+	reopened, err := New(ctx, dsn)
+	assert.NoError(t, err)
+
+	defer reopened.Close()
+
+	points, err := reopened.Get(ctx, first)
+	assert.NoError(t, err)
+	assert.Equal(t, storagetest.NewPoints(10), *points)
+
+	rcpt, err := reopened.GetReceipt(ctx, first)
+	assert.NoError(t, err)
+	assert.Equal(t, storagetest.NewReceipt("Market"), *rcpt)
+
+	id, err := reopened.FindByKey(ctx, "key-a")
+	assert.NoError(t, err)
+	assert.Equal(t, first, id)
+
+	matching, err := reopened.FindMatching(ctx, storagetest.NewReceipt("Market"))
+	assert.NoError(t, err)
+	assert.Equal(t, []uuid.UUID{first, second}, matching)
+
+	flagged, err := reopened.Flagged(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []receipt.FlaggedReceipt{{ID: second, Points: flaggedPoints}}, flagged)
+}
+
+func Test_Ping(t *testing.T) {
+	repo, _ := newRepository(t)
+	ctx := context.Background()
+
+	assert.NoError(t, repo.Ping(ctx))
+	assert.NoError(t, repo.Close())
+	assert.Error(t, repo.Ping(ctx))
+}
+
+func Test_ContextCanceled(t *testing.T) {
+	repo, _ := newRepository(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := repo.Save(ctx, storagetest.NewReceipt("Market"), storagetest.NewPoints(10))
+	assert.ErrorIs(t, err, context.Canceled)
+
+	_, err = repo.Get(ctx, uuid.New())
+	assert.ErrorIs(t, err, context.Canceled)
+
+	ctx, cancel = context.WithTimeout(context.Background(), time.Nanosecond)
+	defer cancel()
+
+	<-ctx.Done()
+
+	_, err = repo.FindMatching(ctx, storagetest.NewReceipt("Market"))
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	matching, err := repo.FindMatching(context.Background(), storagetest.NewReceipt("Market"))
+	assert.NoError(t, err)
+	assert.Empty(t, matching)
+}
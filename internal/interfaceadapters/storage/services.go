@@ -2,16 +2,208 @@ package storage
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"time"
+
 	"receipt-processor-challenge/internal/domain/receipt"
+	"receipt-processor-challenge/internal/interfaceadapters/storage/cache"
+	"receipt-processor-challenge/internal/interfaceadapters/storage/disk"
 	"receipt-processor-challenge/internal/interfaceadapters/storage/memory"
+	"receipt-processor-challenge/internal/interfaceadapters/storage/postgres"
+	"receipt-processor-challenge/internal/interfaceadapters/storage/sqlite"
+)
+
+const (
+	// BackendMemory keeps the receipts in memory, they are lost on restart.
+	BackendMemory string = "memory"
+	// BackendFile keeps the receipts in a local directory.
+	BackendFile string = "file"
+	// BackendSQLite keeps the receipts in a SQLite database.
+	BackendSQLite string = "sqlite"
+	// BackendPostgres keeps the receipts in a PostgreSQL database.
+	BackendPostgres string = "postgres"
+
+	// CacheLRU caches the reads in process.
+	CacheLRU string = "lru"
+	// CacheRedis caches the reads in a Redis compatible server.
+	CacheRedis string = "redis"
+
+	sqliteFile string = "receipts.db"
+)
+
+var (
+	ErrUnknownBackend = errors.New("unknown storage backend")
+	ErrMissingDSN     = errors.New("postgres storage needs a DSN")
+	ErrUnknownCache   = errors.New("unknown cache backend")
+	// ErrNoSnapshot is returned by Snapshot when the repository is not the
+	// memory backend or it has no snapshot file.
+	ErrNoSnapshot = errors.New("no snapshot configured")
 )
 
+// Config selects the repository implementation. Dir is the directory used by
+// the file and SQLite backends, DSN is the Postgres connection string or
+// overrides the SQLite database path, SnapshotEvery is only used by BackendFile.
+// MemorySnapshot is the file BackendMemory is loaded from at startup and saved
+// to on Close and on Snapshot, TimeOut is the time out of its operations.
+type Config struct {
+	Backend        string
+	Dir            string
+	DSN            string
+	SnapshotEvery  int
+	MemorySnapshot string
+	TimeOut        time.Duration
+	Retention      RetentionConfig
+	Cache          CacheConfig
+}
+
+// RetentionConfig limits the receipts kept by BackendMemory, zero values mean
+// no limit. Capacity is the maximum number of receipts and MaxBytes their
+// maximum estimated size.
+type RetentionConfig struct {
+	Capacity int
+	MaxBytes int64
+	TTL      time.Duration
+}
+
+// CacheConfig puts a cache in front of the repository when Backend is set.
+// Size is the capacity of CacheLRU and the connection pool size of CacheRedis.
+type CacheConfig struct {
+	Backend   string
+	Size      int
+	TTL       time.Duration
+	RedisAddr string
+}
+
 type Service struct {
 	Repository receipt.Repository
+	// Memory is the memory engine, nil with the other backends.
+	Memory *memory.Engine
+
+	snapshotPath string
+	// Cache is the cached repository, nil when there is no cache.
+	Cache *cache.Repository
+}
+
+func New(ctx context.Context, cfg Config) (Service, error) {
+	service, err := newRepository(ctx, cfg)
+	if err != nil || cfg.Cache.Backend == "" {
+		return service, err
+	}
+
+	var backend cache.Backend
+
+	switch cfg.Cache.Backend {
+	case CacheLRU:
+		backend = cache.NewLRU(cfg.Cache.Size)
+	case CacheRedis:
+		backend = cache.NewRedis(cfg.Cache.RedisAddr, cfg.Cache.Size)
+	default:
+		return Service{}, errors.Join(
+			fmt.Errorf("%s:%w", cfg.Cache.Backend, ErrUnknownCache), service.Close(),
+		)
+	}
+
+	service.Cache = cache.New(service.Repository, backend, cfg.Cache.TTL)
+	service.Repository = service.Cache
+
+	return service, nil
+}
+
+func newRepository(ctx context.Context, cfg Config) (Service, error) {
+	switch cfg.Backend {
+	case BackendMemory, "":
+		opts := []memory.Option{
+			memory.WithCapacity(cfg.Retention.Capacity),
+			memory.WithMaxBytes(cfg.Retention.MaxBytes),
+			memory.WithTTL(cfg.Retention.TTL),
+		}
+
+		if cfg.TimeOut > 0 {
+			opts = append(opts, memory.WithTimeOut(cfg.TimeOut))
+		}
+
+		engine := memory.New(ctx, opts...)
+
+		if cfg.MemorySnapshot != "" {
+			if err := engine.LoadSnapshot(ctx, cfg.MemorySnapshot); err != nil {
+				return Service{}, errors.Join(err, engine.Close())
+			}
+		}
+
+		return Service{Repository: engine, Memory: engine, snapshotPath: cfg.MemorySnapshot}, nil
+	case BackendFile:
+		store, err := disk.New(ctx, cfg.Dir, cfg.SnapshotEvery)
+		if err != nil {
+			return Service{}, err
+		}
+
+		return Service{Repository: store}, nil
+	case BackendSQLite:
+		path := cfg.DSN
+		if path == "" {
+			path = filepath.Join(cfg.Dir, sqliteFile)
+		}
+
+		repo, err := sqlite.New(ctx, path)
+		if err != nil {
+			return Service{}, err
+		}
+
+		return Service{Repository: repo}, nil
+	case BackendPostgres:
+		if cfg.DSN == "" {
+			return Service{}, ErrMissingDSN
+		}
+
+		repo, err := postgres.New(ctx, cfg.DSN)
+		if err != nil {
+			return Service{}, err
+		}
+
+		return Service{Repository: repo}, nil
+	}
+
+	return Service{}, fmt.Errorf("%s:%w", cfg.Backend, ErrUnknownBackend)
+}
+
+// Ping checks the repository.
+func (s Service) Ping(ctx context.Context) error {
+	return s.Repository.Ping(ctx)
+}
+
+// PingCache checks the cache, there is nothing to check without one.
+func (s Service) PingCache(ctx context.Context) error {
+	if s.Cache == nil {
+		return nil
+	}
+
+	return s.Cache.PingCache(ctx)
+}
+
+// Snapshot saves the memory backend to its snapshot file.
+func (s Service) Snapshot(ctx context.Context) error {
+	if s.Memory == nil || s.snapshotPath == "" {
+		return ErrNoSnapshot
+	}
+
+	return s.Memory.SaveSnapshot(ctx, s.snapshotPath)
 }
 
-func New(ctx context.Context) Service {
-	return Service{
-		Repository: memory.New(ctx),
+// Close releases the repository when it holds resources, the memory backend
+// is saved to its snapshot file first.
+func (s Service) Close() error {
+	if s.Memory != nil && s.snapshotPath != "" {
+		if err := s.Snapshot(context.Background()); err != nil {
+			return errors.Join(err, s.Memory.Close())
+		}
 	}
+
+	if closer, ok := s.Repository.(io.Closer); ok {
+		return closer.Close()
+	}
+
+	return nil
 }
@@ -0,0 +1,75 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+
+	"receipt-processor-challenge/internal/interfaceadapters/storage/migrate"
+)
+
+//go:embed migrations/*.sql
+var migrations embed.FS
+
+// ErrMigration is migrate.ErrMigration, returned when a migration can not be
+// read or applied.
+//
+//nolint:gochecknoglobals
+var ErrMigration = migrate.ErrMigration
+
+// migrateSchema applies the migrations newer than the schema version, each one
+// in its own transaction.
+func migrateSchema(ctx context.Context, db *sql.DB) error {
+	_, err := db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    INTEGER PRIMARY KEY,
+		name       TEXT    NOT NULL,
+		applied_at TEXT    NOT NULL DEFAULT CURRENT_TIMESTAMP
+	)`)
+	if err != nil {
+		return err
+	}
+
+	current := 0
+	if err := db.QueryRowContext(ctx, `SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&current); err != nil {
+		return err
+	}
+
+	all, err := migrate.Load(migrations, "migrations")
+	if err != nil {
+		return err
+	}
+
+	for _, m := range all {
+		if m.Version <= current {
+			continue
+		}
+
+		if err := apply(ctx, db, m); err != nil {
+			return fmt.Errorf("%s: %s:%w", m.Name, err.Error(), ErrMigration)
+		}
+	}
+
+	return nil
+}
+
+func apply(ctx context.Context, db *sql.DB, m migrate.Migration) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback() //nolint:errcheck // no-op once committed.
+
+	if _, err := tx.ExecContext(ctx, m.Script); err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx,
+		`INSERT INTO schema_migrations (version, name) VALUES (?, ?)`, m.Version, m.Name,
+	); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,37 @@
+CREATE TABLE receipts (
+    id            TEXT    PRIMARY KEY,
+    retailer      TEXT    NOT NULL,
+    purchase_date TEXT    NOT NULL,
+    purchase_time TEXT    NOT NULL,
+    total         INTEGER NOT NULL,
+    match_key     TEXT    NOT NULL
+);
+
+CREATE INDEX receipts_match_key ON receipts (match_key);
+
+CREATE TABLE items (
+    receipt_id        TEXT    NOT NULL REFERENCES receipts (id) ON DELETE CASCADE,
+    position          INTEGER NOT NULL,
+    short_description TEXT    NOT NULL,
+    price             INTEGER NOT NULL,
+    PRIMARY KEY (receipt_id, position)
+);
+
+CREATE TABLE points (
+    receipt_id       TEXT    PRIMARY KEY REFERENCES receipts (id) ON DELETE CASCADE,
+    points           INTEGER NOT NULL,
+    rule_set_version TEXT    NOT NULL,
+    duplicate_of     TEXT,
+    similarity       REAL,
+    duplicate_policy TEXT
+);
+
+CREATE TABLE awards (
+    receipt_id  TEXT    NOT NULL REFERENCES receipts (id) ON DELETE CASCADE,
+    position    INTEGER NOT NULL,
+    rule        TEXT    NOT NULL,
+    description TEXT    NOT NULL,
+    points      INTEGER NOT NULL,
+    item_index  INTEGER,
+    PRIMARY KEY (receipt_id, position)
+);
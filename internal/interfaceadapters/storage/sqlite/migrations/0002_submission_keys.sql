@@ -0,0 +1,6 @@
+CREATE TABLE submission_keys (
+    key        TEXT PRIMARY KEY,
+    receipt_id TEXT NOT NULL REFERENCES receipts (id) ON DELETE CASCADE
+);
+
+CREATE INDEX points_flagged ON points (duplicate_of) WHERE duplicate_of IS NOT NULL;
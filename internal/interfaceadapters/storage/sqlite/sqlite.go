@@ -0,0 +1,427 @@
+/*
+Package sqlite stores receipts in a SQLite database. The schema is created and
+upgraded at startup by the migrations embedded in the binary.
+*/
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"receipt-processor-challenge/internal/domain/receipt"
+
+	"github.com/google/uuid"
+	_ "modernc.org/sqlite" // registers the sqlite driver.
+)
+
+const (
+	driverName     string        = "sqlite"
+	defaultTimeOut time.Duration = time.Second
+)
+
+var errInvalidID = errors.New("invalid id")
+
+type Repository struct {
+	db        *sql.DB
+	opTimeOut time.Duration
+}
+
+// New opens the database in path, creating it and its directory when they do
+// not exist, and applies the pending migrations.
+func New(ctx context.Context, path string) (*Repository, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return nil, err
+	}
+
+	dsn := fmt.Sprintf(
+		"file:%s?_pragma=foreign_keys(1)&_pragma=journal_mode(WAL)&_pragma=busy_timeout(5000)", path,
+	)
+
+	db, err := sql.Open(driverName, dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	// SQLite has a single writer, one connection keeps the saves serialized
+	// instead of failing with a busy database.
+	db.SetMaxOpenConns(1)
+
+	if err := migrateSchema(ctx, db); err != nil {
+		db.Close()
+
+		return nil, err
+	}
+
+	return &Repository{db: db, opTimeOut: defaultTimeOut}, nil
+}
+
+// withTimeOut applies the default time out to contexts without deadline.
+func (r *Repository) withTimeOut(ctx context.Context) (context.Context, context.CancelFunc) {
+	if _, deadLineSet := ctx.Deadline(); deadLineSet {
+		return ctx, func() {}
+	}
+
+	return context.WithTimeout(ctx, r.opTimeOut)
+}
+
+func (r *Repository) Save(
+	ctx context.Context, rcpt receipt.Receipt, points receipt.Points, keys ...string,
+) (uuid.UUID, error) {
+	ctx, cancel := r.withTimeOut(ctx)
+	defer cancel()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	defer tx.Rollback() //nolint:errcheck // no-op once committed.
+
+	if existing, err := storedKey(ctx, tx, keys); err != nil || existing != uuid.Nil {
+		if err != nil {
+			return uuid.Nil, err
+		}
+
+		return existing, receipt.ErrDuplicated
+	}
+
+	id := uuid.New()
+
+	if err := insert(ctx, tx, id, rcpt, points, keys); err != nil {
+		return uuid.Nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return uuid.Nil, err
+	}
+
+	return id, nil
+}
+
+// storedKey returns the id of the receipt stored with any of the keys, or
+// uuid.Nil when none of them is stored.
+func storedKey(ctx context.Context, tx *sql.Tx, keys []string) (uuid.UUID, error) {
+	if len(keys) == 0 {
+		return uuid.Nil, nil
+	}
+
+	args := make([]any, len(keys))
+	for i, key := range keys {
+		args[i] = key
+	}
+
+	query := fmt.Sprintf(
+		`SELECT receipt_id FROM submission_keys WHERE key IN (%s) LIMIT 1`,
+		strings.TrimSuffix(strings.Repeat("?,", len(keys)), ","),
+	)
+
+	var id uuid.UUID
+
+	err := tx.QueryRowContext(ctx, query, args...).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return uuid.Nil, nil
+	}
+
+	return id, err
+}
+
+func insert(
+	ctx context.Context, tx *sql.Tx, id uuid.UUID, rcpt receipt.Receipt, points receipt.Points, keys []string,
+) error {
+	if _, err := tx.ExecContext(ctx,
+		`INSERT INTO receipts (id, retailer, purchase_date, purchase_time, total, match_key)
+		VALUES (?, ?, ?, ?, ?, ?)`,
+		id,
+		rcpt.Retailer,
+		rcpt.PurchaseDate.Format(receipt.DatePurchaseFormat),
+		rcpt.PurchaseTime.Format(receipt.TimePurchaseFormat),
+		rcpt.Total.Cents(),
+		rcpt.MatchKey(),
+	); err != nil {
+		return err
+	}
+
+	for position, item := range rcpt.Items {
+		if _, err := tx.ExecContext(ctx,
+			`INSERT INTO items (receipt_id, position, short_description, price) VALUES (?, ?, ?, ?)`,
+			id, position, item.ShortDescription, item.Price.Cents(),
+		); err != nil {
+			return err
+		}
+	}
+
+	var (
+		duplicateOf *string
+		similarity  *float64
+		policy      *string
+	)
+
+	if d := points.Duplicate; d != nil {
+		of := d.Of.String()
+		duplicateOf, similarity, policy = &of, &d.Similarity, &d.Policy
+	}
+
+	if _, err := tx.ExecContext(ctx,
+		`INSERT INTO points (receipt_id, points, rule_set_version, duplicate_of, similarity, duplicate_policy)
+		VALUES (?, ?, ?, ?, ?, ?)`,
+		id, points.Points, points.RuleSetVersion, duplicateOf, similarity, policy,
+	); err != nil {
+		return err
+	}
+
+	for position, award := range points.Breakdown {
+		var itemIndex *int
+		if award.Item != nil {
+			itemIndex = &award.Item.Index
+		}
+
+		if _, err := tx.ExecContext(ctx,
+			`INSERT INTO awards (receipt_id, position, rule, description, points, item_index)
+			VALUES (?, ?, ?, ?, ?, ?)`,
+			id, position, award.Rule, award.Description, award.Points, itemIndex,
+		); err != nil {
+			return err
+		}
+	}
+
+	for _, key := range keys {
+		if _, err := tx.ExecContext(ctx,
+			`INSERT INTO submission_keys (key, receipt_id) VALUES (?, ?)`, key, id,
+		); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (r *Repository) Get(ctx context.Context, id uuid.UUID) (*receipt.Points, error) {
+	if id == uuid.Nil {
+		return nil, errInvalidID
+	}
+
+	ctx, cancel := r.withTimeOut(ctx)
+	defer cancel()
+
+	return r.points(ctx, id)
+}
+
+func (r *Repository) points(ctx context.Context, id uuid.UUID) (*receipt.Points, error) {
+	var (
+		points      receipt.Points
+		duplicateOf sql.NullString
+		similarity  sql.NullFloat64
+		policy      sql.NullString
+	)
+
+	err := r.db.QueryRowContext(ctx,
+		`SELECT points, rule_set_version, duplicate_of, similarity, duplicate_policy
+		FROM points WHERE receipt_id = ?`, id,
+	).Scan(&points.Points, &points.RuleSetVersion, &duplicateOf, &similarity, &policy)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, receipt.ErrNotFound
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if duplicateOf.Valid {
+		of, err := uuid.Parse(duplicateOf.String)
+		if err != nil {
+			return nil, err
+		}
+
+		points.Duplicate = &receipt.Duplicate{Of: of, Similarity: similarity.Float64, Policy: policy.String}
+	}
+
+	points.Breakdown, err = r.awards(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return &points, nil
+}
+
+func (r *Repository) awards(ctx context.Context, id uuid.UUID) ([]receipt.Award, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT a.rule, a.description, a.points, a.item_index, i.short_description, i.price
+		FROM awards a
+		LEFT JOIN items i ON i.receipt_id = a.receipt_id AND i.position = a.item_index
+		WHERE a.receipt_id = ?
+		ORDER BY a.position`, id,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	awards := []receipt.Award{}
+
+	for rows.Next() {
+		var (
+			award       receipt.Award
+			itemIndex   sql.NullInt64
+			description sql.NullString
+			price       sql.NullInt64
+		)
+
+		if err := rows.Scan(
+			&award.Rule, &award.Description, &award.Points, &itemIndex, &description, &price,
+		); err != nil {
+			return nil, err
+		}
+
+		if itemIndex.Valid {
+			award.Item = &receipt.AwardedItem{
+				Index: int(itemIndex.Int64),
+				Item:  receipt.Item{ShortDescription: description.String, Price: receipt.Money(price.Int64)},
+			}
+		}
+
+		awards = append(awards, award)
+	}
+
+	return awards, rows.Err()
+}
+
+func (r *Repository) GetReceipt(ctx context.Context, id uuid.UUID) (*receipt.Receipt, error) {
+	if id == uuid.Nil {
+		return nil, errInvalidID
+	}
+
+	ctx, cancel := r.withTimeOut(ctx)
+	defer cancel()
+
+	var (
+		rcpt         receipt.Receipt
+		purchaseDate string
+		purchaseTime string
+	)
+
+	err := r.db.QueryRowContext(ctx,
+		`SELECT retailer, purchase_date, purchase_time, total FROM receipts WHERE id = ?`, id,
+	).Scan(&rcpt.Retailer, &purchaseDate, &purchaseTime, &rcpt.Total)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, receipt.ErrNotFound
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if rcpt.PurchaseDate, err = time.Parse(receipt.DatePurchaseFormat, purchaseDate); err != nil {
+		return nil, err
+	}
+
+	if rcpt.PurchaseTime, err = time.Parse(receipt.TimePurchaseFormat, purchaseTime); err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT short_description, price FROM items WHERE receipt_id = ? ORDER BY position`, id,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	rcpt.Items = []receipt.Item{}
+
+	for rows.Next() {
+		item := receipt.Item{}
+		if err := rows.Scan(&item.ShortDescription, &item.Price); err != nil {
+			return nil, err
+		}
+
+		rcpt.Items = append(rcpt.Items, item)
+	}
+
+	return &rcpt, rows.Err()
+}
+
+func (r *Repository) FindByKey(ctx context.Context, key string) (uuid.UUID, error) {
+	ctx, cancel := r.withTimeOut(ctx)
+	defer cancel()
+
+	var id uuid.UUID
+
+	err := r.db.QueryRowContext(ctx, `SELECT receipt_id FROM submission_keys WHERE key = ?`, key).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return uuid.Nil, receipt.ErrNotFound
+	}
+
+	return id, err
+}
+
+func (r *Repository) FindMatching(ctx context.Context, rcpt receipt.Receipt) ([]uuid.UUID, error) {
+	ctx, cancel := r.withTimeOut(ctx)
+	defer cancel()
+
+	return r.ids(ctx, `SELECT id FROM receipts WHERE match_key = ? ORDER BY rowid`, rcpt.MatchKey())
+}
+
+// Ping checks that the database is reachable.
+func (r *Repository) Ping(ctx context.Context) error {
+	ctx, cancel := r.withTimeOut(ctx)
+	defer cancel()
+
+	return r.db.PingContext(ctx)
+}
+
+func (r *Repository) Flagged(ctx context.Context) ([]receipt.FlaggedReceipt, error) {
+	ctx, cancel := r.withTimeOut(ctx)
+	defer cancel()
+
+	ids, err := r.ids(ctx, `SELECT receipt_id FROM points WHERE duplicate_of IS NOT NULL ORDER BY rowid`)
+	if err != nil {
+		return nil, err
+	}
+
+	flagged := make([]receipt.FlaggedReceipt, 0, len(ids))
+
+	for _, id := range ids {
+		points, err := r.points(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+
+		flagged = append(flagged, receipt.FlaggedReceipt{ID: id, Points: *points})
+	}
+
+	return flagged, nil
+}
+
+func (r *Repository) ids(ctx context.Context, query string, args ...any) ([]uuid.UUID, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	ids := []uuid.UUID{}
+
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
+
+func (r *Repository) Close() error {
+	return r.db.Close()
+}
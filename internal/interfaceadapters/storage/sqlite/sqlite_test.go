@@ -0,0 +1,96 @@
+package sqlite_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"receipt-processor-challenge/internal/domain/receipt"
+	. "receipt-processor-challenge/internal/interfaceadapters/storage/sqlite"
+	"receipt-processor-challenge/internal/interfaceadapters/storage/storagetest"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func newRepository(t *testing.T) receipt.Repository {
+	t.Helper()
+
+	repo, err := New(context.Background(), filepath.Join(t.TempDir(), "receipts.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { repo.Close() })
+
+	return repo
+}
+
+func Test_Repository(t *testing.T) {
+	storagetest.Run(t, newRepository)
+}
+
+func Test_Reopen(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "receipts.db")
+
+	repo, err := New(ctx, path)
+	assert.NoError(t, err)
+
+	first, err := repo.Save(ctx, storagetest.NewReceipt("Market"), storagetest.NewPoints(10), "key-a")
+	assert.NoError(t, err)
+
+	flaggedPoints := storagetest.NewPoints(12)
+	flaggedPoints.Duplicate = &receipt.Duplicate{Of: first, Similarity: 0.9, Policy: "flag"}
+
+	second, err := repo.Save(ctx, storagetest.NewReceipt("Market"), flaggedPoints)
+	assert.NoError(t, err)
+
+	assert.NoError(t, repo.Close())
+
+	// reopening applies no migration twice and keeps the data.
+	repo, err = New(ctx, path)
+	assert.NoError(t, err)
+
+	defer repo.Close()
+
+	points, err := repo.Get(ctx, first)
+	assert.NoError(t, err)
+	assert.Equal(t, storagetest.NewPoints(10), *points)
+
+	rcpt, err := repo.GetReceipt(ctx, first)
+	assert.NoError(t, err)
+	assert.Equal(t, storagetest.NewReceipt("Market"), *rcpt)
+
+	id, err := repo.FindByKey(ctx, "key-a")
+	assert.NoError(t, err)
+	assert.Equal(t, first, id)
+
+	matching, err := repo.FindMatching(ctx, storagetest.NewReceipt("Market"))
+	assert.NoError(t, err)
+	assert.Equal(t, []uuid.UUID{first, second}, matching)
+
+	flagged, err := repo.Flagged(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []receipt.FlaggedReceipt{{ID: second, Points: flaggedPoints}}, flagged)
+}
+
+func Test_Ping(t *testing.T) {
+	ctx := context.Background()
+
+	repo, err := New(ctx, filepath.Join(t.TempDir(), "receipts.db"))
+	assert.NoError(t, err)
+	assert.NoError(t, repo.Ping(ctx))
+	assert.NoError(t, repo.Close())
+	assert.Error(t, repo.Ping(ctx))
+}
+
+func Test_ContextCanceled(t *testing.T) {
+	repo := newRepository(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := repo.Save(ctx, storagetest.NewReceipt("Market"), storagetest.NewPoints(10))
+	assert.ErrorIs(t, err, context.Canceled)
+}
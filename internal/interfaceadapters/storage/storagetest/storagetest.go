@@ -0,0 +1,251 @@
+/*
+Package storagetest checks that a storage behaves as the application expects
+from a receipt.Repository. Every storage runs the same checks from its tests
+and only tests on its own what is specific to it, like surviving a restart.
+*/
+package storagetest
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"receipt-processor-challenge/internal/domain/receipt"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+// NewRepository returns an empty repository for the test, it is closed when
+// the test ends.
+type NewRepository func(t *testing.T) receipt.Repository
+
+// NewReceipt returns a receipt of the retailer, receipts of the same retailer
+// share their match key.
+func NewReceipt(retailer string) receipt.Receipt {
+	return receipt.Receipt{
+		Retailer:     retailer,
+		PurchaseDate: time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC),
+		PurchaseTime: time.Date(0, 1, 1, 13, 1, 0, 0, time.UTC),
+		Items: []receipt.Item{
+			{ShortDescription: "Mountain Dew 12PK", Price: 649},
+			{ShortDescription: "Emils Cheese Pizza", Price: 1225},
+		},
+		Total: 1874,
+	}
+}
+
+// NewPoints returns points with a breakdown that awards an item.
+func NewPoints(points int) receipt.Points {
+	return receipt.Points{
+		Points:         points,
+		RuleSetVersion: "v1",
+		Breakdown: []receipt.Award{
+			{Rule: "retailer-name", Description: "retailer", Points: points - 3},
+			{
+				Rule:        "item-description",
+				Description: "description",
+				Points:      3,
+				Item: &receipt.AwardedItem{
+					Index: 1,
+					Item:  receipt.Item{ShortDescription: "Emils Cheese Pizza", Price: 1225},
+				},
+			},
+		},
+	}
+}
+
+// Run runs every check on a new repository.
+func Run(t *testing.T, newRepository NewRepository) {
+	t.Helper()
+
+	cases := []struct {
+		name  string
+		check func(t *testing.T, repo receipt.Repository)
+	}{
+		{name: "save-case", check: testSave},
+		{name: "keys-case", check: testKeys},
+		{name: "concurrent-keys-case", check: testConcurrentKeys},
+		{name: "matching-case", check: testMatching},
+		{name: "flagged-case", check: testFlagged},
+		{name: "context-canceled-case", check: testContextCanceled},
+	}
+
+	for _, c := range cases {
+		c := c
+
+		t.Run(c.name, func(t *testing.T) {
+			c.check(t, newRepository(t))
+		})
+	}
+}
+
+func testSave(t *testing.T, repo receipt.Repository) {
+	ctx := context.Background()
+
+	id, err := repo.Save(ctx, NewReceipt("Market"), NewPoints(10))
+	assert.NoError(t, err)
+	assert.NotEqual(t, uuid.Nil, id)
+
+	points, err := repo.Get(ctx, id)
+	assert.NoError(t, err)
+	assert.Equal(t, NewPoints(10), *points)
+
+	rcpt, err := repo.GetReceipt(ctx, id)
+	assert.NoError(t, err)
+	assert.Equal(t, NewReceipt("Market"), *rcpt)
+
+	_, err = repo.Get(ctx, uuid.New())
+	assert.ErrorIs(t, err, receipt.ErrNotFound)
+
+	_, err = repo.GetReceipt(ctx, uuid.New())
+	assert.ErrorIs(t, err, receipt.ErrNotFound)
+
+	assert.NoError(t, repo.Ping(ctx))
+}
+
+func testKeys(t *testing.T, repo receipt.Repository) {
+	ctx := context.Background()
+
+	first, err := repo.Save(ctx, NewReceipt("Market"), NewPoints(10), "key-a", "key-b")
+	assert.NoError(t, err)
+
+	id, err := repo.Save(ctx, NewReceipt("Market"), NewPoints(1), "key-c", "key-b")
+	assert.ErrorIs(t, err, receipt.ErrDuplicated)
+	assert.Equal(t, first, id)
+
+	// a rejected save stores nothing, not even its new keys.
+	second, err := repo.Save(ctx, NewReceipt("Market"), NewPoints(1), "key-c")
+	assert.NoError(t, err)
+	assert.NotEqual(t, first, second)
+
+	points, err := repo.Get(ctx, first)
+	assert.NoError(t, err)
+	assert.Equal(t, 10, points.Points)
+
+	for key, expected := range map[string]uuid.UUID{"key-a": first, "key-b": first, "key-c": second} {
+		id, err := repo.FindByKey(ctx, key)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, id, key)
+	}
+
+	_, err = repo.FindByKey(ctx, "key-d")
+	assert.ErrorIs(t, err, receipt.ErrNotFound)
+}
+
+func testConcurrentKeys(t *testing.T, repo receipt.Repository) {
+	ctx := context.Background()
+
+	const saves = 50
+
+	ids := make([]uuid.UUID, saves)
+	errs := make([]error, saves)
+	wg := sync.WaitGroup{}
+
+	for i := 0; i < saves; i++ {
+		wg.Add(1)
+
+		go func(i int) {
+			defer wg.Done()
+
+			// every save shares one key with the others and has one of its own.
+			ids[i], errs[i] = repo.Save(ctx, NewReceipt("Market"), NewPoints(i),
+				fmt.Sprintf("own-key-%d", i), "shared-key")
+		}(i)
+	}
+
+	wg.Wait()
+
+	created := 0
+
+	for i := range ids {
+		if errs[i] == nil {
+			created++
+		} else {
+			assert.ErrorIs(t, errs[i], receipt.ErrDuplicated)
+		}
+
+		assert.Equal(t, ids[0], ids[i])
+	}
+
+	assert.Equal(t, 1, created)
+
+	matching, err := repo.FindMatching(ctx, NewReceipt("Market"))
+	assert.NoError(t, err)
+	assert.Equal(t, []uuid.UUID{ids[0]}, matching)
+}
+
+func testMatching(t *testing.T, repo receipt.Repository) {
+	ctx := context.Background()
+
+	first, err := repo.Save(ctx, NewReceipt("Market"), NewPoints(10))
+	assert.NoError(t, err)
+
+	_, err = repo.Save(ctx, NewReceipt("Another Market"), NewPoints(10))
+	assert.NoError(t, err)
+
+	second, err := repo.Save(ctx, NewReceipt("Market"), NewPoints(12))
+	assert.NoError(t, err)
+
+	matching, err := repo.FindMatching(ctx, NewReceipt("Market"))
+	assert.NoError(t, err)
+	assert.Equal(t, []uuid.UUID{first, second}, matching)
+
+	matching, err = repo.FindMatching(ctx, NewReceipt("Unknown Market"))
+	assert.NoError(t, err)
+	assert.Empty(t, matching)
+}
+
+func testFlagged(t *testing.T, repo receipt.Repository) {
+	ctx := context.Background()
+
+	flagged, err := repo.Flagged(ctx)
+	assert.NoError(t, err)
+	assert.Empty(t, flagged)
+
+	first, err := repo.Save(ctx, NewReceipt("Market"), NewPoints(10))
+	assert.NoError(t, err)
+
+	ids := []uuid.UUID{}
+	expected := []receipt.FlaggedReceipt{}
+
+	for i := 0; i < 2; i++ {
+		points := NewPoints(12 + i)
+		points.Duplicate = &receipt.Duplicate{Of: first, Similarity: 0.9, Policy: "flag"}
+
+		id, err := repo.Save(ctx, NewReceipt("Market"), points)
+		assert.NoError(t, err)
+
+		ids = append(ids, id)
+		expected = append(expected, receipt.FlaggedReceipt{ID: id, Points: points})
+	}
+
+	flagged, err = repo.Flagged(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, expected, flagged)
+
+	points, err := repo.Get(ctx, ids[0])
+	assert.NoError(t, err)
+	assert.Equal(t, expected[0].Points, *points)
+}
+
+func testContextCanceled(t *testing.T, repo receipt.Repository) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := repo.Save(ctx, NewReceipt("Market"), NewPoints(10), "key-a")
+	assert.Error(t, err)
+
+	_, err = repo.Get(ctx, uuid.New())
+	assert.Error(t, err)
+
+	// the canceled save stored nothing.
+	_, err = repo.FindByKey(context.Background(), "key-a")
+	assert.ErrorIs(t, err, receipt.ErrNotFound)
+
+	matching, err := repo.FindMatching(context.Background(), NewReceipt("Market"))
+	assert.NoError(t, err)
+	assert.Empty(t, matching)
+}
@@ -0,0 +1,146 @@
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	// ExporterStdout writes the spans to the standard output.
+	ExporterStdout string = "stdout"
+	// ExporterFile appends the spans to a file.
+	ExporterFile string = "file"
+	// ExporterOTLP sends the spans to an OpenTelemetry collector.
+	ExporterOTLP string = "otlp"
+)
+
+var ErrUnknownExporter = errors.New("unknown tracing exporter")
+
+// Exporter sends finished spans to a backend. The spans must not be kept once
+// Export returns.
+type Exporter interface {
+	Export(ctx context.Context, spans []SpanData) error
+	// Shutdown releases the exporter, it is called once no span is exported.
+	Shutdown(ctx context.Context) error
+}
+
+// WriterExporter writes the spans as JSON lines, one span per line, to read
+// the traces without a collector.
+type WriterExporter struct {
+	mu      sync.Mutex
+	encoder *json.Encoder
+	closer  io.Closer
+}
+
+type jsonSpan struct {
+	TraceID    string            `json:"traceId"`
+	SpanID     string            `json:"spanId"`
+	ParentID   string            `json:"parentSpanId,omitempty"`
+	Name       string            `json:"name"`
+	Kind       string            `json:"kind"`
+	Start      time.Time         `json:"start"`
+	End        time.Time         `json:"end"`
+	Duration   string            `json:"duration"`
+	Attributes map[string]string `json:"attributes,omitempty"`
+	Error      string            `json:"error,omitempty"`
+}
+
+// NewWriterExporter writes the spans to w, e.g. os.Stdout. w is not closed on
+// Shutdown.
+func NewWriterExporter(w io.Writer) *WriterExporter {
+	return &WriterExporter{encoder: json.NewEncoder(w)}
+}
+
+// NewFileExporter appends the spans to the file at path, it is created when it
+// does not exist and closed on Shutdown.
+func NewFileExporter(path string) (*WriterExporter, error) {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644) //nolint:gomnd
+	if err != nil {
+		return nil, err
+	}
+
+	e := NewWriterExporter(file)
+	e.closer = file
+
+	return e, nil
+}
+
+func (e *WriterExporter) Export(_ context.Context, spans []SpanData) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for i := range spans {
+		if err := e.encoder.Encode(toJSONSpan(spans[i])); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (e *WriterExporter) Shutdown(context.Context) error {
+	if e.closer == nil {
+		return nil
+	}
+
+	return e.closer.Close()
+}
+
+func toJSONSpan(span SpanData) jsonSpan {
+	js := jsonSpan{
+		TraceID:  span.TraceID.String(),
+		SpanID:   span.SpanID.String(),
+		Name:     span.Name,
+		Kind:     "internal",
+		Start:    span.Start,
+		End:      span.End,
+		Duration: span.End.Sub(span.Start).String(),
+		Error:    span.Err,
+	}
+
+	if span.Parent.IsValid() {
+		js.ParentID = span.Parent.String()
+	}
+
+	if span.Kind == KindServer {
+		js.Kind = "server"
+	}
+
+	if len(span.Attributes) > 0 {
+		js.Attributes = make(map[string]string, len(span.Attributes))
+		for _, attr := range span.Attributes {
+			js.Attributes[attr.Key] = attr.Value
+		}
+	}
+
+	return js
+}
+
+/*
+NewExporter creates the exporter of the given kind: ExporterStdout,
+ExporterFile writing to path or ExporterOTLP sending to endpoint, service is
+the name the OTLP spans are reported under.
+*/
+func NewExporter(kind, path, endpoint, service string) (Exporter, error) {
+	switch kind {
+	case ExporterStdout:
+		return NewWriterExporter(os.Stdout), nil
+	case ExporterFile:
+		exporter, err := NewFileExporter(path)
+		if err != nil {
+			return nil, err
+		}
+
+		return exporter, nil
+	case ExporterOTLP:
+		return NewOTLPExporter(endpoint, service), nil
+	}
+
+	return nil, fmt.Errorf("%s:%w", kind, ErrUnknownExporter)
+}
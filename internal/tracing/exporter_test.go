@@ -0,0 +1,82 @@
+package tracing_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	. "receipt-processor-challenge/internal/tracing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testSpan(t *testing.T) SpanData {
+	t.Helper()
+
+	parent, err := ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	assert.NoError(t, err)
+
+	start := time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	return SpanData{
+		SpanContext: SpanContext{TraceID: parent.TraceID, SpanID: SpanID{1, 2, 3, 4, 5, 6, 7, 8}, Sampled: true},
+		Parent:      parent.SpanID,
+		Name:        "storage.Save",
+		Start:       start,
+		End:         start.Add(1500 * time.Microsecond),
+		Attributes:  []Attribute{{Key: "receipt.id", Value: "id"}},
+		Err:         "time out",
+	}
+}
+
+func Test_WriterExporter(t *testing.T) {
+	out := bytes.Buffer{}
+	exporter := NewWriterExporter(&out)
+
+	assert.NoError(t, exporter.Export(context.Background(), []SpanData{testSpan(t), testSpan(t)}))
+	assert.NoError(t, exporter.Shutdown(context.Background()))
+
+	lines := bytes.Split(bytes.TrimSpace(out.Bytes()), []byte("\n"))
+	assert.Len(t, lines, 2)
+	assert.JSONEq(t, `{"traceId":"4bf92f3577b34da6a3ce929d0e0e4736","spanId":"0102030405060708",`+
+		`"parentSpanId":"00f067aa0ba902b7","name":"storage.Save","kind":"internal",`+
+		`"start":"2023-01-02T03:04:05Z","end":"2023-01-02T03:04:05.0015Z","duration":"1.5ms",`+
+		`"attributes":{"receipt.id":"id"},"error":"time out"}`, string(lines[0]))
+}
+
+func Test_FileExporter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "spans.jsonl")
+
+	for i := 0; i < 2; i++ {
+		exporter, err := NewExporter(ExporterFile, path, "", "service")
+		assert.NoError(t, err)
+		assert.NoError(t, exporter.Export(context.Background(), []SpanData{testSpan(t)}))
+		assert.NoError(t, exporter.Shutdown(context.Background()))
+	}
+
+	content, err := os.ReadFile(path)
+	assert.NoError(t, err)
+
+	lines := bytes.Split(bytes.TrimSpace(content), []byte("\n"))
+	assert.Len(t, lines, 2)
+
+	span := map[string]any{}
+	assert.NoError(t, json.Unmarshal(lines[1], &span))
+	assert.Equal(t, "storage.Save", span["name"])
+}
+
+func Test_NewExporter(t *testing.T) {
+	_, err := NewExporter("jaeger", "", "", "service")
+	assert.ErrorIs(t, err, ErrUnknownExporter)
+
+	_, err = NewExporter(ExporterFile, filepath.Join(t.TempDir(), "missing", "spans.jsonl"), "", "service")
+	assert.Error(t, err)
+
+	exporter, err := NewExporter(ExporterOTLP, "", "http://localhost:4318", "service")
+	assert.NoError(t, err)
+	assert.IsType(t, &OTLPExporter{}, exporter)
+}
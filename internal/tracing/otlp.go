@@ -0,0 +1,180 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const (
+	otlpTracesPath string = "/v1/traces"
+	otlpScope      string = "receipt-processor-challenge"
+
+	// span kinds and status codes of the OTLP protocol.
+	otlpKindInternal = 1
+	otlpKindServer   = 2
+	otlpStatusError  = 2
+
+	maxErrorBody = 512
+)
+
+var ErrExport = errors.New("otlp export failed")
+
+/*
+OTLPExporter sends the spans to an OpenTelemetry collector with OTLP/HTTP in
+its JSON encoding. The endpoint is the base URL of the collector, e.g.
+http://localhost:4318, the traces path is added to it.
+*/
+type OTLPExporter struct {
+	url     string
+	service string
+	client  *http.Client
+}
+
+func NewOTLPExporter(endpoint, service string) *OTLPExporter {
+	url := strings.TrimSuffix(endpoint, "/")
+	if !strings.HasSuffix(url, otlpTracesPath) {
+		url += otlpTracesPath
+	}
+
+	return &OTLPExporter{
+		url:     url,
+		service: service,
+		client:  &http.Client{Timeout: exportTimeOut},
+	}
+}
+
+type otlpRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []otlpAttribute `json:"attributes"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScopeName `json:"scope"`
+	Spans []otlpSpan    `json:"spans"`
+}
+
+type otlpScopeName struct {
+	Name string `json:"name"`
+}
+
+type otlpSpan struct {
+	TraceID           string          `json:"traceId"`
+	SpanID            string          `json:"spanId"`
+	ParentSpanID      string          `json:"parentSpanId,omitempty"`
+	Name              string          `json:"name"`
+	Kind              int             `json:"kind"`
+	StartTimeUnixNano string          `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+	Attributes        []otlpAttribute `json:"attributes,omitempty"`
+	Status            *otlpStatus     `json:"status,omitempty"`
+}
+
+type otlpAttribute struct {
+	Key   string    `json:"key"`
+	Value otlpValue `json:"value"`
+}
+
+type otlpValue struct {
+	StringValue string `json:"stringValue"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+func (e *OTLPExporter) Export(ctx context.Context, spans []SpanData) error {
+	body, err := json.Marshal(e.request(spans))
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 { //nolint:gomnd
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+
+		return fmt.Errorf("%s %s:%w", resp.Status, bytes.TrimSpace(message), ErrExport)
+	}
+
+	_, err = io.Copy(io.Discard, resp.Body)
+
+	return err
+}
+
+func (e *OTLPExporter) Shutdown(context.Context) error {
+	e.client.CloseIdleConnections()
+
+	return nil
+}
+
+func (e *OTLPExporter) request(spans []SpanData) otlpRequest {
+	converted := make([]otlpSpan, 0, len(spans))
+
+	for i := range spans {
+		span := spans[i]
+		out := otlpSpan{
+			TraceID:           span.TraceID.String(),
+			SpanID:            span.SpanID.String(),
+			Name:              span.Name,
+			Kind:              otlpKindInternal,
+			StartTimeUnixNano: strconv.FormatInt(span.Start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(span.End.UnixNano(), 10),
+		}
+
+		if span.Parent.IsValid() {
+			out.ParentSpanID = span.Parent.String()
+		}
+
+		if span.Kind == KindServer {
+			out.Kind = otlpKindServer
+		}
+
+		for _, attr := range span.Attributes {
+			out.Attributes = append(out.Attributes, otlpAttribute{Key: attr.Key, Value: otlpValue{attr.Value}})
+		}
+
+		if span.Err != "" {
+			out.Status = &otlpStatus{Code: otlpStatusError, Message: span.Err}
+		}
+
+		converted = append(converted, out)
+	}
+
+	return otlpRequest{ResourceSpans: []otlpResourceSpans{{
+		Resource: otlpResource{Attributes: []otlpAttribute{
+			{Key: "service.name", Value: otlpValue{e.service}},
+		}},
+		ScopeSpans: []otlpScopeSpans{{
+			Scope: otlpScopeName{Name: otlpScope},
+			Spans: converted,
+		}},
+	}}}
+}
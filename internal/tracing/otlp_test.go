@@ -0,0 +1,56 @@
+package tracing_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "receipt-processor-challenge/internal/tracing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_OTLPExporter(t *testing.T) {
+	requests := make(chan *http.Request, 1)
+	bodies := make(chan []byte, 1)
+
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests <- r
+		bodies <- body
+	}))
+	t.Cleanup(collector.Close)
+
+	exporter := NewOTLPExporter(collector.URL+"/", "receipts")
+	assert.NoError(t, exporter.Export(context.Background(), []SpanData{testSpan(t)}))
+	assert.NoError(t, exporter.Shutdown(context.Background()))
+
+	req := <-requests
+	assert.Equal(t, http.MethodPost, req.Method)
+	assert.Equal(t, "/v1/traces", req.URL.Path)
+	assert.Equal(t, "application/json", req.Header.Get("Content-Type"))
+	assert.JSONEq(t, `{"resourceSpans":[{
+		"resource":{"attributes":[{"key":"service.name","value":{"stringValue":"receipts"}}]},
+		"scopeSpans":[{"scope":{"name":"receipt-processor-challenge"},"spans":[{
+			"traceId":"4bf92f3577b34da6a3ce929d0e0e4736","spanId":"0102030405060708",
+			"parentSpanId":"00f067aa0ba902b7","name":"storage.Save","kind":1,
+			"startTimeUnixNano":"1672628645000000000","endTimeUnixNano":"1672628645001500000",
+			"attributes":[{"key":"receipt.id","value":{"stringValue":"id"}}],
+			"status":{"code":2,"message":"time out"}}]}]}]}`, string(<-bodies))
+}
+
+func Test_OTLPExporterError(t *testing.T) {
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"message": "bad spans"})
+	}))
+	t.Cleanup(collector.Close)
+
+	exporter := NewOTLPExporter(collector.URL+"/v1/traces", "receipts")
+	err := exporter.Export(context.Background(), []SpanData{testSpan(t)})
+	assert.ErrorIs(t, err, ErrExport)
+	assert.Contains(t, err.Error(), "bad spans")
+}
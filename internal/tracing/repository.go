@@ -0,0 +1,82 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+
+	"receipt-processor-challenge/internal/domain/receipt"
+
+	"github.com/google/uuid"
+)
+
+// Repository records a span for every operation of the repository it wraps,
+// the time waited for the storage locks or connections is part of them.
+type Repository struct {
+	receipt.Repository
+}
+
+func NewRepository(repo receipt.Repository) Repository {
+	return Repository{Repository: repo}
+}
+
+func (r Repository) Save(
+	ctx context.Context, rcpt receipt.Receipt, points receipt.Points, keys ...string,
+) (id uuid.UUID, err error) {
+	ctx, span := Start(ctx, "storage.Save")
+	defer func() { end(span, err) }()
+
+	id, err = r.Repository.Save(ctx, rcpt, points, keys...)
+	span.SetAttribute("receipt.id", id.String())
+
+	return id, err
+}
+
+func (r Repository) Get(ctx context.Context, id uuid.UUID) (points *receipt.Points, err error) {
+	ctx, span := Start(ctx, "storage.Get")
+	defer func() { end(span, err) }()
+
+	span.SetAttribute("receipt.id", id.String())
+
+	return r.Repository.Get(ctx, id)
+}
+
+func (r Repository) GetReceipt(ctx context.Context, id uuid.UUID) (rcpt *receipt.Receipt, err error) {
+	ctx, span := Start(ctx, "storage.GetReceipt")
+	defer func() { end(span, err) }()
+
+	span.SetAttribute("receipt.id", id.String())
+
+	return r.Repository.GetReceipt(ctx, id)
+}
+
+func (r Repository) FindByKey(ctx context.Context, key string) (id uuid.UUID, err error) {
+	ctx, span := Start(ctx, "storage.FindByKey")
+	defer func() { end(span, err) }()
+
+	return r.Repository.FindByKey(ctx, key)
+}
+
+func (r Repository) FindMatching(ctx context.Context, rcpt receipt.Receipt) (ids []uuid.UUID, err error) {
+	ctx, span := Start(ctx, "storage.FindMatching")
+	defer func() { end(span, err) }()
+
+	return r.Repository.FindMatching(ctx, rcpt)
+}
+
+func (r Repository) Flagged(ctx context.Context) (flagged []receipt.FlaggedReceipt, err error) {
+	ctx, span := Start(ctx, "storage.Flagged")
+	defer func() { end(span, err) }()
+
+	return r.Repository.Flagged(ctx)
+}
+
+// end ends the span, not found, evicted and duplicated receipts are answers
+// of the repository, not failures.
+func end(span *Span, err error) {
+	if !errors.Is(err, receipt.ErrNotFound) && !errors.Is(err, receipt.ErrEvicted) &&
+		!errors.Is(err, receipt.ErrDuplicated) {
+		span.RecordError(err)
+	}
+
+	span.End()
+}
@@ -0,0 +1,56 @@
+package tracing_test
+
+import (
+	"context"
+	"testing"
+
+	"receipt-processor-challenge/internal/domain/receipt"
+	"receipt-processor-challenge/internal/interfaceadapters/storage/memory"
+	. "receipt-processor-challenge/internal/tracing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Repository(t *testing.T) {
+	engine := memory.New(context.Background())
+	t.Cleanup(func() { engine.Close() })
+
+	exporter := &recordingExporter{}
+	tracer := New(exporter)
+	repo := NewRepository(engine)
+
+	ctx, root := tracer.Start(context.Background(), "root", KindServer, SpanContext{})
+
+	id, err := repo.Save(ctx, receipt.Receipt{Retailer: "Target"}, receipt.Points{Points: 6})
+	assert.NoError(t, err)
+
+	_, err = repo.Get(ctx, uuid.New())
+	assert.ErrorIs(t, err, receipt.ErrNotFound)
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+
+	_, err = repo.GetReceipt(canceled, id)
+	assert.Error(t, err)
+
+	// without a span in the context nothing is recorded.
+	_, err = repo.Flagged(context.Background())
+	assert.NoError(t, err)
+
+	root.End()
+	assert.NoError(t, tracer.Shutdown(context.Background()))
+
+	spans := exporter.exported()
+	assert.Len(t, spans, 4)
+	assert.Equal(t, "storage.Save", spans[0].Name)
+	assert.Equal(t, []Attribute{{Key: "receipt.id", Value: id.String()}}, spans[0].Attributes)
+	assert.Equal(t, "storage.Get", spans[1].Name)
+	assert.Empty(t, spans[1].Err)
+	assert.Equal(t, "storage.GetReceipt", spans[2].Name)
+	assert.Equal(t, "time out", spans[2].Err)
+
+	for _, span := range spans[:3] {
+		assert.Equal(t, root.Context().SpanID, span.Parent)
+	}
+}
@@ -0,0 +1,417 @@
+/*
+Package tracing records spans of the work done for a request and hands them to
+an Exporter. The trace context is propagated with the W3C traceparent header.
+
+The span being recorded travels in the context: Start creates a child of it, or
+returns a nil span that records nothing when the context has no span, so the
+code doing the work does not need to know whether tracing is enabled.
+*/
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// HeaderTraceparent is the W3C trace context header.
+const HeaderTraceparent string = "traceparent"
+
+const (
+	defaultQueueSize     = 2048
+	defaultBatchSize     = 512
+	defaultBatchInterval = 5 * time.Second
+	exportTimeOut        = 10 * time.Second
+
+	traceparentLength = 55
+)
+
+var ErrInvalidTraceparent = errors.New("invalid traceparent")
+
+// Kind is the role of a span in the trace.
+type Kind int
+
+const (
+	KindInternal Kind = iota
+	// KindServer is the span of an incoming request.
+	KindServer
+)
+
+type (
+	TraceID [16]byte
+	SpanID  [8]byte
+)
+
+func (t TraceID) String() string { return hex.EncodeToString(t[:]) }
+
+func (t TraceID) IsValid() bool { return t != TraceID{} }
+
+func (s SpanID) String() string { return hex.EncodeToString(s[:]) }
+
+func (s SpanID) IsValid() bool { return s != SpanID{} }
+
+// SpanContext identifies a span across processes.
+type SpanContext struct {
+	TraceID TraceID
+	SpanID  SpanID
+	Sampled bool
+}
+
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID.IsValid() && sc.SpanID.IsValid()
+}
+
+// Traceparent formats sc as a traceparent header value.
+func (sc SpanContext) Traceparent() string {
+	flags := "00"
+	if sc.Sampled {
+		flags = "01"
+	}
+
+	return "00-" + sc.TraceID.String() + "-" + sc.SpanID.String() + "-" + flags
+}
+
+/*
+ParseTraceparent reads a traceparent header value. Values of a version newer
+than 00 are read as version 00 as long as they start with its fields, as the
+specification asks.
+*/
+func ParseTraceparent(value string) (SpanContext, error) {
+	invalid := fmt.Errorf("%q:%w", value, ErrInvalidTraceparent)
+
+	if len(value) < traceparentLength || (len(value) > traceparentLength && value[traceparentLength] != '-') {
+		return SpanContext{}, invalid
+	}
+
+	parts := strings.Split(value[:traceparentLength], "-")
+	if len(parts) != 4 || parts[0] == "ff" || (parts[0] == "00" && len(value) != traceparentLength) {
+		return SpanContext{}, invalid
+	}
+
+	for _, part := range parts {
+		if strings.ToLower(part) != part {
+			return SpanContext{}, invalid
+		}
+	}
+
+	version, vErr := hex.DecodeString(parts[0])
+	traceID, tErr := hex.DecodeString(parts[1])
+	spanID, sErr := hex.DecodeString(parts[2])
+	flags, fErr := hex.DecodeString(parts[3])
+
+	if err := errors.Join(vErr, tErr, sErr, fErr); err != nil || len(version) != 1 || len(flags) != 1 {
+		return SpanContext{}, invalid
+	}
+
+	sc := SpanContext{Sampled: flags[0]&1 == 1}
+	copy(sc.TraceID[:], traceID)
+	copy(sc.SpanID[:], spanID)
+
+	if !sc.IsValid() {
+		return SpanContext{}, invalid
+	}
+
+	return sc, nil
+}
+
+// Attribute is a key value pair describing a span.
+type Attribute struct {
+	Key   string
+	Value string
+}
+
+// SpanData is a finished span as handed to the exporters.
+type SpanData struct {
+	SpanContext
+	Parent     SpanID
+	Name       string
+	Kind       Kind
+	Start      time.Time
+	End        time.Time
+	Attributes []Attribute
+	// Err is the failure of the operation, empty when it succeeded.
+	Err string
+}
+
+// Span is an operation being recorded. Every method of a nil span does
+// nothing.
+type Span struct {
+	tracer *Tracer
+	mu     sync.Mutex
+	data   SpanData
+	ended  bool
+}
+
+// Context returns the identifiers of the span, to propagate it.
+func (s *Span) Context() SpanContext {
+	if s == nil {
+		return SpanContext{}
+	}
+
+	return s.data.SpanContext
+}
+
+func (s *Span) SetAttribute(key, value string) {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.data.Attributes = append(s.data.Attributes, Attribute{Key: key, Value: value})
+}
+
+// RecordError marks the span as failed, a nil err is ignored.
+func (s *Span) RecordError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.data.Err = err.Error()
+}
+
+// End finishes the span and queues it for export when it is sampled, only the
+// first call has an effect.
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+
+		return
+	}
+
+	s.ended = true
+	s.data.End = time.Now()
+	data := s.data
+	s.mu.Unlock()
+
+	if data.Sampled {
+		s.tracer.enqueue(data)
+	}
+}
+
+type spanKey struct{}
+
+// FromContext returns the span in ctx, nil when there is none.
+func FromContext(ctx context.Context) *Span {
+	span, _ := ctx.Value(spanKey{}).(*Span)
+
+	return span
+}
+
+// Start starts a child of the span in ctx and returns a context with the new
+// span, it returns ctx and a nil span when ctx has no span.
+func Start(ctx context.Context, name string) (context.Context, *Span) {
+	parent := FromContext(ctx)
+	if parent == nil {
+		return ctx, nil
+	}
+
+	return parent.tracer.start(ctx, name, KindInternal, parent.Context())
+}
+
+/*
+Tracer creates the root spans and exports the finished spans in batches from a
+background goroutine. Spans are dropped when the export falls behind, Shutdown
+exports the queued spans and stops the exporter.
+*/
+type Tracer struct {
+	exporter      Exporter
+	ratio         float64
+	batchSize     int
+	batchInterval time.Duration
+	onError       func(err error)
+
+	queue   chan SpanData
+	done    chan struct{}
+	stopped chan struct{}
+	once    sync.Once
+	dropped atomic.Uint64
+}
+
+// Option configures a Tracer.
+type Option func(t *Tracer)
+
+// WithSampleRatio sets the ratio of the new traces that are recorded, every
+// trace by default. Traces started by a caller follow its sampling decision.
+func WithSampleRatio(ratio float64) Option {
+	return func(t *Tracer) {
+		t.ratio = ratio
+	}
+}
+
+// WithBatch sets how many spans are exported at once and how often the queued
+// spans are exported, 512 spans every 5 seconds by default.
+func WithBatch(size int, interval time.Duration) Option {
+	return func(t *Tracer) {
+		t.batchSize = size
+		t.batchInterval = interval
+	}
+}
+
+// WithErrorHandler sets the function called when an export fails, the error
+// is logged with slog.Default() by default.
+func WithErrorHandler(onError func(err error)) Option {
+	return func(t *Tracer) {
+		t.onError = onError
+	}
+}
+
+func New(exporter Exporter, opts ...Option) *Tracer {
+	t := &Tracer{
+		exporter:      exporter,
+		ratio:         1,
+		batchSize:     defaultBatchSize,
+		batchInterval: defaultBatchInterval,
+		onError: func(err error) {
+			slog.Error("tracing export failed", slog.String("error", err.Error()))
+		},
+		queue:   make(chan SpanData, defaultQueueSize),
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+
+	for _, opt := range opts {
+		opt(t)
+	}
+
+	go t.run()
+
+	return t
+}
+
+/*
+Start starts a span continuing the trace of remote, the span context received
+from the caller, or a new trace when remote is not valid. It returns a context
+with the new span.
+*/
+func (t *Tracer) Start(ctx context.Context, name string, kind Kind, remote SpanContext) (context.Context, *Span) {
+	return t.start(ctx, name, kind, remote)
+}
+
+func (t *Tracer) start(ctx context.Context, name string, kind Kind, parent SpanContext) (context.Context, *Span) {
+	data := SpanData{Name: name, Kind: kind, Start: time.Now()}
+
+	if parent.IsValid() {
+		data.TraceID = parent.TraceID
+		data.Parent = parent.SpanID
+		data.Sampled = parent.Sampled
+	} else {
+		rand.Read(data.TraceID[:]) //nolint:errcheck
+		data.Sampled = t.sampled(data.TraceID)
+	}
+
+	rand.Read(data.SpanID[:]) //nolint:errcheck
+
+	span := &Span{tracer: t, data: data}
+
+	return context.WithValue(ctx, spanKey{}, span), span
+}
+
+// sampled decides from the trace id so every process makes the same choice.
+func (t *Tracer) sampled(id TraceID) bool {
+	if t.ratio >= 1 {
+		return true
+	}
+
+	return binary.BigEndian.Uint64(id[8:])>>1 < uint64(t.ratio*(1<<63))
+}
+
+// Dropped returns the number of spans dropped because the queue was full.
+func (t *Tracer) Dropped() uint64 {
+	return t.dropped.Load()
+}
+
+func (t *Tracer) enqueue(data SpanData) {
+	select {
+	case <-t.done:
+		t.dropped.Add(1)
+
+		return
+	default:
+	}
+
+	select {
+	case t.queue <- data:
+	default:
+		t.dropped.Add(1)
+	}
+}
+
+func (t *Tracer) run() {
+	defer close(t.stopped)
+
+	ticker := time.NewTicker(t.batchInterval)
+	defer ticker.Stop()
+
+	batch := []SpanData{}
+
+	for {
+		select {
+		case data := <-t.queue:
+			if batch = append(batch, data); len(batch) >= t.batchSize {
+				batch = t.export(batch)
+			}
+		case <-ticker.C:
+			batch = t.export(batch)
+		case <-t.done:
+			for {
+				select {
+				case data := <-t.queue:
+					batch = append(batch, data)
+				default:
+					t.export(batch)
+
+					return
+				}
+			}
+		}
+	}
+}
+
+func (t *Tracer) export(batch []SpanData) []SpanData {
+	if len(batch) == 0 {
+		return batch
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), exportTimeOut)
+	defer cancel()
+
+	if err := t.exporter.Export(ctx, batch); err != nil {
+		t.onError(err)
+	}
+
+	return batch[:0]
+}
+
+// Shutdown exports the queued spans and shuts the exporter down, the spans
+// ended afterwards are dropped.
+func (t *Tracer) Shutdown(ctx context.Context) error {
+	t.once.Do(func() {
+		close(t.done)
+	})
+
+	select {
+	case <-t.stopped:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	return t.exporter.Shutdown(ctx)
+}
@@ -0,0 +1,205 @@
+package tracing_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	. "receipt-processor-challenge/internal/tracing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// recordingExporter keeps the exported spans.
+type recordingExporter struct {
+	mu       sync.Mutex
+	spans    []SpanData
+	shutdown bool
+}
+
+func (r *recordingExporter) Export(_ context.Context, spans []SpanData) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.spans = append(r.spans, spans...)
+
+	return nil
+}
+
+func (r *recordingExporter) Shutdown(context.Context) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.shutdown = true
+
+	return nil
+}
+
+func (r *recordingExporter) exported() []SpanData {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]SpanData(nil), r.spans...)
+}
+
+func Test_ParseTraceparent(t *testing.T) {
+	cases := []struct {
+		name     string
+		value    string
+		expected string
+		sampled  bool
+	}{
+		{
+			name:     "sampled-case",
+			value:    "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+			expected: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+			sampled:  true,
+		},
+		{
+			name:     "not-sampled-case",
+			value:    "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00",
+			expected: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00",
+		},
+		{
+			name:     "future-version-case",
+			value:    "01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+			expected: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+			sampled:  true,
+		},
+		{name: "empty-case", value: ""},
+		{name: "uppercase-case", value: "00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01"},
+		{name: "zero-trace-case", value: "00-00000000000000000000000000000000-00f067aa0ba902b7-01"},
+		{name: "zero-span-case", value: "00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01"},
+		{name: "invalid-version-case", value: "ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"},
+		{name: "version-00-extra-case", value: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra"},
+		{name: "not-hex-case", value: "00-4bf92f3577b34da6a3ce929d0e0e473z-00f067aa0ba902b7-01"},
+		{name: "bad-separator-case", value: "00_4bf92f3577b34da6a3ce929d0e0e4736_00f067aa0ba902b7_01"},
+	}
+
+	for _, c := range cases {
+		c := c
+
+		t.Run(c.name, func(t *testing.T) {
+			sc, err := ParseTraceparent(c.value)
+			if c.expected == "" {
+				assert.ErrorIs(t, err, ErrInvalidTraceparent)
+
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, c.expected, sc.Traceparent())
+			assert.Equal(t, c.sampled, sc.Sampled)
+		})
+	}
+}
+
+func Test_StartWithoutSpan(t *testing.T) {
+	ctx, span := Start(context.Background(), "nothing")
+
+	assert.Nil(t, span)
+	assert.Equal(t, context.Background(), ctx)
+
+	// a nil span does nothing.
+	span.SetAttribute("key", "value")
+	span.RecordError(errors.New("failure"))
+	span.End()
+	assert.False(t, span.Context().IsValid())
+}
+
+func Test_Trace(t *testing.T) {
+	exporter := &recordingExporter{}
+	tracer := New(exporter)
+
+	remote, err := ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	assert.NoError(t, err)
+
+	ctx, root := tracer.Start(context.Background(), "POST /receipt/process", KindServer, remote)
+	assert.Equal(t, root, FromContext(ctx))
+
+	_, child := Start(ctx, "calculator.Points")
+	child.SetAttribute("receipt.points", "28")
+	child.RecordError(errors.New("failure"))
+	child.End()
+	child.End()
+	root.End()
+
+	assert.NoError(t, tracer.Shutdown(context.Background()))
+
+	spans := exporter.exported()
+	assert.True(t, exporter.shutdown)
+	assert.Len(t, spans, 2)
+	assert.Equal(t, "calculator.Points", spans[0].Name)
+	assert.Equal(t, remote.TraceID, spans[0].TraceID)
+	assert.Equal(t, root.Context().SpanID, spans[0].Parent)
+	assert.Equal(t, []Attribute{{Key: "receipt.points", Value: "28"}}, spans[0].Attributes)
+	assert.Equal(t, "failure", spans[0].Err)
+	assert.Equal(t, KindServer, spans[1].Kind)
+	assert.Equal(t, remote.SpanID, spans[1].Parent)
+	assert.False(t, spans[1].End.Before(spans[1].Start))
+
+	// spans ended after the shutdown are dropped.
+	_, late := tracer.Start(context.Background(), "late", KindInternal, SpanContext{})
+	late.End()
+	assert.Equal(t, uint64(1), tracer.Dropped())
+}
+
+func Test_Sampling(t *testing.T) {
+	exporter := &recordingExporter{}
+	tracer := New(exporter, WithSampleRatio(0))
+
+	ctx, root := tracer.Start(context.Background(), "root", KindServer, SpanContext{})
+	_, child := Start(ctx, "child")
+
+	assert.True(t, root.Context().IsValid())
+	assert.False(t, child.Context().Sampled)
+	child.End()
+	root.End()
+
+	// the caller decision is followed.
+	remote, err := ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	assert.NoError(t, err)
+
+	_, sampled := tracer.Start(context.Background(), "sampled", KindServer, remote)
+	sampled.End()
+
+	assert.NoError(t, tracer.Shutdown(context.Background()))
+	assert.Len(t, exporter.exported(), 1)
+}
+
+func Test_BatchInterval(t *testing.T) {
+	exporter := &recordingExporter{}
+	tracer := New(exporter, WithBatch(10, 10*time.Millisecond))
+
+	t.Cleanup(func() { tracer.Shutdown(context.Background()) })
+
+	_, span := tracer.Start(context.Background(), "span", KindServer, SpanContext{})
+	span.End()
+
+	assert.Eventually(t, func() bool {
+		return len(exporter.exported()) == 1
+	}, time.Second, 5*time.Millisecond)
+}
+
+func Test_ExportError(t *testing.T) {
+	errs := make(chan error, 1)
+	tracer := New(failingExporter{}, WithErrorHandler(func(err error) { errs <- err }))
+
+	_, span := tracer.Start(context.Background(), "span", KindServer, SpanContext{})
+	span.End()
+
+	assert.NoError(t, tracer.Shutdown(context.Background()))
+	assert.EqualError(t, <-errs, "collector down")
+}
+
+type failingExporter struct{}
+
+func (failingExporter) Export(context.Context, []SpanData) error {
+	return errors.New("collector down")
+}
+
+func (failingExporter) Shutdown(context.Context) error {
+	return nil
+}